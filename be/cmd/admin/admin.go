@@ -0,0 +1,87 @@
+package main
+
+import (
+	"calometer/internal/config"
+	"calometer/internal/db"
+	"calometer/internal/lib"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+)
+
+// The admin API needs an admin to begin with, this sets roles directly in
+// the database. It also runs maintenance that works on every profile.
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatalf("Usage: %s <command> [args...]\n", os.Args[0])
+	}
+
+	command := os.Args[1]
+	args := os.Args[2:]
+
+	cfg, err := config.Load(os.Args[0], nil)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if err := cfg.Database.Validate(); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+
+	var store lib.Store
+	switch cfg.Database.Driver {
+	case "postgres":
+		pool, err := db.Init(cfg.Database)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer db.Close(pool)
+
+		store = lib.NewPgStore(pool)
+	case "sqlite":
+		conn, err := db.InitSQLite(cfg.Database)
+		if err != nil {
+			log.Fatalf("Failed to open database: %v", err)
+		}
+		defer db.CloseSQLite(conn)
+
+		store = lib.NewSqliteStore(conn)
+	}
+
+	// Stop the running queries on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch command {
+	case "set-role":
+		if len(args) < 2 {
+			log.Fatalf("Usage: %s set-role <username> <role>\n", os.Args[0])
+		}
+
+		username, role := args[0], args[1]
+		if !lib.IsValidRole(role) {
+			log.Fatalf("Unknown role %q, expected one of %v", role, lib.Roles)
+		}
+
+		updated, err := lib.SetUserRoleByUsername(ctx, store, username, role)
+		if err != nil {
+			log.Fatalf("Failed to set role: %v", err)
+		}
+
+		if !*updated {
+			log.Fatalf("User %q not found", username)
+		}
+
+		log.Printf("User %s is now %s. The role applies from their next token refresh.\n", username, role)
+	case "recompute-achievements":
+		// Run after changing the achievement rules
+		count, err := lib.RecomputeAllAchievements(ctx, store)
+		if err != nil {
+			log.Fatalf("Failed to recompute achievements after %d profiles: %v", count, err)
+		}
+
+		log.Printf("Recomputed achievements and streaks of %d profiles.\n", count)
+	default:
+		log.Fatalf("Unknown command: %s\n", command)
+	}
+}
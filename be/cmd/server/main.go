@@ -2,15 +2,34 @@ package main
 
 import (
 	"calometer/internal/api"
+	"calometer/internal/config"
 	"calometer/internal/db"
+	"calometer/internal/lib"
 	"calometer/internal/logger"
-	"fmt"
-	"net/http"
+	"calometer/internal/metrics"
+	"calometer/internal/migratecmd"
+	"context"
+	stdlog "log"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
 
 func main() {
+	// "migrate <command>" manages the schema instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		name := os.Args[0] + " migrate"
+		if len(os.Args) < 3 {
+			stdlog.Fatalf(migratecmd.Usage, name)
+		}
+
+		migratecmd.Run(name, os.Args[2], os.Args[3:])
+		return
+	}
+
 	log := logger.GetLogger()
 
 	log.Info(
@@ -18,18 +37,77 @@ func main() {
 		zap.String("name", "calometer"),
 	)
 
+	// Load config from defaults, an optional file, the environment and flags
+	cfg, err := config.Load(os.Args[0], os.Args[1:])
+	if err != nil {
+		log.Fatal("Failed to load config", zap.Error(err))
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid config", zap.Error(err))
+	}
+
 	// Init database
-	pool, err := db.Init()
+	var stores api.Stores
+	var closeDB func()
+	// pool holds the migration lock, SQLite doesn't need one
+	var pool *pgxpool.Pool
+	switch cfg.Database.Driver {
+	case "postgres":
+		pool, err = db.Init(cfg.Database)
+		if err != nil {
+			log.Fatal("Failed to initliaze database", zap.Error(err))
+		}
+		closeDB = func() { db.Close(pool) }
+
+		if err := metrics.Register(metrics.NewPoolCollector(pool)); err != nil {
+			log.Fatal("Failed to register database pool metrics", zap.Error(err))
+		}
+
+		stores = api.NewStores(lib.NewPgStore(pool), lib.NewPostgresLoginAttemptStore(pool), db.NewPgHealth(pool))
+	case "sqlite":
+		conn, err := db.InitSQLite(cfg.Database)
+		if err != nil {
+			log.Fatal("Failed to initliaze database", zap.Error(err))
+		}
+		closeDB = func() { db.CloseSQLite(conn) }
+
+		stores = api.NewStores(lib.NewSqliteStore(conn), lib.NewSqliteLoginAttemptStore(conn), db.NewSqliteHealth(conn))
+	}
+	defer closeDB()
+
+	// Check the schema, MigrateOnStart applies pending migrations instead of
+	// refusing to start
+	schema, err := db.CheckSchema(context.Background(), cfg.Database, pool)
 	if err != nil {
-		log.Fatal("Failed to initliaze database", zap.Error(err))
+		log.Fatal("Database schema is not current", zap.Error(err))
+	}
+	if schema.Version > schema.Latest {
+		log.Warn(
+			"Database schema is newer than this build",
+			zap.Uint("version", schema.Version),
+			zap.Uint("latest", schema.Latest),
+		)
 	}
-	defer db.Close(pool)
 
-	// Init server
-	router := api.SetupRouter()
+	// Init signing keys, login throttling, the password policy, OpenID
+	// Connect login, CORS and query deadlines
+	service, err := api.NewService(cfg, stores)
+	if err != nil {
+		log.Fatal("Failed to initialize API", zap.Error(err))
+	}
+
+	// Init server, SIGINT or SIGTERM fails readiness, drains in-flight
+	// requests and then lets the deferred database close run
+	router := api.SetupRouter(service)
 
-	fmt.Println("Starting server on: :8080")
-	if err := http.ListenAndServe(":8080", router); err != nil {
-		log.Fatal("Server failed to start", zap.Error(err))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := serve(ctx, log, cfg.Server, router, service.SetShuttingDown); err != nil {
+		log.Error("Server stopped", zap.Error(err))
+		closeDB()
+		os.Exit(1)
 	}
+
+	log.Info("Server stopped")
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"calometer/internal/config"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// serve runs the API on every configured listener until ctx is canceled.
+// It then calls onShutdown, keeps serving for ShutdownDelay, stops accepting
+// connections and waits up to ShutdownTimeout for in-flight requests before
+// returning.
+func serve(ctx context.Context, log *zap.Logger, cfg config.ServerConfig, handler http.Handler, onShutdown func()) error {
+	errorLog, err := zap.NewStdLogAt(log, zap.WarnLevel)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		ErrorLog:          errorLog,
+	}
+
+	listeners, err := listen(cfg)
+	if err != nil {
+		return err
+	}
+
+	errs := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		log.Info(
+			"Starting server",
+			zap.String("network", listener.Addr().Network()),
+			zap.String("addr", listener.Addr().String()),
+			zap.Bool("tls", cfg.TLSCertFile != ""),
+		)
+
+		go func(listener net.Listener) {
+			if cfg.TLSCertFile != "" {
+				errs <- server.ServeTLS(listener, cfg.TLSCertFile, cfg.TLSKeyFile)
+			} else {
+				errs <- server.Serve(listener)
+			}
+		}(listener)
+	}
+
+	select {
+	case err := <-errs:
+		// A listener failed before shutdown was asked for
+		server.Close()
+		return err
+	case <-ctx.Done():
+	}
+
+	onShutdown()
+	if cfg.ShutdownDelay > 0 {
+		log.Info("Shutdown requested, failing readiness", zap.Duration("delay", cfg.ShutdownDelay))
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	log.Info("Shutting down, draining in-flight requests", zap.Duration("timeout", cfg.ShutdownTimeout))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		// Cut off whatever is still running so the pool can close
+		server.Close()
+		return err
+	}
+
+	for range listeners {
+		if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func listen(cfg config.ServerConfig) ([]net.Listener, error) {
+	var listeners []net.Listener
+
+	if cfg.Addr != "" {
+		listener, err := net.Listen("tcp", cfg.Addr)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+
+	if cfg.UnixSocket != "" {
+		// A socket left behind by a crash would make listening fail
+		if err := os.Remove(cfg.UnixSocket); err != nil && !errors.Is(err, os.ErrNotExist) {
+			closeListeners(listeners)
+			return nil, err
+		}
+
+		listener, err := net.Listen("unix", cfg.UnixSocket)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
+func closeListeners(listeners []net.Listener) {
+	for _, listener := range listeners {
+		listener.Close()
+	}
+}
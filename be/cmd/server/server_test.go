@@ -0,0 +1,121 @@
+package main
+
+import (
+	"calometer/internal/config"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// waitFor polls until ok holds, the server starts and stops listening in
+// the background.
+func waitFor(t *testing.T, what string, ok func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !ok() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "calometer.sock")
+
+	cfg := config.Default().Server
+	cfg.Addr = ""
+	cfg.UnixSocket = socket
+	cfg.ShutdownTimeout = 5 * time.Second
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+
+		io.WriteString(w, "done")
+	})
+
+	shutdown := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, zap.NewNop(), cfg, handler, func() { close(shutdown) })
+	}()
+
+	canDial := func() bool {
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+	waitFor(t, "the server to listen", canDial)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+
+	type result struct {
+		body string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := client.Get("http://calometer/slow")
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		done <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case <-shutdown:
+	case <-time.After(5 * time.Second):
+		t.Fatal("onShutdown wasn't called")
+	}
+
+	// New connections are refused while the slow request keeps running
+	waitFor(t, "the server to stop listening", func() bool { return !canDial() })
+
+	select {
+	case err := <-served:
+		t.Fatalf("serve returned %v before the request finished", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	if res := <-done; res.err != nil || res.body != "done" {
+		t.Fatalf("in-flight request got %q, %v", res.body, res.err)
+	}
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("serve failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve didn't return after draining")
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type CoachShareReq struct {
+	Id uuid.UUID `json:"id"`
+}
+
+func (s *Service) AcceptCoachInviteHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the user id from the context
+	userId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	var req CoachShareReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Info(
+			"failed to decode incoming json",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
+		writeResponse(w, &resp)
+		return
+	}
+
+	accepted, err := s.Coaches.AcceptCoachInvite(r.Context(), userId, req.Id)
+	if err != nil {
+		log.Info(
+			"failed to accept coach invite by id",
+			zap.String("userId", userId.String()),
+			zap.String("shareId", req.Id.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if !*accepted {
+		resp.Code[http.StatusNotFound] = "Invite not found."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	resp.Code[http.StatusOK] = "OK"
+	writeResponse(w, &resp)
+}
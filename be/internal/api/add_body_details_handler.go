@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -16,20 +17,25 @@ type AddBodyDetailsReq struct {
 	Goal      string  `json:"goal,omitempty"`
 }
 
-func AddBodyDetailsHandler(w http.ResponseWriter, r *http.Request) {
+type AddBodyDetailsResp struct {
+	// Achievements are the badges the weigh-in newly earned
+	Achievements []lib.Achievement `json:"achievements"`
+}
+
+func (s *Service) AddBodyDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	resp := Response{}
 	resp.Code = make(map[int]string)
 
-	// Retrieve the token from the context
-	tokenStr, ok := r.Context().Value(TokenContextKey).(string)
+	// Retrieve the profile id from the context
+	profileId, ok := r.Context().Value(ProfileIdContextKey).(uuid.UUID)
 	if !ok {
 		log.Info(
-			"token not found in context",
+			"profile id not found in context",
 		)
 
-		// Token is not present in context
+		// Profile id is not present in context
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
@@ -41,25 +47,13 @@ func AddBodyDetailsHandler(w http.ResponseWriter, r *http.Request) {
 		)
 
 		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
-		json.NewEncoder(w).Encode(&resp)
-		return
-	}
-
-	userId, err := lib.ExtractUserIdFromToken(tokenStr)
-	if err != nil {
-		log.Info(
-			"failed to extract username from token",
-			zap.String("tokenStr", tokenStr),
-			zap.Error(err),
-		)
-
-		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	if err := lib.AddUserBodyDetails(
-		*userId,
+	if err := s.Users.AddUserBodyDetails(
+		r.Context(),
+		profileId,
 		req.Age,
 		req.Height_cm,
 		req.Weight_kg,
@@ -67,28 +61,63 @@ func AddBodyDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	); err != nil {
 		log.Info(
 			"failed to add user body details by id",
-			zap.String("userId", userId.String()),
+			zap.String("profileId", profileId.String()),
 			zap.Error(err),
 		)
 
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	if err := lib.SetUserGoal(*userId, req.Goal); err != nil {
+	// Every weight entered becomes part of the weigh-in history
+	if req.Weight_kg > 0 {
+		if err := s.WeighIns.RecordWeighIn(r.Context(), profileId, req.Weight_kg); err != nil {
+			log.Info(
+				"failed to record weigh-in by profile id",
+				zap.String("profileId", profileId.String()),
+				zap.Error(err),
+			)
+
+			resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+			writeResponse(w, &resp)
+			return
+		}
+	}
+
+	if err := s.Users.SetUserGoal(r.Context(), profileId, req.Goal); err != nil {
 		log.Info(
 			"failed to set user weight goal by id",
-			zap.String("userId", userId.String()),
+			zap.String("profileId", profileId.String()),
 			zap.Error(err),
 		)
 
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
+	achievements := []lib.Achievement{}
+	if req.Weight_kg > 0 {
+		awarded, err := s.Achievements.EvaluateAchievements(r.Context(), profileId, lib.EventWeighIn)
+		if err != nil {
+			log.Info(
+				"failed to evaluate achievements by profile id",
+				zap.String("profileId", profileId.String()),
+				zap.Error(err),
+			)
+		} else {
+			achievements = awarded
+		}
+	}
+
 	w.WriteHeader(http.StatusOK)
+
+	data := &AddBodyDetailsResp{
+		Achievements: achievements,
+	}
+
 	resp.Code[http.StatusOK] = "OK"
-	json.NewEncoder(w).Encode(&resp)
+	resp.Data = data
+	writeResponse(w, &resp)
 }
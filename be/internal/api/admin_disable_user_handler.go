@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type AdminDisableUserReq struct {
+	UserId uuid.UUID `json:"user_id"`
+}
+
+func (s *Service) AdminDisableUserHandler(w http.ResponseWriter, r *http.Request) {
+	s.setUserDisabled(w, r, true)
+}
+
+func (s *Service) AdminEnableUserHandler(w http.ResponseWriter, r *http.Request) {
+	s.setUserDisabled(w, r, false)
+}
+
+func (s *Service) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the user id from the context
+	adminId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	var req AdminDisableUserReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Info(
+			"failed to decode incoming json",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if disabled && req.UserId == adminId {
+		resp.Code[http.StatusBadRequest] = "You can't disable your own account."
+		writeResponse(w, &resp)
+		return
+	}
+
+	updated, err := s.Users.SetUserDisabled(r.Context(), req.UserId, disabled)
+	if err != nil {
+		log.Info(
+			"failed to set user disabled by id",
+			zap.String("userId", req.UserId.String()),
+			zap.Bool("disabled", disabled),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if !*updated {
+		resp.Code[http.StatusNotFound] = "User not found."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if disabled {
+		// Access tokens run out on their own, refresh tokens must not
+		if err := s.RefreshTokens.RevokeUserRefreshTokens(r.Context(), req.UserId); err != nil {
+			log.Info(
+				"failed to revoke refresh tokens by user id",
+				zap.String("userId", req.UserId.String()),
+				zap.Error(err),
+			)
+		}
+	}
+
+	log.Info(
+		"admin changed user disabled state",
+		zap.String("adminId", adminId.String()),
+		zap.String("userId", req.UserId.String()),
+		zap.Bool("disabled", disabled),
+	)
+
+	w.WriteHeader(http.StatusOK)
+	resp.Code[http.StatusOK] = "OK"
+	writeResponse(w, &resp)
+}
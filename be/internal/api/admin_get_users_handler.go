@@ -0,0 +1,70 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultUsersPageSize = 50
+	maxUsersPageSize     = 200
+)
+
+type AdminGetUsersResp struct {
+	Users []lib.UserSummary `json:"users"`
+}
+
+func (s *Service) AdminGetUsersHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	query := r.URL.Query()
+
+	limit := defaultUsersPageSize
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxUsersPageSize {
+			resp.Code[http.StatusBadRequest] = "Limit must be between 1 and " + strconv.Itoa(maxUsersPageSize) + "."
+			writeResponse(w, &resp)
+			return
+		}
+		limit = parsed
+	}
+
+	offset := 0
+	if value := query.Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			resp.Code[http.StatusBadRequest] = "Offset must not be negative."
+			writeResponse(w, &resp)
+			return
+		}
+		offset = parsed
+	}
+
+	users, err := s.Users.SearchUsers(r.Context(), query.Get("q"), limit, offset)
+	if err != nil {
+		log.Info(
+			"failed to search users",
+			zap.String("query", query.Get("q")),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	data := &AdminGetUsersResp{
+		Users: users,
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
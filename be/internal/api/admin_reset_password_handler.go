@@ -0,0 +1,113 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type AdminResetPasswordReq struct {
+	UserId      uuid.UUID `json:"user_id"`
+	NewPassword string    `json:"new_password"`
+}
+
+func (s *Service) AdminResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the user id from the context
+	adminId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	var req AdminResetPasswordReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Info(
+			"failed to decode incoming json",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
+		writeResponse(w, &resp)
+		return
+	}
+
+	username, err := s.Users.GetUsernameById(r.Context(), req.UserId)
+	if err == pgx.ErrNoRows {
+		resp.Code[http.StatusNotFound] = "User not found."
+		writeResponse(w, &resp)
+		return
+	} else if err != nil {
+		log.Info(
+			"failed to get username by id",
+			zap.String("userId", req.UserId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if errs := s.passwordPolicy.Validate(req.NewPassword, *username); len(errs) > 0 {
+		resp.Code[http.StatusBadRequest] = "Password does not meet the requirements."
+		resp.Data = &ValidationErrorsResp{Errors: errs}
+		writeResponse(w, &resp)
+		return
+	}
+
+	passwordHash, err := lib.HashPassword(req.NewPassword)
+	if err != nil {
+		log.Info(
+			"failed to hash user's password",
+			zap.String("username", *username),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if err := s.Users.UpdatePassword(r.Context(), req.UserId, passwordHash); err != nil {
+		log.Info(
+			"failed to update password by user id",
+			zap.String("userId", req.UserId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	// Whoever knew the old password is signed out
+	if err := s.RefreshTokens.RevokeUserRefreshTokens(r.Context(), req.UserId); err != nil {
+		log.Info(
+			"failed to revoke refresh tokens by user id",
+			zap.String("userId", req.UserId.String()),
+			zap.Error(err),
+		)
+	}
+
+	log.Info(
+		"admin reset user password",
+		zap.String("adminId", adminId.String()),
+		zap.String("userId", req.UserId.String()),
+	)
+
+	w.WriteHeader(http.StatusOK)
+	resp.Code[http.StatusOK] = "OK"
+	writeResponse(w, &resp)
+}
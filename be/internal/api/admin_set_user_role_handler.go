@@ -0,0 +1,89 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type AdminSetUserRoleReq struct {
+	UserId uuid.UUID `json:"user_id"`
+	Role   string    `json:"role"`
+}
+
+func (s *Service) AdminSetUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the user id from the context
+	adminId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	var req AdminSetUserRoleReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Info(
+			"failed to decode incoming json",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if !lib.IsValidRole(req.Role) {
+		resp.Code[http.StatusBadRequest] = "Unknown role: " + req.Role
+		writeResponse(w, &resp)
+		return
+	}
+
+	// Keeps the last admin from locking everyone out
+	if req.UserId == adminId && req.Role != lib.RoleAdmin {
+		resp.Code[http.StatusBadRequest] = "You can't remove your own admin role."
+		writeResponse(w, &resp)
+		return
+	}
+
+	updated, err := s.Users.SetUserRole(r.Context(), req.UserId, req.Role)
+	if err != nil {
+		log.Info(
+			"failed to set user role by id",
+			zap.String("userId", req.UserId.String()),
+			zap.String("role", req.Role),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if !*updated {
+		resp.Code[http.StatusNotFound] = "User not found."
+		writeResponse(w, &resp)
+		return
+	}
+
+	log.Info(
+		"admin changed user role",
+		zap.String("adminId", adminId.String()),
+		zap.String("userId", req.UserId.String()),
+		zap.String("role", req.Role),
+	)
+
+	w.WriteHeader(http.StatusOK)
+	resp.Code[http.StatusOK] = "OK"
+	writeResponse(w, &resp)
+}
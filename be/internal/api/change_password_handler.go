@@ -0,0 +1,132 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type ChangePasswordReq struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+func (s *Service) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the user id from the context
+	userId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	var req ChangePasswordReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Info(
+			"failed to decode incoming json",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
+		writeResponse(w, &resp)
+		return
+	}
+
+	username, err := s.Users.GetUsernameById(r.Context(), userId)
+	if err != nil {
+		log.Info(
+			"failed to get username by id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	passwordHash, err := s.Users.GetPasswordHash(r.Context(), *username)
+	if err != nil {
+		log.Info(
+			"failed to fetch user's hashed password",
+			zap.String("username", *username),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if err := lib.CheckPasswordValidity(req.CurrentPassword, *passwordHash); err != nil {
+		resp.Code[http.StatusUnauthorized] = "Current password is incorrect."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if errs := s.passwordPolicy.Validate(req.NewPassword, *username); len(errs) > 0 {
+		resp.Code[http.StatusBadRequest] = "Password does not meet the requirements."
+		resp.Data = &ValidationErrorsResp{Errors: errs}
+		writeResponse(w, &resp)
+		return
+	}
+
+	newPasswordHash, err := lib.HashPassword(req.NewPassword)
+	if err != nil {
+		log.Info(
+			"failed to hash user's password",
+			zap.String("username", *username),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if err := s.Users.UpdatePassword(r.Context(), userId, newPasswordHash); err != nil {
+		log.Info(
+			"failed to update password by user id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	// Sign out every other session, then give this one a fresh start
+	if err := s.RefreshTokens.RevokeUserRefreshTokens(r.Context(), userId); err != nil {
+		log.Info(
+			"failed to revoke refresh tokens by user id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+	}
+
+	if err := s.startSession(r.Context(), w, userId, *username); err != nil {
+		log.Info(
+			"failed to start session for user id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	resp.Code[http.StatusOK] = "OK"
+	writeResponse(w, &resp)
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChangePasswordHandlerRequiresCurrentPassword(t *testing.T) {
+	service, store := newTestService(t, testConfig())
+	userId := createTestUser(t, store, "alice", "correct horse")
+
+	r := newJSONRequest(t, http.MethodPost, "/api/users/password/change", ChangePasswordReq{
+		CurrentPassword: "wrong horse",
+		NewPassword:     "battery staple",
+	})
+	r = r.WithContext(context.WithValue(r.Context(), UserIdContextKey, userId))
+	w := httptest.NewRecorder()
+
+	service.ChangePasswordHandler(w, r)
+
+	expectCode(t, w, http.StatusUnauthorized)
+
+	passwordHash, err := store.GetPasswordHash(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("failed to get password hash: %v", err)
+	}
+
+	if err := lib.CheckPasswordValidity("correct horse", *passwordHash); err != nil {
+		t.Fatalf("password changed without the current one: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"net/http"
+	"time"
+)
+
+const (
+	tokenCookieName        = "token"
+	refreshTokenCookieName = "refresh_token"
+
+	// The refresh token is only needed by the refresh and logout routes, so
+	// it is scoped to the users API instead of the whole site.
+	refreshTokenCookiePath = "/api/users"
+)
+
+// sessionCookieSameSite defaults to Lax. Strict only works when the frontend
+// and API share a site, None needs HTTPS.
+func (s *Service) sessionCookieSameSite() http.SameSite {
+	switch s.cfg.Cookies.SameSite {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+
+	return http.SameSiteLaxMode
+}
+
+func (s *Service) sessionCookieSecure() bool {
+	// Browsers drop SameSite=None cookies that aren't Secure
+	return s.cfg.IsProduction() || s.sessionCookieSameSite() == http.SameSiteNoneMode
+}
+
+func (s *Service) setSessionCookies(w http.ResponseWriter, token string, refreshToken string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     tokenCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   s.sessionCookieSecure(),
+		SameSite: s.sessionCookieSameSite(),
+		Expires:  time.Now().Add(lib.AccessTokenTTL),
+	})
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshTokenCookieName,
+		Value:    refreshToken,
+		Path:     refreshTokenCookiePath,
+		HttpOnly: true,
+		Secure:   s.sessionCookieSecure(),
+		SameSite: s.sessionCookieSameSite(),
+		Expires:  time.Now().Add(lib.RefreshTokenTTL),
+	})
+}
+
+func (s *Service) clearSessionCookies(w http.ResponseWriter) {
+	// Set both cookies with an expired date to delete them
+	http.SetCookie(w, &http.Cookie{
+		Name:     tokenCookieName,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   s.sessionCookieSecure(),
+		SameSite: s.sessionCookieSameSite(),
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+	})
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshTokenCookieName,
+		Value:    "",
+		Path:     refreshTokenCookiePath,
+		HttpOnly: true,
+		Secure:   s.sessionCookieSecure(),
+		SameSite: s.sessionCookieSameSite(),
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+	})
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"calometer/internal/config"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type CORSOptions struct {
+	// AllowedOrigins are exact origins like "https://app.example.com" or
+	// wildcard subdomains like "https://*.example.com".
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+type wildcardOrigin struct {
+	prefix string
+	suffix string
+}
+
+type CORS struct {
+	options   CORSOptions
+	origins   map[string]bool
+	wildcards []wildcardOrigin
+}
+
+var subdomainLabels = regexp.MustCompile(`^[a-z0-9-]+(\.[a-z0-9-]+)*$`)
+
+func NewCORS(options CORSOptions) *CORS {
+	c := &CORS{
+		options: options,
+		origins: make(map[string]bool),
+	}
+
+	for _, origin := range options.AllowedOrigins {
+		origin = normalizeOrigin(origin)
+
+		if prefix, suffix, ok := strings.Cut(origin, "*."); ok {
+			c.wildcards = append(c.wildcards, wildcardOrigin{prefix: prefix, suffix: "." + suffix})
+			continue
+		}
+
+		c.origins[origin] = true
+	}
+
+	for i, method := range c.options.AllowedMethods {
+		c.options.AllowedMethods[i] = strings.ToUpper(method)
+	}
+
+	return c
+}
+
+// NewCORSFromConfig defaults methods and headers to what the API uses.
+func NewCORSFromConfig(cfg config.CORSConfig) *CORS {
+	options := CORSOptions{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   slices.Clone(cfg.AllowedMethods),
+		AllowedHeaders:   cfg.AllowedHeaders,
+		AllowCredentials: true,
+		MaxAge:           cfg.MaxAge,
+	}
+
+	if len(options.AllowedMethods) == 0 {
+		options.AllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+	}
+
+	if len(options.AllowedHeaders) == 0 {
+		options.AllowedHeaders = []string{"Content-Type", "Authorization", ProfileIdHeader}
+	}
+
+	return NewCORS(options)
+}
+
+func (c *CORS) IsAllowedOrigin(origin string) bool {
+	origin = normalizeOrigin(origin)
+
+	if c.origins[origin] {
+		return true
+	}
+
+	for _, wildcard := range c.wildcards {
+		if len(origin) <= len(wildcard.prefix)+len(wildcard.suffix) ||
+			!strings.HasPrefix(origin, wildcard.prefix) || !strings.HasSuffix(origin, wildcard.suffix) {
+			continue
+		}
+
+		subdomain := origin[len(wildcard.prefix) : len(origin)-len(wildcard.suffix)]
+		if subdomainLabels.MatchString(subdomain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Handler adds the CORS response headers for allowed origins.
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.setOriginHeaders(w, r)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// PreflightHandler answers OPTIONS requests for any route registered on the
+// router, advertising only the methods that route actually accepts.
+func (c *CORS) PreflightHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods := []string{}
+		for _, method := range c.options.AllowedMethods {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+
+			var match mux.RouteMatch
+			if router.Match(probe, &match) && match.MatchErr == nil {
+				methods = append(methods, method)
+			}
+		}
+
+		if len(methods) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(append(methods, http.MethodOptions), ", "))
+
+		requestedMethod := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+		if !c.setOriginHeaders(w, r) || !slices.Contains(methods, requestedMethod) {
+			// Without the CORS headers the browser fails the preflight
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(c.options.AllowedHeaders, ", "))
+
+		if c.options.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.options.MaxAge.Seconds())))
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (c *CORS) setOriginHeaders(w http.ResponseWriter, r *http.Request) bool {
+	// The response depends on the Origin, so caches must keep them apart
+	w.Header().Add("Vary", "Origin")
+
+	origin := r.Header.Get("Origin")
+	if origin == "" || !c.IsAllowedOrigin(origin) {
+		return false
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if c.options.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	return true
+}
+
+func normalizeOrigin(origin string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(origin)), "/")
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"calometer/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func newTestCORS() *CORS {
+	return NewCORSFromConfig(config.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com/", "https://*.preview.example.com"},
+		MaxAge:         10 * time.Minute,
+	})
+}
+
+func TestCORSAllowedOrigins(t *testing.T) {
+	cors := newTestCORS()
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"HTTPS://APP.EXAMPLE.COM", true},
+		{"https://pr-12.preview.example.com", true},
+		{"https://a.b.preview.example.com", true},
+		{"http://app.example.com", false},
+		{"https://app.example.com.evil.com", false},
+		{"https://evil.com", false},
+		{"https://preview.example.com", false},
+		{"https://.preview.example.com", false},
+		{"https://evil.com/.preview.example.com", false},
+		{"null", false},
+	}
+
+	for _, tt := range tests {
+		if got := cors.IsAllowedOrigin(tt.origin); got != tt.want {
+			t.Errorf("IsAllowedOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestCORSHandler(t *testing.T) {
+	handler := newTestCORS().Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name        string
+		origin      string
+		allowOrigin string
+	}{
+		{"allowed", "https://app.example.com", "https://app.example.com"},
+		{"rejected", "https://evil.com", ""},
+		{"same origin", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/logs", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+				t.Fatalf("Access-Control-Allow-Origin = %q, want %q", got, tt.allowOrigin)
+			}
+
+			wantCredentials := ""
+			if tt.allowOrigin != "" {
+				wantCredentials = "true"
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != wantCredentials {
+				t.Fatalf("Access-Control-Allow-Credentials = %q, want %q", got, wantCredentials)
+			}
+
+			// Rejected responses must not be served from a cache to allowed
+			// origins either
+			if !slices.Contains(w.Header().Values("Vary"), "Origin") {
+				t.Fatalf("Vary = %v, want Origin", w.Header().Values("Vary"))
+			}
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	cors := newTestCORS()
+
+	router := mux.NewRouter()
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	router.HandleFunc("/api/logs", ok).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc("/api/logs/{id}", ok).Methods(http.MethodDelete)
+	router.Methods(http.MethodOptions).Handler(cors.PreflightHandler(router))
+
+	preflight := func(path string, origin string, method string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodOptions, path, nil)
+		r.Header.Set("Origin", origin)
+		r.Header.Set("Access-Control-Request-Method", method)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		w := preflight("/api/logs", "https://app.example.com", "post")
+
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusNoContent)
+		}
+
+		want := map[string]string{
+			"Access-Control-Allow-Origin":      "https://app.example.com",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Allow-Methods":     "GET, POST",
+			"Access-Control-Allow-Headers":     "Content-Type, Authorization, " + ProfileIdHeader,
+			"Access-Control-Max-Age":           "600",
+			"Allow":                            "GET, POST, OPTIONS",
+		}
+		for header, value := range want {
+			if got := w.Header().Get(header); got != value {
+				t.Errorf("%s = %q, want %q", header, got, value)
+			}
+		}
+
+		vary := w.Header().Values("Vary")
+		for _, header := range []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"} {
+			if !slices.Contains(vary, header) {
+				t.Errorf("Vary = %v, want %s", vary, header)
+			}
+		}
+	})
+
+	t.Run("only the route's methods", func(t *testing.T) {
+		w := preflight("/api/logs/1", "https://app.example.com", http.MethodDelete)
+
+		if got := w.Header().Get("Access-Control-Allow-Methods"); got != "DELETE" {
+			t.Fatalf("Access-Control-Allow-Methods = %q, want DELETE", got)
+		}
+	})
+
+	t.Run("method not accepted by the route", func(t *testing.T) {
+		w := preflight("/api/logs/1", "https://app.example.com", http.MethodPut)
+
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusNoContent)
+		}
+
+		if got := w.Header().Get("Access-Control-Allow-Methods"); got != "" {
+			t.Fatalf("Access-Control-Allow-Methods = %q, want none", got)
+		}
+	})
+
+	t.Run("rejected origin", func(t *testing.T) {
+		w := preflight("/api/logs", "https://evil.com", http.MethodPost)
+
+		for _, header := range []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Methods", "Access-Control-Allow-Headers"} {
+			if got := w.Header().Get(header); got != "" {
+				t.Errorf("%s = %q, want none", header, got)
+			}
+		}
+
+		if !slices.Contains(w.Header().Values("Vary"), "Origin") {
+			t.Fatalf("Vary = %v, want Origin", w.Header().Values("Vary"))
+		}
+	})
+
+	t.Run("unknown route", func(t *testing.T) {
+		w := preflight("/api/nothing", "https://app.example.com", http.MethodGet)
+
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+type CreateAccessTokenReq struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty"`
+}
+
+type CreateAccessTokenResp struct {
+	Id    uuid.UUID `json:"id"`
+	Token string    `json:"token"`
+}
+
+func (s *Service) CreateAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the user id from the context
+	userId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	var req CreateAccessTokenReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Info(
+			"failed to decode incoming json",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
+		writeResponse(w, &resp)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Scopes) == 0 || req.ExpiresInDays < 0 {
+		resp.Code[http.StatusBadRequest] = "Please enter correct details."
+		writeResponse(w, &resp)
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !lib.IsValidScope(scope) {
+			resp.Code[http.StatusBadRequest] = "Unknown scope: " + scope
+			writeResponse(w, &resp)
+			return
+		}
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		expiry := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &expiry
+	}
+
+	tokenId, token, err := s.AccessTokens.CreateAccessToken(r.Context(), userId, req.Name, req.Scopes, expiresAt)
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+			resp.Code[http.StatusConflict] = "An access token with this name already exists."
+			writeResponse(w, &resp)
+			return
+		}
+
+		log.Info(
+			"failed to create access token by user id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	// The token is only ever shown once, only its hash is stored
+	data := &CreateAccessTokenResp{
+		Id:    *tokenId,
+		Token: token,
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
@@ -1,11 +1,12 @@
 package api
 
 import (
-	"calometer/internal/lib"
+	"calometer/internal/metrics"
 	"encoding/json"
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -13,33 +14,20 @@ type CreateCalorieLogReq struct {
 	LogDate time.Time `json:"log_date,omitempty"`
 }
 
-func CreateCalorieLogHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Service) CreateCalorieLogHandler(w http.ResponseWriter, r *http.Request) {
 	resp := Response{}
 	resp.Code = make(map[int]string)
 
-	// Retrieve the token from the context
-	tokenStr, ok := r.Context().Value(TokenContextKey).(string)
+	// Retrieve the profile id from the context
+	profileId, ok := r.Context().Value(ProfileIdContextKey).(uuid.UUID)
 	if !ok {
 		log.Info(
-			"token not found in context",
+			"profile id not found in context",
 		)
 
-		// Token is not present in context
+		// Profile id is not present in context
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
-		return
-	}
-
-	userId, err := lib.ExtractUserIdFromToken(tokenStr)
-	if err != nil {
-		log.Info(
-			"failed to get user id by token",
-			zap.String("userId", userId.String()),
-			zap.Error(err),
-		)
-
-		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
@@ -51,7 +39,7 @@ func CreateCalorieLogHandler(w http.ResponseWriter, r *http.Request) {
 		)
 
 		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
@@ -62,58 +50,60 @@ func CreateCalorieLogHandler(w http.ResponseWriter, r *http.Request) {
 		// Check if the logDate is in the future
 		if req.LogDate.After(time.Now()) {
 			resp.Code[http.StatusBadRequest] = "Log date cannot be a future date."
-			json.NewEncoder(w).Encode(&resp)
+			writeResponse(w, &resp)
 			return
 		}
 
 		logDate = req.LogDate.Format("2006-01-02")
 	}
 
-	exists, err := lib.DoesLogExistForTheDay(*userId, logDate)
+	exists, err := s.CalorieLogs.DoesLogExistForTheDay(r.Context(), profileId, logDate)
 	if err != nil {
 		log.Info(
 			"failed to determine user log's existence",
-			zap.String("userId", userId.String()),
+			zap.String("profileId", profileId.String()),
 			zap.Error(err),
 		)
 
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
 	if *exists {
 		resp.Code[http.StatusConflict] = "Log already exists for this day."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	bmr, err := lib.GetUserBmr(*userId)
+	bmr, err := s.Users.GetUserBmr(r.Context(), profileId)
 	if err != nil {
 		log.Info(
 			"failed to get user bmr by id",
-			zap.String("userId", userId.String()),
+			zap.String("profileId", profileId.String()),
 			zap.Error(err),
 		)
 
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	if err := lib.CreateUserLog(*userId, *bmr, logDate); err != nil {
+	if err := s.CalorieLogs.CreateUserLog(r.Context(), profileId, *bmr, logDate); err != nil {
 		log.Info(
 			"failed to create user log by id",
-			zap.String("userId", userId.String()),
+			zap.String("profileId", profileId.String()),
 			zap.Error(err),
 		)
 
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
+	metrics.LogsCreated.Inc()
+
 	w.WriteHeader(http.StatusOK)
 	resp.Code[http.StatusOK] = "OK"
-	json.NewEncoder(w).Encode(&resp)
+	writeResponse(w, &resp)
 }
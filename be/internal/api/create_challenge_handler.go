@@ -0,0 +1,125 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	maxChallengeNameLength = 50
+	maxChallengeDays       = 366
+)
+
+type CreateChallengeReq struct {
+	GroupId  uuid.UUID `json:"group_id"`
+	Name     string    `json:"name"`
+	Metric   string    `json:"metric"`
+	StartsOn string    `json:"starts_on"`
+	EndsOn   string    `json:"ends_on"`
+}
+
+type CreateChallengeResp struct {
+	Id uuid.UUID `json:"id"`
+}
+
+func (s *Service) CreateChallengeHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the user id from the context
+	userId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	var req CreateChallengeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Info(
+			"failed to decode incoming json",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
+		writeResponse(w, &resp)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len([]rune(name)) > maxChallengeNameLength {
+		resp.Code[http.StatusBadRequest] = "Please enter a challenge name of up to 50 characters."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if !lib.IsValidChallengeMetric(req.Metric) {
+		resp.Code[http.StatusBadRequest] = "Invalid challenge metric."
+		writeResponse(w, &resp)
+		return
+	}
+
+	startsOn, startErr := time.Parse("2006-01-02", req.StartsOn)
+	endsOn, endErr := time.Parse("2006-01-02", req.EndsOn)
+	if startErr != nil || endErr != nil || endsOn.Before(startsOn) || endsOn.Sub(startsOn).Hours()/24 >= maxChallengeDays {
+		resp.Code[http.StatusBadRequest] = "Please enter a date range of up to a year."
+		writeResponse(w, &resp)
+		return
+	}
+
+	isOwner, err := s.Groups.IsGroupOwner(r.Context(), req.GroupId, userId)
+	if err != nil {
+		log.Info(
+			"failed to check group owner by id",
+			zap.String("groupId", req.GroupId.String()),
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	// Only the owner sets challenges, other members can't tell the group apart
+	// from one that doesn't exist
+	if !*isOwner {
+		resp.Code[http.StatusNotFound] = "Group not found."
+		writeResponse(w, &resp)
+		return
+	}
+
+	challengeId, err := s.Challenges.CreateChallenge(r.Context(), req.GroupId, userId, name, req.Metric, req.StartsOn, req.EndsOn)
+	if err != nil {
+		log.Info(
+			"failed to create challenge by group id",
+			zap.String("groupId", req.GroupId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	data := &CreateChallengeResp{
+		Id: *challengeId,
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
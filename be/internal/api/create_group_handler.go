@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const maxGroupNameLength = 50
+
+type CreateGroupReq struct {
+	Name string `json:"name"`
+}
+
+type CreateGroupResp struct {
+	Id uuid.UUID `json:"id"`
+}
+
+func (s *Service) CreateGroupHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the user id from the context
+	userId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	// Retrieve the profile id from the context
+	profileId, ok := r.Context().Value(ProfileIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"profile id not found in context",
+		)
+
+		// Profile id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	var req CreateGroupReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Info(
+			"failed to decode incoming json",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
+		writeResponse(w, &resp)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len([]rune(name)) > maxGroupNameLength {
+		resp.Code[http.StatusBadRequest] = "Please enter a group name of up to 50 characters."
+		writeResponse(w, &resp)
+		return
+	}
+
+	groupId, err := s.Groups.CreateGroup(r.Context(), userId, profileId, name)
+	if err != nil {
+		log.Info(
+			"failed to create group by user id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	data := &CreateGroupResp{
+		Id: *groupId,
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
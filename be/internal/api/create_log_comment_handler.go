@@ -0,0 +1,119 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type CreateLogCommentReq struct {
+	LogDate   string `json:"log_date,omitempty"`
+	WeekStart string `json:"week_start,omitempty"`
+	Body      string `json:"body"`
+}
+
+type LogCommentResp struct {
+	Id uuid.UUID `json:"id"`
+}
+
+// CreateLogCommentHandler lets a coach start a thread on a client's day or
+// week.
+func (s *Service) CreateLogCommentHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	clientId, profileId, coachId, _, ok := commentParticipants(r)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	var req CreateLogCommentReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Info(
+			"failed to decode incoming json",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if (req.LogDate == "") == (req.WeekStart == "") {
+		resp.Code[http.StatusBadRequest] = "Comment on either a log date or a week."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if req.WeekStart != "" {
+		if _, err := lib.WeekStartOf(req.WeekStart); err != nil {
+			resp.Code[http.StatusBadRequest] = "Invalid week start date."
+			writeResponse(w, &resp)
+			return
+		}
+	}
+
+	if !validateCommentBody(&resp, req.Body) {
+		writeResponse(w, &resp)
+		return
+	}
+
+	commentId, err := s.LogComments.CreateLogComment(
+		r.Context(),
+		clientId,
+		profileId,
+		coachId,
+		coachId,
+		lib.LogCommentTarget{
+			LogDate:   req.LogDate,
+			WeekStart: req.WeekStart,
+		},
+		strings.TrimSpace(req.Body),
+	)
+	if err != nil {
+		if errors.Is(err, lib.ErrLogNotFound) {
+			resp.Code[http.StatusNotFound] = "No log found for this day."
+			writeResponse(w, &resp)
+			return
+		}
+
+		if errors.Is(err, lib.ErrNoCoachShare) {
+			resp.Code[http.StatusForbidden] = "You don't have access to this client's data."
+			writeResponse(w, &resp)
+			return
+		}
+
+		log.Info(
+			"failed to create log comment",
+			zap.String("profileId", profileId.String()),
+			zap.String("coachId", coachId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	data := &LogCommentResp{
+		Id: *commentId,
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
@@ -1,11 +1,11 @@
 package api
 
 import (
-	"calometer/internal/lib"
 	"encoding/json"
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -13,33 +13,20 @@ type DeleteCalorieLogReq struct {
 	LogDate time.Time `json:"log_date"`
 }
 
-func DeleteCalorieLogHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Service) DeleteCalorieLogHandler(w http.ResponseWriter, r *http.Request) {
 	resp := Response{}
 	resp.Code = make(map[int]string)
 
-	// Retrieve the token from the context
-	tokenStr, ok := r.Context().Value(TokenContextKey).(string)
+	// Retrieve the profile id from the context
+	profileId, ok := r.Context().Value(ProfileIdContextKey).(uuid.UUID)
 	if !ok {
 		log.Info(
-			"token not found in context",
+			"profile id not found in context",
 		)
 
-		// Token is not present in context
+		// Profile id is not present in context
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
-		return
-	}
-
-	userId, err := lib.ExtractUserIdFromToken(tokenStr)
-	if err != nil {
-		log.Info(
-			"failed to get user id by token",
-			zap.String("userId", userId.String()),
-			zap.Error(err),
-		)
-
-		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
@@ -51,7 +38,7 @@ func DeleteCalorieLogHandler(w http.ResponseWriter, r *http.Request) {
 		)
 
 		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
@@ -59,47 +46,47 @@ func DeleteCalorieLogHandler(w http.ResponseWriter, r *http.Request) {
 	// Check if the logDate is in the future
 	if req.LogDate.After(time.Now()) {
 		resp.Code[http.StatusBadRequest] = "Log date cannot be a future date."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
 	logDate = req.LogDate.Format("2006-01-02")
 
-	exists, err := lib.DoesLogExistForTheDay(*userId, logDate)
+	exists, err := s.CalorieLogs.DoesLogExistForTheDay(r.Context(), profileId, logDate)
 	if err != nil {
 		log.Info(
 			"failed to determine user log's existence by id and date",
-			zap.String("userId", userId.String()),
+			zap.String("profileId", profileId.String()),
 			zap.String("logDate", logDate),
 			zap.Error(err),
 		)
 
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
 	if !*exists {
 		resp.Code[http.StatusConflict] = "No log exists for this day."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	logId, err := lib.GetCalorieLogId(*userId, logDate)
+	logId, err := s.CalorieLogs.GetCalorieLogId(r.Context(), profileId, logDate)
 	if err != nil {
 		log.Info(
 			"failed to get logId by id and date",
-			zap.String("userId", userId.String()),
+			zap.String("profileId", profileId.String()),
 			zap.String("logDate", logDate),
 			zap.Error(err),
 		)
 
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	if err := lib.DeleteCaloricBalanceByLogId(*logId); err != nil {
+	if err := s.Balances.DeleteCaloricBalanceByLogId(r.Context(), *logId); err != nil {
 		log.Info(
 			"failed to delete caloric balance by log id",
 			zap.String("logId", logId.String()),
@@ -107,24 +94,24 @@ func DeleteCalorieLogHandler(w http.ResponseWriter, r *http.Request) {
 		)
 
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	if err := lib.DeleteCalorieLog(*userId, logDate); err != nil {
+	if err := s.CalorieLogs.DeleteCalorieLog(r.Context(), profileId, logDate); err != nil {
 		log.Info(
 			"failed to delete log by id and date",
-			zap.String("userId", userId.String()),
+			zap.String("profileId", profileId.String()),
 			zap.String("logDate", logDate),
 			zap.Error(err),
 		)
 
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	resp.Code[http.StatusOK] = "OK"
-	json.NewEncoder(w).Encode(&resp)
+	writeResponse(w, &resp)
 }
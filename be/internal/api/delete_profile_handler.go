@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type DeleteProfileReq struct {
+	Id uuid.UUID `json:"id"`
+}
+
+func (s *Service) DeleteProfileHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the user id from the context
+	userId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	var req DeleteProfileReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Info(
+			"failed to decode incoming json",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if req.Id == userId {
+		resp.Code[http.StatusBadRequest] = "The default profile can't be deleted."
+		writeResponse(w, &resp)
+		return
+	}
+
+	deleted, err := s.Profiles.DeleteProfile(r.Context(), userId, req.Id)
+	if err != nil {
+		log.Info(
+			"failed to delete profile by id",
+			zap.String("userId", userId.String()),
+			zap.String("profileId", req.Id.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if !*deleted {
+		resp.Code[http.StatusNotFound] = "Profile not found."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	resp.Code[http.StatusOK] = "OK"
+	writeResponse(w, &resp)
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type DisableTOTPReq struct {
+	Password     string `json:"password"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+func (s *Service) DisableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the user id from the context
+	userId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	var req DisableTOTPReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Info(
+			"failed to decode incoming json",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
+		writeResponse(w, &resp)
+		return
+	}
+
+	username, err := s.Users.GetUsernameById(r.Context(), userId)
+	if err != nil {
+		log.Info(
+			"failed to get username by id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	passwordHash, err := s.Users.GetPasswordHash(r.Context(), *username)
+	if err != nil {
+		log.Info(
+			"failed to fetch user's hashed password",
+			zap.String("username", *username),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if err := lib.CheckPasswordValidity(req.Password, *passwordHash); err != nil {
+		resp.Code[http.StatusUnauthorized] = "Password is incorrect."
+		writeResponse(w, &resp)
+		return
+	}
+
+	valid, err := s.verifySecondFactor(r.Context(), userId, req.Code, req.RecoveryCode)
+	if err != nil {
+		log.Info(
+			"failed to verify second factor by user id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if !valid {
+		resp.Code[http.StatusUnauthorized] = "Invalid code."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if err := s.TwoFactor.DisableTOTP(r.Context(), userId); err != nil {
+		log.Info(
+			"failed to disable totp by user id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	resp.Code[http.StatusOK] = "OK"
+	writeResponse(w, &resp)
+}
@@ -1,10 +1,9 @@
 package api
 
 import (
-	"calometer/internal/lib"
-	"encoding/json"
 	"net/http"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -12,46 +11,33 @@ type DoBodyDetailsExistResp struct {
 	Exists bool `json:"exists"`
 }
 
-func DoBodyDetailsExistHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Service) DoBodyDetailsExistHandler(w http.ResponseWriter, r *http.Request) {
 	resp := Response{}
 	resp.Code = make(map[int]string)
 
-	// Retrieve the token from the context
-	tokenStr, ok := r.Context().Value(TokenContextKey).(string)
+	// Retrieve the profile id from the context
+	profileId, ok := r.Context().Value(ProfileIdContextKey).(uuid.UUID)
 	if !ok {
 		log.Info(
-			"token not found in context",
+			"profile id not found in context",
 		)
 
-		// Token is not present in context
+		// Profile id is not present in context
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	userId, err := lib.ExtractUserIdFromToken(tokenStr)
+	exists, err := s.Users.DoesBodyDetailsExist(r.Context(), profileId)
 	if err != nil {
 		log.Info(
-			"failed to get user id by token",
-			zap.String("userId", userId.String()),
+			"failed to check existence of body details by profile id",
+			zap.String("profileId", profileId.String()),
 			zap.Error(err),
 		)
 
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
-		return
-	}
-
-	exists, err := lib.DoesBodyDetailsExist(*userId)
-	if err != nil {
-		log.Info(
-			"failed to check existence of body details by user id",
-			zap.String("userId", userId.String()),
-			zap.Error(err),
-		)
-
-		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
@@ -61,5 +47,5 @@ func DoBodyDetailsExistHandler(w http.ResponseWriter, r *http.Request) {
 		Exists: *exists,
 	}
 	resp.Data = data
-	json.NewEncoder(w).Encode(&resp)
+	writeResponse(w, &resp)
 }
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type EnrollTOTPResp struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+func (s *Service) EnrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the user id from the context
+	userId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	username, err := s.Users.GetUsernameById(r.Context(), userId)
+	if err != nil {
+		log.Info(
+			"failed to get username by id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	secret, err := s.TwoFactor.StartTOTPEnrollment(r.Context(), userId)
+	if err != nil {
+		if errors.Is(err, lib.ErrTOTPAlreadyEnabled) {
+			resp.Code[http.StatusConflict] = "Two-factor authentication is already enabled."
+			writeResponse(w, &resp)
+			return
+		}
+
+		log.Info(
+			"failed to start totp enrollment by user id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	data := &EnrollTOTPResp{
+		Secret:          secret,
+		ProvisioningURI: lib.TOTPProvisioningURI(*username, secret),
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type GetAccessTokensResp struct {
+	AccessTokens []lib.UserAccessToken `json:"access_tokens"`
+}
+
+func (s *Service) GetAccessTokensHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the user id from the context
+	userId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	tokens, err := s.AccessTokens.GetAccessTokens(r.Context(), userId)
+	if err != nil {
+		log.Info(
+			"failed to get access tokens by user id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	data := &GetAccessTokensResp{
+		AccessTokens: tokens,
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
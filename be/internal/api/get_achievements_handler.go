@@ -0,0 +1,54 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type GetAchievementsResp struct {
+	Achievements []lib.Achievement `json:"achievements"`
+}
+
+func (s *Service) GetAchievementsHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the profile id from the context
+	profileId, ok := r.Context().Value(ProfileIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"profile id not found in context",
+		)
+
+		// Profile id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	achievements, err := s.Achievements.GetAchievements(r.Context(), profileId)
+	if err != nil {
+		log.Info(
+			"failed to get achievements by profile id",
+			zap.String("profileId", profileId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	data := &GetAchievementsResp{
+		Achievements: achievements,
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
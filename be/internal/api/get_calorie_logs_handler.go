@@ -2,9 +2,9 @@ package api
 
 import (
 	"calometer/internal/lib"
-	"encoding/json"
 	"net/http"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -12,46 +12,33 @@ type GetCaloricLogsHandlerResp struct {
 	MonthlyLogs map[string][]lib.UserCalorieLogs `json:"monthly_logs"`
 }
 
-func GetCalorieLogsHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Service) GetCalorieLogsHandler(w http.ResponseWriter, r *http.Request) {
 	resp := Response{}
 	resp.Code = make(map[int]string)
 
-	// Retrieve the token from the context
-	tokenStr, ok := r.Context().Value(TokenContextKey).(string)
+	// Retrieve the profile id from the context
+	profileId, ok := r.Context().Value(ProfileIdContextKey).(uuid.UUID)
 	if !ok {
 		log.Info(
-			"token not found in context",
+			"profile id not found in context",
 		)
 
-		// Token is not present in context
+		// Profile id is not present in context
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	userId, err := lib.ExtractUserIdFromToken(tokenStr)
+	monthlyLogs, err := s.CalorieLogs.GetCalorieLogs(r.Context(), profileId)
 	if err != nil {
 		log.Info(
-			"failed to get user id by token",
-			zap.String("userId", userId.String()),
+			"failed to get logs by profile id",
+			zap.String("profileId", profileId.String()),
 			zap.Error(err),
 		)
 
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
-		return
-	}
-
-	monthlyLogs, err := lib.GetCalorieLogs(*userId)
-	if err != nil {
-		log.Info(
-			"failed to get logs by user id",
-			zap.String("userId", userId.String()),
-			zap.Error(err),
-		)
-
-		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
@@ -63,5 +50,5 @@ func GetCalorieLogsHandler(w http.ResponseWriter, r *http.Request) {
 
 	resp.Code[http.StatusOK] = "OK"
 	resp.Data = data
-	json.NewEncoder(w).Encode(&resp)
+	writeResponse(w, &resp)
 }
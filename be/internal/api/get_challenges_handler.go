@@ -0,0 +1,81 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type GetChallengesResp struct {
+	Challenges []lib.Challenge `json:"challenges"`
+}
+
+func (s *Service) GetChallengesHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the profile id from the context
+	profileId, ok := r.Context().Value(ProfileIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"profile id not found in context",
+		)
+
+		// Profile id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	groupId, err := uuid.Parse(r.URL.Query().Get("group_id"))
+	if err != nil {
+		resp.Code[http.StatusBadRequest] = "Invalid group id."
+		writeResponse(w, &resp)
+		return
+	}
+
+	isMember, err := s.Groups.IsGroupMember(r.Context(), groupId, profileId)
+	if err != nil {
+		log.Info(
+			"failed to check group member by id",
+			zap.String("groupId", groupId.String()),
+			zap.String("profileId", profileId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if !*isMember {
+		resp.Code[http.StatusNotFound] = "Group not found."
+		writeResponse(w, &resp)
+		return
+	}
+
+	challenges, err := s.Challenges.GetChallenges(r.Context(), groupId)
+	if err != nil {
+		log.Info(
+			"failed to get challenges by group id",
+			zap.String("groupId", groupId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	data := &GetChallengesResp{
+		Challenges: challenges,
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const coachAccessLogLimit = 200
+
+type GetCoachAccessLogResp struct {
+	Accesses []lib.CoachAccess `json:"accesses"`
+}
+
+func (s *Service) GetCoachAccessLogHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the user id from the context
+	userId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	accesses, err := s.Coaches.GetCoachAccessLog(r.Context(), userId, coachAccessLogLimit)
+	if err != nil {
+		log.Info(
+			"failed to get coach access log by user id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	data := &GetCoachAccessLogResp{
+		Accesses: accesses,
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type GetCoachSharesResp struct {
+	Shares []lib.CoachShare `json:"shares"`
+}
+
+// GetCoachesHandler lists the coaches the user shares their data with.
+func (s *Service) GetCoachesHandler(w http.ResponseWriter, r *http.Request) {
+	s.getCoachShares(w, r, s.Coaches.GetClientShares)
+}
+
+// GetCoachClientsHandler lists a coach's clients and open invites.
+func (s *Service) GetCoachClientsHandler(w http.ResponseWriter, r *http.Request) {
+	s.getCoachShares(w, r, s.Coaches.GetCoachShares)
+}
+
+func (s *Service) getCoachShares(
+	w http.ResponseWriter,
+	r *http.Request,
+	getShares func(context.Context, uuid.UUID) ([]lib.CoachShare, error),
+) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the user id from the context
+	userId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	shares, err := getShares(r.Context(), userId)
+	if err != nil {
+		log.Info(
+			"failed to get coach shares by user id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	data := &GetCoachSharesResp{
+		Shares: shares,
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
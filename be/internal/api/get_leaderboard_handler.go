@@ -0,0 +1,102 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type GetLeaderboardResp struct {
+	Challenge lib.Challenge          `json:"challenge"`
+	Entries   []lib.LeaderboardEntry `json:"entries"`
+}
+
+func (s *Service) GetLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the profile id from the context
+	profileId, ok := r.Context().Value(ProfileIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"profile id not found in context",
+		)
+
+		// Profile id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	challengeId, err := uuid.Parse(r.URL.Query().Get("id"))
+	if err != nil {
+		resp.Code[http.StatusBadRequest] = "Invalid challenge id."
+		writeResponse(w, &resp)
+		return
+	}
+
+	challenge, err := s.Challenges.GetChallenge(r.Context(), challengeId)
+	if err != nil {
+		log.Info(
+			"failed to get challenge by id",
+			zap.String("challengeId", challengeId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if challenge == nil {
+		resp.Code[http.StatusNotFound] = "Challenge not found."
+		writeResponse(w, &resp)
+		return
+	}
+
+	isMember, err := s.Groups.IsGroupMember(r.Context(), challenge.GroupId, profileId)
+	if err != nil {
+		log.Info(
+			"failed to check group member by id",
+			zap.String("groupId", challenge.GroupId.String()),
+			zap.String("profileId", profileId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if !*isMember {
+		resp.Code[http.StatusNotFound] = "Challenge not found."
+		writeResponse(w, &resp)
+		return
+	}
+
+	entries, err := lib.GetLeaderboard(r.Context(), s.Challenges, *challenge, profileId)
+	if err != nil {
+		log.Info(
+			"failed to get leaderboard by challenge id",
+			zap.String("challengeId", challengeId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	data := &GetLeaderboardResp{
+		Challenge: *challenge,
+		Entries:   entries,
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+type GetLogCommentsResp struct {
+	Comments []lib.LogComment `json:"comments"`
+}
+
+func (s *Service) GetLogCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	s.getLogComments(w, r, lib.LogCommentFilter{
+		LogDate:   query.Get("log_date"),
+		WeekStart: query.Get("week_start"),
+	})
+}
+
+// GetNewFeedbackHandler lists the comments the user hasn't read yet.
+func (s *Service) GetNewFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	s.getLogComments(w, r, lib.LogCommentFilter{
+		UnreadOnly: true,
+	})
+}
+
+func (s *Service) getLogComments(w http.ResponseWriter, r *http.Request, filter lib.LogCommentFilter) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	_, profileId, viewerId, coachId, ok := commentParticipants(r)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if filter.WeekStart != "" {
+		if _, err := lib.WeekStartOf(filter.WeekStart); err != nil {
+			resp.Code[http.StatusBadRequest] = "Invalid week start date."
+			writeResponse(w, &resp)
+			return
+		}
+	}
+
+	filter.CoachId = coachId
+
+	comments, err := s.LogComments.GetLogComments(r.Context(), profileId, viewerId, filter)
+	if err != nil {
+		log.Info(
+			"failed to get log comments by profile id",
+			zap.String("profileId", profileId.String()),
+			zap.String("userId", viewerId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	data := &GetLogCommentsResp{
+		Comments: comments,
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
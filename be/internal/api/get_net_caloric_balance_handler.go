@@ -1,11 +1,10 @@
 package api
 
 import (
-	"calometer/internal/lib"
-	"encoding/json"
 	"math"
 	"net/http"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -13,59 +12,46 @@ type GetNetCaloricBalanceHandlerResp struct {
 	NetCaloricBalance float64 `json:"net_caloric_balance"`
 }
 
-func GetNetCaloricBalanceHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Service) GetNetCaloricBalanceHandler(w http.ResponseWriter, r *http.Request) {
 	resp := Response{}
 	resp.Code = make(map[int]string)
 
-	// Retrieve the token from the context
-	tokenStr, ok := r.Context().Value(TokenContextKey).(string)
+	// Retrieve the profile id from the context
+	profileId, ok := r.Context().Value(ProfileIdContextKey).(uuid.UUID)
 	if !ok {
 		log.Info(
-			"token not found in context",
+			"profile id not found in context",
 		)
 
-		// Token is not present in context
+		// Profile id is not present in context
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	userId, err := lib.ExtractUserIdFromToken(tokenStr)
-	if err != nil {
-		log.Info(
-			"failed to get user id by token",
-			zap.String("userId", userId.String()),
-			zap.Error(err),
-		)
-
-		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
-		return
-	}
-
-	netCaloricBalance, err := lib.GetNetCaloricBalance(*userId)
+	netCaloricBalance, err := s.Balances.GetNetCaloricBalance(r.Context(), profileId)
 	if err != nil {
 		log.Info(
 			"failed to get net caloric balance by id",
-			zap.String("userId", userId.String()),
+			zap.String("profileId", profileId.String()),
 			zap.Error(err),
 		)
 
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	weightGoal, err := lib.GetUserWeightGoalById(*userId)
+	weightGoal, err := s.Users.GetUserWeightGoalById(r.Context(), profileId)
 	if err != nil {
 		log.Info(
 			"failed to get user weight goal by id",
-			zap.String("userId", userId.String()),
+			zap.String("profileId", profileId.String()),
 			zap.Error(err),
 		)
 
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
@@ -83,5 +69,5 @@ func GetNetCaloricBalanceHandler(w http.ResponseWriter, r *http.Request) {
 	resp.Data = data
 
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(&resp)
+	writeResponse(w, &resp)
 }
@@ -0,0 +1,16 @@
+package api
+
+import (
+	"net/http"
+)
+
+// HealthzHandler tells the orchestrator the process is alive. It checks
+// nothing else, a database outage shouldn't get the server restarted.
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	w.WriteHeader(http.StatusOK)
+	resp.Code[http.StatusOK] = "OK"
+	writeResponse(w, &resp)
+}
@@ -0,0 +1,776 @@
+package api
+
+import (
+	"bytes"
+	"calometer/internal/config"
+	"calometer/internal/db"
+	"calometer/internal/lib"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// testDatabaseURLEnv points the integration suite at a Postgres database it
+// may write to. Without it the suite only runs against SQLite.
+const testDatabaseURLEnv = "TEST_DB_URL"
+
+const integrationPassword = "correct horse battery staple"
+
+// integrationBackend is a migrated database and the stores on top of it.
+type integrationBackend struct {
+	cfg           config.DatabaseConfig
+	store         lib.Store
+	loginAttempts lib.LoginAttemptStore
+	database      db.Health
+}
+
+func openSqliteBackend(t *testing.T) integrationBackend {
+	t.Helper()
+
+	cfg := config.DatabaseConfig{
+		Driver:         "sqlite",
+		SQLitePath:     filepath.Join(t.TempDir(), "calometer.db"),
+		MigrateOnStart: true,
+	}
+
+	conn, err := db.InitSQLite(cfg)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.CloseSQLite(conn) })
+
+	if _, err := db.CheckSchema(context.Background(), cfg, nil); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	return integrationBackend{
+		cfg:           cfg,
+		store:         lib.NewSqliteStore(conn),
+		loginAttempts: lib.NewSqliteLoginAttemptStore(conn),
+		database:      db.NewSqliteHealth(conn),
+	}
+}
+
+func openPostgresBackend(t *testing.T) integrationBackend {
+	t.Helper()
+
+	url := os.Getenv(testDatabaseURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set", testDatabaseURLEnv)
+	}
+
+	cfg := config.DatabaseConfig{
+		Driver:         "postgres",
+		URL:            url,
+		MigrateOnStart: true,
+	}
+
+	pool, err := db.Init(cfg)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(pool) })
+
+	if _, err := db.CheckSchema(context.Background(), cfg, pool); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	return integrationBackend{
+		cfg:           cfg,
+		store:         lib.NewPgStore(pool),
+		loginAttempts: lib.NewPostgresLoginAttemptStore(pool),
+		database:      db.NewPgHealth(pool),
+	}
+}
+
+// TestIntegration runs the same requests through the whole router against
+// every backend, so neither can fall behind the other.
+func TestIntegration(t *testing.T) {
+	backends := []struct {
+		name string
+		open func(t *testing.T) integrationBackend
+	}{
+		{name: "sqlite", open: openSqliteBackend},
+		{name: "postgres", open: openPostgresBackend},
+	}
+
+	scenarios := []struct {
+		name string
+		run  func(t *testing.T, env *integrationEnv)
+	}{
+		{name: "session", run: testSessionScenario},
+		{name: "calorie logs", run: testCalorieLogScenario},
+		{name: "profiles", run: testProfileScenario},
+		{name: "access tokens", run: testAccessTokenScenario},
+		{name: "two factor", run: testTwoFactorScenario},
+		{name: "coaching", run: testCoachingScenario},
+		{name: "groups", run: testGroupScenario},
+		{name: "admin", run: testAdminScenario},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			env := newIntegrationEnv(t, backend.open(t))
+
+			for _, scenario := range scenarios {
+				t.Run(scenario.name, func(t *testing.T) {
+					scenario.run(t, env)
+				})
+			}
+		})
+	}
+}
+
+// integrationEnv is a server backed by one database. Postgres isn't reset
+// between runs, so usernames carry a suffix unique to the run.
+type integrationEnv struct {
+	server   *httptest.Server
+	store    lib.Store
+	suffix   string
+	clientIP string
+}
+
+func newIntegrationEnv(t *testing.T, backend integrationBackend) *integrationEnv {
+	t.Helper()
+
+	cfg := testConfig()
+	cfg.Database = backend.cfg
+	// Exercise the database's login attempt store as well
+	cfg.LoginThrottle.Store = backend.cfg.Driver
+	cfg.TrustProxyHeaders = true
+
+	service, err := NewService(cfg, NewStores(backend.store, backend.loginAttempts, backend.database))
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	// Wrong codes still count, but don't hold up the next request
+	service.loginThrottle.BaseDelay = 0
+
+	server := httptest.NewServer(SetupRouter(service))
+	t.Cleanup(server.Close)
+
+	run := uuid.New()
+
+	return &integrationEnv{
+		server: server,
+		store:  backend.store,
+		suffix: run.String()[:8],
+		// Failures from earlier runs against the same database are kept
+		// per address, every run comes from its own
+		clientIP: fmt.Sprintf("10.%d.%d.%d", run[0], run[1], run[2]),
+	}
+}
+
+// apiClient is one user's browser, cookies included.
+type apiClient struct {
+	t      *testing.T
+	env    *integrationEnv
+	client *http.Client
+
+	Username string
+	UserId   uuid.UUID
+	// Bearer is sent instead of the session cookie when set
+	Bearer string
+	// ProfileId selects a profile other than the default one when set
+	ProfileId string
+}
+
+func (env *integrationEnv) newClient(t *testing.T) *apiClient {
+	t.Helper()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("failed to create cookie jar: %v", err)
+	}
+
+	return &apiClient{t: t, env: env, client: &http.Client{Jar: jar}}
+}
+
+// signUp creates a user named name plus the run's suffix and logs them in.
+func (env *integrationEnv) signUp(t *testing.T, name string) *apiClient {
+	t.Helper()
+
+	c := env.newClient(t)
+	c.Username = name + "-" + env.suffix
+
+	var signup SignupHandlerResp
+	c.call(http.MethodPost, "/api/users/signup", SignupHandlerReq{
+		Name:     name,
+		Username: c.Username,
+		Password: integrationPassword,
+	}, http.StatusOK, &signup)
+	c.UserId = signup.UserId
+
+	c.login(http.StatusOK)
+
+	return c
+}
+
+// setRole changes the user's role in the database and logs in again so the
+// new token carries it.
+func (c *apiClient) setRole(role string) {
+	c.t.Helper()
+
+	if _, err := c.env.store.SetUserRole(context.Background(), c.UserId, role); err != nil {
+		c.t.Fatalf("failed to set role: %v", err)
+	}
+
+	c.login(http.StatusOK)
+}
+
+func (c *apiClient) login(code int) *LoginHandlerResp {
+	c.t.Helper()
+
+	return c.loginWith(integrationPassword, code)
+}
+
+// loginWith logs in with password. Wrong passwords are left out of the
+// suite, the throttle would slow down every login from the same address
+// after them.
+func (c *apiClient) loginWith(password string, code int) *LoginHandlerResp {
+	c.t.Helper()
+
+	// Login short-circuits for a valid session, start from a fresh one
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		c.t.Fatalf("failed to create cookie jar: %v", err)
+	}
+	c.client.Jar = jar
+
+	var login LoginHandlerResp
+	c.call(http.MethodPost, "/api/users/login", LoginHandlerReq{
+		Username: c.Username,
+		Password: password,
+	}, code, &login)
+
+	return &login
+}
+
+// call sends body as JSON, checks the response carries code and decodes its
+// data into data when given.
+func (c *apiClient) call(method string, path string, body interface{}, code int, data interface{}) Response {
+	c.t.Helper()
+
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			c.t.Fatalf("failed to encode request: %v", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, c.env.server.URL+path, &reqBody)
+	if err != nil {
+		c.t.Fatalf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("X-Forwarded-For", c.env.clientIP)
+	if c.Bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Bearer)
+	}
+	if c.ProfileId != "" {
+		req.Header.Set(ProfileIdHeader, c.ProfileId)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer res.Body.Close()
+
+	resp := Response{}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		c.t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+	}
+
+	if _, ok := resp.Code[code]; !ok {
+		c.t.Fatalf("%s %s: response code = %v, want %d", method, path, resp.Code, code)
+	}
+
+	if data != nil {
+		encoded, err := json.Marshal(resp.Data)
+		if err != nil {
+			c.t.Fatalf("failed to encode response data: %v", err)
+		}
+
+		if err := json.Unmarshal(encoded, data); err != nil {
+			c.t.Fatalf("%s %s: failed to decode response data: %v", method, path, err)
+		}
+	}
+
+	return resp
+}
+
+// integrationDay is days before today, as the handlers expect log dates.
+func integrationDay(days int) time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day()-days, 0, 0, 0, 0, time.UTC)
+}
+
+// addBodyDetails lets the user start logging days.
+func (c *apiClient) addBodyDetails(weight float64) {
+	c.t.Helper()
+
+	c.call(http.MethodPost, "/api/users/body_details/add", AddBodyDetailsReq{
+		Age:       30,
+		Weight_kg: weight,
+		Height_cm: 180,
+		Gender:    "M",
+		Goal:      "L",
+	}, http.StatusOK, nil)
+}
+
+func (c *apiClient) expectBodyDetails(want bool) {
+	c.t.Helper()
+
+	var exists DoBodyDetailsExistResp
+	c.call(http.MethodGet, "/api/users/body_details/exists", nil, http.StatusOK, &exists)
+	if exists.Exists != want {
+		c.t.Fatalf("body details exist = %v, want %v", exists.Exists, want)
+	}
+}
+
+// completeDay logs a day days ago and marks it as done.
+func (c *apiClient) completeDay(days int, consumed float64) {
+	c.t.Helper()
+
+	logDate := integrationDay(days)
+
+	c.call(http.MethodPost, "/api/users/log/create", CreateCalorieLogReq{LogDate: logDate}, http.StatusOK, nil)
+	c.call(http.MethodPut, "/api/users/log/update", UpdateCalorieLogReq{
+		CaloriesConsumed: consumed,
+		LogDate:          logDate,
+	}, http.StatusOK, nil)
+	c.call(http.MethodPost, "/api/users/log/mark_status", MarkLoggingStatusReq{
+		Status:  "D",
+		LogDate: logDate,
+	}, http.StatusOK, nil)
+}
+
+func testSessionScenario(t *testing.T, env *integrationEnv) {
+	user := env.signUp(t, "session")
+
+	user.call(http.MethodGet, "/readyz", nil, http.StatusOK, nil)
+
+	user.call(http.MethodPost, "/api/users/token/refresh", nil, http.StatusOK, nil)
+	user.call(http.MethodGet, "/api/users/profiles/get", nil, http.StatusOK, nil)
+
+	user.call(http.MethodPost, "/api/users/logout", nil, http.StatusOK, nil)
+	user.call(http.MethodPost, "/api/users/token/refresh", nil, http.StatusUnauthorized, nil)
+	user.call(http.MethodGet, "/api/users/profiles/get", nil, http.StatusUnauthorized, nil)
+
+	user.login(http.StatusOK)
+	other := env.newClient(t)
+	other.Username = user.Username
+	other.login(http.StatusOK)
+
+	user.call(http.MethodPost, "/api/users/password/change", ChangePasswordReq{
+		CurrentPassword: integrationPassword,
+		NewPassword:     integrationPassword + " again",
+	}, http.StatusOK, nil)
+
+	// Changing the password ends every other session
+	other.call(http.MethodPost, "/api/users/token/refresh", nil, http.StatusUnauthorized, nil)
+	other.loginWith(integrationPassword+" again", http.StatusOK)
+}
+
+func testCalorieLogScenario(t *testing.T, env *integrationEnv) {
+	user := env.signUp(t, "logger")
+
+	user.expectBodyDetails(false)
+	user.addBodyDetails(80)
+	user.expectBodyDetails(true)
+	user.call(http.MethodPost, "/api/users/weight_goal/set", SetUserWeightGoalReq{Goal: "G"}, http.StatusOK, nil)
+
+	var weighIns GetWeighInsResp
+	user.call(http.MethodGet, "/api/users/weigh_ins/get", nil, http.StatusOK, &weighIns)
+	if len(weighIns.WeighIns) != 1 || weighIns.WeighIns[0].WeightKg != 80 {
+		t.Fatalf("weigh-ins = %+v, want one of 80 kg", weighIns.WeighIns)
+	}
+
+	user.completeDay(1, 1500)
+
+	logDate := integrationDay(1)
+	user.call(http.MethodPost, "/api/users/log/create", CreateCalorieLogReq{LogDate: logDate}, http.StatusConflict, nil)
+	user.call(http.MethodPut, "/api/users/log/update", UpdateCalorieLogReq{
+		CaloriesConsumed: 100,
+		LogDate:          logDate,
+	}, http.StatusConflict, nil)
+
+	var logs GetCaloricLogsHandlerResp
+	user.call(http.MethodGet, "/api/users/log/get", nil, http.StatusOK, &logs)
+	count := 0
+	for _, monthly := range logs.MonthlyLogs {
+		count += len(monthly)
+	}
+	if count != 1 {
+		t.Fatalf("got %d logs, want 1", count)
+	}
+
+	var balance GetNetCaloricBalanceHandlerResp
+	user.call(http.MethodGet, "/api/users/net_caloric_balance/get", nil, http.StatusOK, &balance)
+	if balance.NetCaloricBalance == 0 {
+		t.Fatal("net caloric balance is 0 after a completed day")
+	}
+
+	var achievements GetAchievementsResp
+	user.call(http.MethodGet, "/api/users/achievements/get", nil, http.StatusOK, &achievements)
+	earned := 0
+	for _, achievement := range achievements.Achievements {
+		if achievement.EarnedOn != nil {
+			earned++
+		}
+	}
+	if earned == 0 {
+		t.Fatal("no badge earned after the first weigh-in and completed day")
+	}
+
+	// What the admin command runs after the rules change
+	if _, err := lib.RecomputeAllAchievements(context.Background(), env.store); err != nil {
+		t.Fatalf("failed to recompute achievements: %v", err)
+	}
+
+	var streaks lib.Streaks
+	user.call(http.MethodGet, "/api/users/streaks/get", nil, http.StatusOK, &streaks)
+	if streaks.Longest != 1 {
+		t.Fatalf("longest streak = %d, want 1", streaks.Longest)
+	}
+
+	user.call(http.MethodPost, "/api/users/log/mark_status", MarkLoggingStatusReq{
+		Status:  "P",
+		LogDate: logDate,
+	}, http.StatusOK, nil)
+	user.call(http.MethodDelete, "/api/users/log/delete", DeleteCalorieLogReq{LogDate: logDate}, http.StatusOK, nil)
+
+	var remaining GetCaloricLogsHandlerResp
+	user.call(http.MethodGet, "/api/users/log/get", nil, http.StatusOK, &remaining)
+	for _, monthly := range remaining.MonthlyLogs {
+		if len(monthly) != 0 {
+			t.Fatalf("logs = %+v after deleting the only one", remaining.MonthlyLogs)
+		}
+	}
+}
+
+func testProfileScenario(t *testing.T, env *integrationEnv) {
+	user := env.signUp(t, "family")
+
+	var created CreateProfileResp
+	user.call(http.MethodPost, "/api/users/profiles/create", CreateProfileReq{Name: "Kid"}, http.StatusOK, &created)
+	user.call(http.MethodPut, "/api/users/profiles/update", UpdateProfileReq{Id: created.Id, Name: "Teen"}, http.StatusOK, nil)
+
+	var profiles GetProfilesResp
+	user.call(http.MethodGet, "/api/users/profiles/get", nil, http.StatusOK, &profiles)
+	if len(profiles.Profiles) != 2 {
+		t.Fatalf("got %d profiles, want 2", len(profiles.Profiles))
+	}
+
+	// Data logged for the second profile stays with it
+	user.ProfileId = created.Id.String()
+	user.addBodyDetails(60)
+	user.completeDay(1, 1800)
+
+	user.ProfileId = ""
+	user.expectBodyDetails(false)
+
+	// Someone else's profile can't be selected
+	other := env.signUp(t, "stranger")
+	other.ProfileId = created.Id.String()
+	other.call(http.MethodGet, "/api/users/log/get", nil, http.StatusNotFound, nil)
+
+	user.call(http.MethodDelete, "/api/users/profiles/delete", DeleteProfileReq{Id: created.Id}, http.StatusOK, nil)
+	user.call(http.MethodDelete, "/api/users/profiles/delete", DeleteProfileReq{Id: user.UserId}, http.StatusBadRequest, nil)
+}
+
+func testAccessTokenScenario(t *testing.T, env *integrationEnv) {
+	user := env.signUp(t, "scripter")
+	user.addBodyDetails(75)
+
+	var created CreateAccessTokenResp
+	user.call(http.MethodPost, "/api/users/access_token/create", CreateAccessTokenReq{
+		Name:   "export",
+		Scopes: []string{lib.ScopeLogsRead},
+	}, http.StatusOK, &created)
+
+	var tokens GetAccessTokensResp
+	user.call(http.MethodGet, "/api/users/access_token/get", nil, http.StatusOK, &tokens)
+	if len(tokens.AccessTokens) != 1 {
+		t.Fatalf("got %d access tokens, want 1", len(tokens.AccessTokens))
+	}
+
+	script := env.newClient(t)
+	script.Bearer = created.Token
+	script.call(http.MethodGet, "/api/users/log/get", nil, http.StatusOK, nil)
+	script.call(http.MethodPost, "/api/users/log/create", CreateCalorieLogReq{LogDate: integrationDay(1)}, http.StatusForbidden, nil)
+	script.call(http.MethodGet, "/api/users/access_token/get", nil, http.StatusForbidden, nil)
+
+	user.call(http.MethodDelete, "/api/users/access_token/revoke", RevokeAccessTokenReq{Id: created.Id}, http.StatusOK, nil)
+	script.call(http.MethodGet, "/api/users/log/get", nil, http.StatusUnauthorized, nil)
+}
+
+func testTwoFactorScenario(t *testing.T, env *integrationEnv) {
+	user := env.signUp(t, "careful")
+
+	var enrollment EnrollTOTPResp
+	user.call(http.MethodPost, "/api/users/2fa/enroll", nil, http.StatusOK, &enrollment)
+
+	now := time.Now()
+
+	var verified VerifyTOTPResp
+	user.call(http.MethodPost, "/api/users/2fa/verify", VerifyTOTPReq{
+		Code: integrationTOTPCode(t, enrollment.Secret, now),
+	}, http.StatusOK, &verified)
+	if len(verified.RecoveryCodes) == 0 {
+		t.Fatal("no recovery codes after enabling two-factor authentication")
+	}
+
+	login := user.login(http.StatusAccepted)
+	if !login.TwoFactorRequired {
+		t.Fatal("login didn't ask for a second factor")
+	}
+
+	// The step the enrollment used can't be replayed
+	user.call(http.MethodPost, "/api/users/login/2fa", LoginTwoFactorReq{
+		ChallengeToken: login.ChallengeToken,
+		Code:           integrationTOTPCode(t, enrollment.Secret, now),
+	}, http.StatusUnauthorized, nil)
+	user.call(http.MethodPost, "/api/users/login/2fa", LoginTwoFactorReq{
+		ChallengeToken: login.ChallengeToken,
+		RecoveryCode:   verified.RecoveryCodes[0],
+	}, http.StatusOK, nil)
+
+	// Recovery codes only work once
+	login = user.login(http.StatusAccepted)
+	user.call(http.MethodPost, "/api/users/login/2fa", LoginTwoFactorReq{
+		ChallengeToken: login.ChallengeToken,
+		RecoveryCode:   verified.RecoveryCodes[0],
+	}, http.StatusUnauthorized, nil)
+	user.call(http.MethodPost, "/api/users/login/2fa", LoginTwoFactorReq{
+		ChallengeToken: login.ChallengeToken,
+		RecoveryCode:   verified.RecoveryCodes[1],
+	}, http.StatusOK, nil)
+
+	user.call(http.MethodPost, "/api/users/2fa/disable", DisableTOTPReq{
+		Password: integrationPassword,
+		Code:     integrationTOTPCode(t, enrollment.Secret, now.Add(30*time.Second)),
+	}, http.StatusOK, nil)
+	user.login(http.StatusOK)
+}
+
+// integrationTOTPCode is what an authenticator app shows for secret at now.
+func integrationTOTPCode(t *testing.T, secret string, now time.Time) string {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("failed to decode secret: %v", err)
+	}
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(now.Unix()/30))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+func testCoachingScenario(t *testing.T, env *integrationEnv) {
+	client := env.signUp(t, "client")
+	client.addBodyDetails(90)
+	client.completeDay(1, 2000)
+
+	coach := env.signUp(t, "coach")
+	coach.setRole(lib.RoleCoach)
+
+	var invite InviteCoachResp
+	client.call(http.MethodPost, "/api/users/coach/invite", InviteCoachReq{CoachUsername: coach.Username}, http.StatusOK, &invite)
+	client.call(http.MethodPost, "/api/users/coach/invite", InviteCoachReq{CoachUsername: coach.Username}, http.StatusConflict, nil)
+
+	clientPath := "/api/coach/clients/" + client.UserId.String()
+
+	// Nothing is readable before the invite is accepted
+	coach.call(http.MethodGet, clientPath+"/log/get", nil, http.StatusForbidden, nil)
+
+	var clients GetCoachSharesResp
+	coach.call(http.MethodGet, "/api/coach/clients/get", nil, http.StatusOK, &clients)
+	if len(clients.Shares) != 1 || clients.Shares[0].AcceptedAt != nil {
+		t.Fatalf("coach shares = %+v, want one open invite", clients.Shares)
+	}
+
+	coach.call(http.MethodPost, "/api/coach/clients/accept", CoachShareReq{Id: invite.Id}, http.StatusOK, nil)
+	coach.call(http.MethodGet, clientPath+"/log/get", nil, http.StatusOK, nil)
+	coach.call(http.MethodGet, clientPath+"/weigh_ins/get", nil, http.StatusOK, nil)
+	coach.call(http.MethodGet, clientPath+"/net_caloric_balance/get", nil, http.StatusOK, nil)
+
+	var comment LogCommentResp
+	coach.call(http.MethodPost, clientPath+"/comments/create", CreateLogCommentReq{
+		LogDate: integrationDay(1).Format("2006-01-02"),
+		Body:    "Nice work",
+	}, http.StatusOK, &comment)
+	coach.call(http.MethodPost, clientPath+"/comments/create", CreateLogCommentReq{
+		LogDate: integrationDay(2).Format("2006-01-02"),
+		Body:    "Where is this day?",
+	}, http.StatusNotFound, nil)
+
+	var counts GetUnreadCommentCountsResp
+	client.call(http.MethodGet, "/api/users/comments/unread_counts", nil, http.StatusOK, &counts)
+	if len(counts.Counts) != 1 || counts.Counts[0].Unread != 1 {
+		t.Fatalf("unread counts = %+v, want one unread comment", counts.Counts)
+	}
+
+	var feedback GetLogCommentsResp
+	client.call(http.MethodGet, "/api/users/comments/new", nil, http.StatusOK, &feedback)
+	if len(feedback.Comments) != 1 || feedback.Comments[0].Body != "Nice work" {
+		t.Fatalf("new feedback = %+v, want the coach's comment", feedback.Comments)
+	}
+
+	client.call(http.MethodPost, "/api/users/comments/reply", ReplyLogCommentReq{ParentId: comment.Id, Body: "Thanks"}, http.StatusOK, nil)
+	client.call(http.MethodPost, "/api/users/comments/mark_read", MarkLogCommentsReadReq{Ids: []uuid.UUID{comment.Id}}, http.StatusOK, nil)
+
+	client.call(http.MethodGet, "/api/users/comments/unread_counts", nil, http.StatusOK, &counts)
+	if len(counts.Counts) != 0 {
+		t.Fatalf("unread counts = %+v after reading everything", counts.Counts)
+	}
+
+	coach.call(http.MethodGet, "/api/coach/comments/unread_counts", nil, http.StatusOK, &counts)
+	if len(counts.Counts) != 1 || counts.Counts[0].Unread != 1 {
+		t.Fatalf("coach unread counts = %+v, want the client's reply", counts.Counts)
+	}
+
+	var comments GetLogCommentsResp
+	coach.call(http.MethodGet, clientPath+"/comments/get", nil, http.StatusOK, &comments)
+	if len(comments.Comments) != 2 {
+		t.Fatalf("got %d comments, want the comment and its reply", len(comments.Comments))
+	}
+
+	var accessLog GetCoachAccessLogResp
+	client.call(http.MethodGet, "/api/users/coach/access_log/get", nil, http.StatusOK, &accessLog)
+	if len(accessLog.Accesses) == 0 {
+		t.Fatal("coach reads are missing from the access log")
+	}
+
+	var coaches GetCoachSharesResp
+	client.call(http.MethodGet, "/api/users/coach/get", nil, http.StatusOK, &coaches)
+	if len(coaches.Shares) != 1 || coaches.Shares[0].AcceptedAt == nil {
+		t.Fatalf("client shares = %+v, want one accepted share", coaches.Shares)
+	}
+
+	client.call(http.MethodDelete, "/api/users/coach/revoke", CoachShareReq{Id: invite.Id}, http.StatusOK, nil)
+	coach.call(http.MethodGet, clientPath+"/log/get", nil, http.StatusForbidden, nil)
+}
+
+func testGroupScenario(t *testing.T, env *integrationEnv) {
+	owner := env.signUp(t, "owner")
+	owner.addBodyDetails(85)
+
+	member := env.signUp(t, "member")
+	member.addBodyDetails(70)
+
+	var group CreateGroupResp
+	owner.call(http.MethodPost, "/api/groups/create", CreateGroupReq{Name: "Office"}, http.StatusOK, &group)
+
+	var groups GetGroupsResp
+	owner.call(http.MethodGet, "/api/groups/get", nil, http.StatusOK, &groups)
+	if len(groups.Groups) != 1 || !groups.Groups[0].IsOwner {
+		t.Fatalf("owner groups = %+v, want the group they own", groups.Groups)
+	}
+
+	member.call(http.MethodPost, "/api/groups/join", JoinGroupReq{InviteCode: "not a code"}, http.StatusNotFound, nil)
+	member.call(http.MethodPost, "/api/groups/join", JoinGroupReq{InviteCode: groups.Groups[0].InviteCode}, http.StatusOK, nil)
+
+	challengeReq := CreateChallengeReq{
+		GroupId:  group.Id,
+		Name:     "Log every day",
+		Metric:   lib.ChallengeMetricAdherence,
+		StartsOn: integrationDay(7).Format("2006-01-02"),
+		EndsOn:   integrationDay(0).Format("2006-01-02"),
+	}
+	member.call(http.MethodPost, "/api/groups/challenges/create", challengeReq, http.StatusNotFound, nil)
+
+	var challenge CreateChallengeResp
+	owner.call(http.MethodPost, "/api/groups/challenges/create", challengeReq, http.StatusOK, &challenge)
+
+	var challenges GetChallengesResp
+	member.call(http.MethodGet, "/api/groups/challenges/get?group_id="+group.Id.String(), nil, http.StatusOK, &challenges)
+	if len(challenges.Challenges) != 1 {
+		t.Fatalf("got %d challenges, want 1", len(challenges.Challenges))
+	}
+
+	owner.completeDay(1, 2000)
+	member.completeDay(1, 1800)
+	member.completeDay(2, 1800)
+
+	var leaderboard GetLeaderboardResp
+	owner.call(http.MethodGet, "/api/groups/challenges/leaderboard?id="+challenge.Id.String(), nil, http.StatusOK, &leaderboard)
+	if len(leaderboard.Entries) != 2 {
+		t.Fatalf("got %d leaderboard entries, want 2", len(leaderboard.Entries))
+	}
+
+	first, second := leaderboard.Entries[0], leaderboard.Entries[1]
+	if first.IsSelf || first.Score == nil || *first.Score != 2 || first.Rank != 1 {
+		t.Fatalf("first entry = %+v, want the member with 2 days", first)
+	}
+	if !second.IsSelf || second.Score == nil || *second.Score != 1 || second.Rank != 2 {
+		t.Fatalf("second entry = %+v, want the owner with 1 day", second)
+	}
+
+	member.call(http.MethodDelete, "/api/groups/leave", LeaveGroupReq{Id: group.Id}, http.StatusOK, nil)
+	member.call(http.MethodGet, "/api/groups/challenges/leaderboard?id="+challenge.Id.String(), nil, http.StatusNotFound, nil)
+
+	member.call(http.MethodGet, "/api/groups/get", nil, http.StatusOK, &groups)
+	if len(groups.Groups) != 0 {
+		t.Fatalf("member groups = %+v after leaving", groups.Groups)
+	}
+}
+
+func testAdminScenario(t *testing.T, env *integrationEnv) {
+	admin := env.signUp(t, "admin")
+	user := env.signUp(t, "managed")
+
+	user.call(http.MethodGet, "/api/admin/users/get", nil, http.StatusForbidden, nil)
+
+	admin.setRole(lib.RoleAdmin)
+
+	var users AdminGetUsersResp
+	admin.call(http.MethodGet, "/api/admin/users/get?q=managed-"+env.suffix, nil, http.StatusOK, &users)
+	if len(users.Users) != 1 || users.Users[0].Id != user.UserId {
+		t.Fatalf("admin users = %+v, want only %s", users.Users, user.Username)
+	}
+
+	admin.call(http.MethodPost, "/api/admin/users/disable", AdminDisableUserReq{UserId: user.UserId}, http.StatusOK, nil)
+	user.call(http.MethodPost, "/api/users/token/refresh", nil, http.StatusUnauthorized, nil)
+	user.login(http.StatusForbidden)
+
+	admin.call(http.MethodGet, "/api/admin/users/get?q=managed-"+env.suffix, nil, http.StatusOK, &users)
+	if len(users.Users) != 1 || users.Users[0].DisabledAt == nil {
+		t.Fatalf("admin users = %+v, want %s disabled", users.Users, user.Username)
+	}
+
+	admin.call(http.MethodPost, "/api/admin/users/enable", AdminDisableUserReq{UserId: user.UserId}, http.StatusOK, nil)
+	user.login(http.StatusOK)
+
+	admin.call(http.MethodPost, "/api/admin/users/role/set", AdminSetUserRoleReq{UserId: user.UserId, Role: lib.RoleCoach}, http.StatusOK, nil)
+	admin.call(http.MethodPost, "/api/admin/users/password/reset", AdminResetPasswordReq{
+		UserId:      user.UserId,
+		NewPassword: integrationPassword + " reset",
+	}, http.StatusOK, nil)
+	user.call(http.MethodPost, "/api/users/token/refresh", nil, http.StatusUnauthorized, nil)
+	user.loginWith(integrationPassword+" reset", http.StatusOK)
+}
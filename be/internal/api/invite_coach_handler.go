@@ -0,0 +1,98 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+type InviteCoachReq struct {
+	CoachUsername string `json:"coach_username"`
+}
+
+type InviteCoachResp struct {
+	Id uuid.UUID `json:"id"`
+}
+
+func (s *Service) InviteCoachHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the user id from the context
+	userId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	// The selected profile is the one shared with the coach
+	profileId, ok := r.Context().Value(ProfileIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"profile id not found in context",
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	var req InviteCoachReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Info(
+			"failed to decode incoming json",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
+		writeResponse(w, &resp)
+		return
+	}
+
+	shareId, err := s.Coaches.InviteCoach(r.Context(), userId, profileId, req.CoachUsername)
+	if err != nil {
+		if errors.Is(err, lib.ErrCoachNotFound) {
+			resp.Code[http.StatusNotFound] = "Coach not found."
+			writeResponse(w, &resp)
+			return
+		}
+
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+			resp.Code[http.StatusConflict] = "This coach has already been invited to this profile."
+			writeResponse(w, &resp)
+			return
+		}
+
+		log.Info(
+			"failed to invite coach by username",
+			zap.String("userId", userId.String()),
+			zap.String("coachUsername", req.CoachUsername),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	data := &InviteCoachResp{
+		Id: *shareId,
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type JoinGroupReq struct {
+	InviteCode string `json:"invite_code"`
+}
+
+type JoinGroupResp struct {
+	Id uuid.UUID `json:"id"`
+}
+
+func (s *Service) JoinGroupHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the profile id from the context
+	profileId, ok := r.Context().Value(ProfileIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"profile id not found in context",
+		)
+
+		// Profile id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	var req JoinGroupReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Info(
+			"failed to decode incoming json",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
+		writeResponse(w, &resp)
+		return
+	}
+
+	inviteCode := strings.TrimSpace(req.InviteCode)
+	if inviteCode == "" {
+		resp.Code[http.StatusBadRequest] = "Please enter an invite code."
+		writeResponse(w, &resp)
+		return
+	}
+
+	groupId, err := s.Groups.JoinGroup(r.Context(), profileId, inviteCode)
+	if err != nil {
+		if err == lib.ErrGroupNotFound {
+			resp.Code[http.StatusNotFound] = "No group found for this invite code."
+			writeResponse(w, &resp)
+			return
+		}
+
+		log.Info(
+			"failed to join group by profile id",
+			zap.String("profileId", profileId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	data := &JoinGroupResp{
+		Id: *groupId,
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
@@ -0,0 +1,16 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// JWKSHandler publishes the public signing keys in the standard JWK Set
+// format, so it is not wrapped in the usual Response envelope.
+func (s *Service) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(s.keys.JWKS())
+}
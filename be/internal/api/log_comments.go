@@ -0,0 +1,46 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// commentParticipants returns whose profile's comments are accessed and who
+// is looking at them. On coach routes the client and profile come from the
+// share and the coach only sees their own threads.
+func commentParticipants(r *http.Request) (clientId uuid.UUID, profileId uuid.UUID, viewerId uuid.UUID, coachId *uuid.UUID, ok bool) {
+	clientId, ok = r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		return clientId, profileId, viewerId, nil, false
+	}
+
+	profileId, ok = r.Context().Value(ProfileIdContextKey).(uuid.UUID)
+	if !ok {
+		return clientId, profileId, viewerId, nil, false
+	}
+
+	if coach, isCoach := r.Context().Value(CoachIdContextKey).(uuid.UUID); isCoach {
+		return clientId, profileId, coach, &coach, true
+	}
+
+	return clientId, profileId, clientId, nil, true
+}
+
+func validateCommentBody(resp *Response, body string) bool {
+	body = strings.TrimSpace(body)
+
+	if body == "" {
+		resp.Code[http.StatusBadRequest] = "Comment can't be empty."
+		return false
+	}
+
+	if len([]rune(body)) > lib.MaxLogCommentLength {
+		resp.Code[http.StatusBadRequest] = "Comment is too long."
+		return false
+	}
+
+	return true
+}
@@ -4,8 +4,6 @@ import (
 	"calometer/internal/lib"
 	"encoding/json"
 	"net/http"
-	"os"
-	"time"
 
 	"go.uber.org/zap"
 )
@@ -16,21 +14,22 @@ type LoginHandlerReq struct {
 }
 
 type LoginHandlerResp struct {
-	Token string `json:"token"`
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
 }
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Service) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	resp := Response{}
 	resp.Code = make(map[int]string)
 
-	cookie, err := r.Cookie("token")
+	cookie, err := r.Cookie(tokenCookieName)
 	if err == nil {
 		// Validate the JWT
-		if err := lib.ValidateToken(cookie.Value); err == nil {
+		if err := s.keys.ValidateToken(cookie.Value); err == nil {
 			// Token is valid, return a success response
 			w.WriteHeader(http.StatusOK)
 			resp.Code[http.StatusOK] = "Logged in successfully."
-			json.NewEncoder(w).Encode(&resp)
+			writeResponse(w, &resp)
 			return
 		}
 	}
@@ -39,21 +38,29 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.Info("failed to decode incoming json")
 		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
 	if req.Username == "" && req.Password == "" {
 		log.Info("invalid input data")
 		resp.Code[http.StatusBadRequest] = "Please enter correct details."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	userId, err := lib.GetUserIdByUsername(req.Username)
+	ip := s.clientIP(r)
+	reservation, ok := s.reserveLoginAttempt(r.Context(), w, &resp, req.Username, ip)
+	if !ok {
+		return
+	}
+	defer s.releaseLoginAttempt(r.Context(), reservation)
+
+	userId, err := s.Users.GetUserIdByUsername(r.Context(), req.Username)
 	if err == nil && userId == nil {
+		s.recordLoginFailure(r.Context(), reservation)
 		resp.Code[http.StatusUnauthorized] = "Username or password is incorrect."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	} else if err != nil {
 		log.Info(
@@ -62,71 +69,122 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		)
 
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	exists, err := lib.DoesUserExists(req.Username)
+	exists, err := s.Users.DoesUserExists(r.Context(), req.Username)
 	if err != nil {
 		log.Info(
 			"failed to check user's existence by username",
 			zap.String("username", req.Username),
 		)
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
 	if !*exists {
+		s.recordLoginFailure(r.Context(), reservation)
 		resp.Code[http.StatusUnauthorized] = "Username or password is incorrect."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	passwordHash, err := lib.GetHashedPass(req.Username)
+	passwordHash, err := s.Users.GetPasswordHash(r.Context(), req.Username)
 	if err != nil {
 		log.Info(
 			"failed to fetch user's hashed password",
 			zap.String("username", req.Username),
 		)
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	if err := lib.CheckPasswordValidity(req.Password, passwordHash); err != nil {
+	if err := lib.CheckPasswordValidity(req.Password, *passwordHash); err != nil {
 		log.Info(
 			"failed to check password validity",
 			zap.String("username", req.Username),
 		)
+		s.recordLoginFailure(r.Context(), reservation)
 		resp.Code[http.StatusUnauthorized] = "Username or password is incorrect."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	token, err := lib.GenerateJWT(*userId, req.Username)
+	status, err := s.Users.GetUserStatusById(r.Context(), *userId)
 	if err != nil {
 		log.Info(
-			"failed to generate JWT for user id",
+			"failed to get user status by user id",
 			zap.String("userId", userId.String()),
-			zap.String("username", req.Username),
+			zap.Error(err),
+		)
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if status.Disabled {
+		resp.Code[http.StatusForbidden] = "This account has been disabled."
+		writeResponse(w, &resp)
+		return
+	}
+
+	totpEnabled, err := s.TwoFactor.IsTOTPEnabled(r.Context(), *userId)
+	if err != nil {
+		log.Info(
+			"failed to check two-factor authentication status by user id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
 		)
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
+		return
+	}
+
+	if *totpEnabled {
+		challengeToken, err := s.keys.GenerateTwoFactorChallengeToken(*userId, req.Username)
+		if err != nil {
+			log.Info(
+				"failed to generate two-factor challenge token for user id",
+				zap.String("userId", userId.String()),
+				zap.Error(err),
+			)
+			resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+			writeResponse(w, &resp)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+
+		data := &LoginHandlerResp{
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+		}
+
+		resp.Code[http.StatusAccepted] = "Two-factor authentication required."
+		resp.Data = data
+		writeResponse(w, &resp)
 		return
 	}
 
-	// Set the JWT as an HttpOnly cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "token",
-		Value:    token,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   os.Getenv("APP_ENV") == "production",
-		Expires:  time.Now().Add(time.Hour * 24),
-	})
+	s.recordLoginSuccess(r.Context(), reservation)
+
+	// Set the JWT and refresh token as HttpOnly cookies
+	if err := s.startSession(r.Context(), w, *userId, req.Username); err != nil {
+		log.Info(
+			"failed to start session for user id",
+			zap.String("userId", userId.String()),
+			zap.String("username", req.Username),
+			zap.Error(err),
+		)
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
 
 	w.WriteHeader(http.StatusOK)
 	resp.Code[http.StatusOK] = "Logged in successfully."
-	json.NewEncoder(w).Encode(&resp)
+	writeResponse(w, &resp)
 }
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestLoginHandlerRejectsBadCredentials(t *testing.T) {
+	service, store := newTestService(t, testConfig())
+	createTestUser(t, store, "alice", "correct horse")
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+	}{
+		{name: "unknown user", username: "bob", password: "correct horse"},
+		{name: "wrong password", username: "alice", password: "wrong horse"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newJSONRequest(t, http.MethodPost, "/api/users/login", LoginHandlerReq{
+				Username: tt.username,
+				Password: tt.password,
+			})
+			// Each case comes from its own address, so earlier failures
+			// don't throttle it
+			r.RemoteAddr = "192.0.2." + strconv.Itoa(i+1) + ":1234"
+			w := httptest.NewRecorder()
+
+			service.LoginHandler(w, r)
+
+			expectCode(t, w, http.StatusUnauthorized)
+
+			if cookies := w.Result().Cookies(); len(cookies) > 0 {
+				t.Fatalf("got session cookies %v", cookies)
+			}
+		})
+	}
+}
+
+func TestLoginHandlerRejectsDisabledUser(t *testing.T) {
+	service, store := newTestService(t, testConfig())
+	userId := createTestUser(t, store, "alice", "correct horse")
+
+	if _, err := store.SetUserDisabled(context.Background(), userId, true); err != nil {
+		t.Fatalf("failed to disable user: %v", err)
+	}
+
+	r := newJSONRequest(t, http.MethodPost, "/api/users/login", LoginHandlerReq{
+		Username: "alice",
+		Password: "correct horse",
+	})
+	w := httptest.NewRecorder()
+
+	service.LoginHandler(w, r)
+
+	expectCode(t, w, http.StatusForbidden)
+}
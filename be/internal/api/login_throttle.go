@@ -0,0 +1,89 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"calometer/internal/metrics"
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// reserveLoginAttempt counts the login attempt against the username and IP
+// before the credentials are checked. It writes a rejection and returns
+// false when either has to wait before trying again. The reservation has
+// to be settled, releaseLoginAttempt does so for attempts that neither
+// failed nor succeeded.
+func (s *Service) reserveLoginAttempt(ctx context.Context, w http.ResponseWriter, resp *Response, username string, ip string) (*lib.LoginReservation, bool) {
+	reservation, wait, err := s.loginThrottle.Reserve(ctx, username, ip)
+	if err != nil {
+		log.Info(
+			"failed to check login throttle",
+			zap.String("username", username),
+			zap.String("ip", ip),
+			zap.Error(err),
+		)
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, resp)
+		return nil, false
+	}
+
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		resp.Code[http.StatusTooManyRequests] = "Too many failed login attempts. Please try again later."
+		writeResponse(w, resp)
+		return nil, false
+	}
+
+	return reservation, true
+}
+
+func (s *Service) recordLoginFailure(ctx context.Context, reservation *lib.LoginReservation) {
+	metrics.FailedLogins.Inc()
+
+	// A client hanging up right after a wrong password must still count
+	ctx = context.WithoutCancel(ctx)
+
+	lockouts, err := reservation.Fail(ctx)
+	if err != nil {
+		log.Info(
+			"failed to record login failure",
+			zap.String("username", reservation.Username),
+			zap.String("ip", reservation.IP),
+			zap.Error(err),
+		)
+		return
+	}
+
+	for _, lockout := range lockouts {
+		log.Warn(
+			"login locked out after repeated failures",
+			zap.String("key", lockout.Key),
+			zap.Int("failures", lockout.Failures),
+			zap.Time("lockedUntil", lockout.LockedUntil),
+		)
+	}
+}
+
+func (s *Service) recordLoginSuccess(ctx context.Context, reservation *lib.LoginReservation) {
+	if err := reservation.Succeed(context.WithoutCancel(ctx)); err != nil {
+		log.Info(
+			"failed to reset login throttle",
+			zap.String("username", reservation.Username),
+			zap.Error(err),
+		)
+	}
+}
+
+func (s *Service) releaseLoginAttempt(ctx context.Context, reservation *lib.LoginReservation) {
+	if err := reservation.Release(context.WithoutCancel(ctx)); err != nil {
+		log.Info(
+			"failed to release login attempt",
+			zap.String("username", reservation.Username),
+			zap.String("ip", reservation.IP),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+type LoginTwoFactorReq struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code,omitempty"`
+	RecoveryCode   string `json:"recovery_code,omitempty"`
+}
+
+func (s *Service) LoginTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	var req LoginTwoFactorReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Info("failed to decode incoming json")
+		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if req.ChallengeToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		resp.Code[http.StatusBadRequest] = "Please enter correct details."
+		writeResponse(w, &resp)
+		return
+	}
+
+	userId, err := s.keys.ExtractUserIdFromChallengeToken(req.ChallengeToken)
+	if err != nil {
+		resp.Code[http.StatusUnauthorized] = "Login attempt expired. Please login again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	username, err := s.Users.GetUsernameById(r.Context(), *userId)
+	if err != nil {
+		log.Info(
+			"failed to get username by id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	// Guessing codes counts against the same limits as guessing passwords
+	ip := s.clientIP(r)
+	reservation, ok := s.reserveLoginAttempt(r.Context(), w, &resp, *username, ip)
+	if !ok {
+		return
+	}
+	defer s.releaseLoginAttempt(r.Context(), reservation)
+
+	valid, err := s.verifySecondFactor(r.Context(), *userId, req.Code, req.RecoveryCode)
+	if err != nil {
+		log.Info(
+			"failed to verify second factor by user id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if !valid {
+		s.recordLoginFailure(r.Context(), reservation)
+		resp.Code[http.StatusUnauthorized] = "Invalid code."
+		writeResponse(w, &resp)
+		return
+	}
+
+	s.recordLoginSuccess(r.Context(), reservation)
+
+	// Set the JWT and refresh token as HttpOnly cookies
+	if err := s.startSession(r.Context(), w, *userId, *username); err != nil {
+		if errors.Is(err, lib.ErrUserDisabled) {
+			resp.Code[http.StatusForbidden] = "This account has been disabled."
+			writeResponse(w, &resp)
+			return
+		}
+
+		log.Info(
+			"failed to start session for user id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	resp.Code[http.StatusOK] = "Logged in successfully."
+	writeResponse(w, &resp)
+}
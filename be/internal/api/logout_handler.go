@@ -1,27 +1,27 @@
 package api
 
 import (
-	"encoding/json"
 	"net/http"
-	"os"
-	"time"
+
+	"go.uber.org/zap"
 )
 
-func LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	// Set the "token" cookie with an expired date to delete it
-	http.SetCookie(w, &http.Cookie{
-		Name:     "token",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   os.Getenv("APP_ENV") == "production",
-		Expires:  time.Unix(0, 0), // Expire immediately
-		MaxAge:   -1,              // Alternatively, set MaxAge to -1 for immediate deletion
-	})
+func (s *Service) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	// Revoke the refresh token so it can't be used to mint new sessions
+	if cookie, err := r.Cookie(refreshTokenCookieName); err == nil {
+		if err := s.RefreshTokens.RevokeRefreshTokenFamily(r.Context(), cookie.Value); err != nil {
+			log.Info(
+				"failed to revoke refresh token family",
+				zap.Error(err),
+			)
+		}
+	}
+
+	s.clearSessionCookies(w)
 
 	resp := Response{}
 	resp.Code = make(map[int]string)
 	resp.Code[http.StatusOK] = "OK"
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(&resp)
+	writeResponse(w, &resp)
 }
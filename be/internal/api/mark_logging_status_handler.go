@@ -2,10 +2,13 @@ package api
 
 import (
 	"calometer/internal/lib"
+	"calometer/internal/metrics"
 	"encoding/json"
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
@@ -14,33 +17,25 @@ type MarkLoggingStatusReq struct {
 	LogDate time.Time `json:"log_date"`
 }
 
-func MarkLoggingStatusHandler(w http.ResponseWriter, r *http.Request) {
+type MarkLoggingStatusResp struct {
+	// Achievements are the badges this day newly earned
+	Achievements []lib.Achievement `json:"achievements"`
+}
+
+func (s *Service) MarkLoggingStatusHandler(w http.ResponseWriter, r *http.Request) {
 	resp := Response{}
 	resp.Code = make(map[int]string)
 
-	// Retrieve the token from the context
-	tokenStr, ok := r.Context().Value(TokenContextKey).(string)
+	// Retrieve the profile id from the context
+	profileId, ok := r.Context().Value(ProfileIdContextKey).(uuid.UUID)
 	if !ok {
 		log.Info(
-			"token not found in context",
-		)
-
-		// Token is not present in context
-		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
-		return
-	}
-
-	userId, err := lib.ExtractUserIdFromToken(tokenStr)
-	if err != nil {
-		log.Info(
-			"failed to get user id by token",
-			zap.String("userId", userId.String()),
-			zap.Error(err),
+			"profile id not found in context",
 		)
 
+		// Profile id is not present in context
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
@@ -52,80 +47,77 @@ func MarkLoggingStatusHandler(w http.ResponseWriter, r *http.Request) {
 		)
 
 		resp.Code[http.StatusBadRequest] = "Invalid JSON."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
 	logDate := req.LogDate.Format("2006-01-02")
 
-	if err := lib.MarkLoggingStatus(*userId, logDate, req.Status); err != nil {
+	if req.Status != "D" && req.Status != "P" {
+		resp.Code[http.StatusBadRequest] = "Invalid logging status."
+		writeResponse(w, &resp)
+		return
+	}
+
+	var err error
+	// finalized is only set when the day goes from pending to done, marking
+	// a done day again recalculates its balance but finalizes nothing
+	var finalized bool
+	if req.Status == "D" {
+		_, finalized, err = s.CalorieLogs.FinalizeCalorieLog(r.Context(), profileId, logDate)
+	} else {
+		err = s.CalorieLogs.ReopenCalorieLog(r.Context(), profileId, logDate)
+	}
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			resp.Code[http.StatusNotFound] = "No log found for this day."
+			writeResponse(w, &resp)
+			return
+		}
+
 		log.Info(
 			"failed to mark logging status by id and date",
-			zap.String("userId", userId.String()),
+			zap.String("profileId", profileId.String()),
 			zap.String("logDate", logDate),
+			zap.String("status", req.Status),
 			zap.Error(err),
 		)
 
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	calorieLogId, err := lib.GetCalorieLogId(*userId, logDate)
+	event := lib.EventDayReopened
+	if req.Status == "D" {
+		event = lib.EventDayCompleted
+	}
+
+	if finalized {
+		metrics.DaysFinalized.Inc()
+	}
+
+	// The day is saved either way, badges are awarded again on the next
+	// evaluation or recompute
+	achievements, err := s.Achievements.EvaluateAchievements(r.Context(), profileId, event)
 	if err != nil {
 		log.Info(
-			"failed to get calorie log by id and date",
-			zap.String("userId", userId.String()),
-			zap.String("logDate", logDate),
+			"failed to evaluate achievements by profile id",
+			zap.String("profileId", profileId.String()),
 			zap.Error(err),
 		)
 
-		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
-		return
+		achievements = []lib.Achievement{}
 	}
 
-	if req.Status == "D" {
-		caloricBalance, err := lib.CalculateCaloricBalanceForTheDay(*userId, logDate)
-		if err != nil {
-			log.Info(
-				"failed to calculate caloric balance by id and date",
-				zap.String("userId", userId.String()),
-				zap.String("logDate", logDate),
-				zap.Error(err),
-			)
-
-			resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-			json.NewEncoder(w).Encode(&resp)
-			return
-		}
-
-		if err := lib.AddCaloricBalanceForTheDay(*calorieLogId, *caloricBalance); err != nil {
-			log.Info(
-				"failed to add caloric balance by log id",
-				zap.String("logId", calorieLogId.String()),
-				zap.Error(err),
-			)
+	w.WriteHeader(http.StatusOK)
 
-			resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-			json.NewEncoder(w).Encode(&resp)
-			return
-		}
-	} else {
-		if err := lib.ResetCaloricBalanceForTheDay(*calorieLogId); err != nil {
-			log.Info(
-				"failed to reset caloric balance by log id",
-				zap.String("logId", calorieLogId.String()),
-				zap.Error(err),
-			)
-
-			resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-			json.NewEncoder(w).Encode(&resp)
-			return
-		}
+	data := &MarkLoggingStatusResp{
+		Achievements: achievements,
 	}
 
-	w.WriteHeader(http.StatusOK)
 	resp.Code[http.StatusOK] = "OK"
-	json.NewEncoder(w).Encode(&resp)
+	resp.Data = data
+	writeResponse(w, &resp)
 }
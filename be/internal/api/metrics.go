@@ -0,0 +1,92 @@
+package api
+
+import (
+	"calometer/internal/metrics"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// statusRecorder remembers the status a handler answered with. Handlers
+// report failures in the Response code with a 200, writeResponse records
+// that code here.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	// code is the Response code, 0 until one was written
+	code int
+	// wroteHeader is set once the first header or body went out, later
+	// ones don't change the status
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Status is the Response code when there was one, the HTTP status
+// otherwise.
+func (r *statusRecorder) Status() int {
+	if r.code != 0 {
+		return r.code
+	}
+
+	return r.status
+}
+
+// recordResponseCode finds the statusRecorder under w, if any, and sets its
+// code to the highest one in codes.
+func recordResponseCode(w http.ResponseWriter, codes map[int]string) {
+	for {
+		switch writer := w.(type) {
+		case *statusRecorder:
+			for code := range codes {
+				writer.code = max(writer.code, code)
+			}
+			return
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = writer.Unwrap()
+		default:
+			return
+		}
+	}
+}
+
+// MetricsMiddleware counts requests and their latency by route template.
+// It runs as router middleware, after a route matched, so requests no route
+// matches aren't counted.
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		next.ServeHTTP(recorder, r)
+
+		code := strconv.Itoa(recorder.Status())
+		metrics.HTTPRequests.WithLabelValues(route, r.Method, code).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(route, r.Method, code).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"calometer/internal/metrics"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	t.Helper()
+
+	var metric dto.Metric
+	if err := counter.Write(&metric); err != nil {
+		t.Fatalf("failed to read counter: %v", err)
+	}
+
+	return metric.GetCounter().GetValue()
+}
+
+func TestMetricsMiddlewareCountsResponseCode(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(MetricsMiddleware)
+	router.HandleFunc("/metrics-test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		resp := Response{}
+		resp.Code = make(map[int]string)
+		resp.Code[http.StatusNotFound] = "Not found."
+		writeResponse(w, &resp)
+	})
+
+	notFound := metrics.HTTPRequests.WithLabelValues("/metrics-test/{id}", http.MethodGet, "404")
+	before := counterValue(t, notFound)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics-test/1", nil))
+
+	if got := counterValue(t, notFound) - before; got != 1 {
+		t.Fatalf("counted %v requests as 404, want 1", got)
+	}
+}
+
+// noAchievements awards nothing, the MemoryStore keeps no achievements.
+type noAchievements struct {
+	lib.AchievementStore
+}
+
+func (noAchievements) EvaluateAchievements(ctx context.Context, profileId uuid.UUID, event string) ([]lib.Achievement, error) {
+	return []lib.Achievement{}, nil
+}
+
+func TestMarkLoggingStatusCountsDayFinalizedOnce(t *testing.T) {
+	service, store := newTestService(t, testConfig())
+	service.Achievements = noAchievements{}
+	userId := createTestUser(t, store, "alice", "correct horse")
+
+	logDate := time.Now().AddDate(0, 0, -1)
+	if err := store.CreateUserLog(context.Background(), userId, 2000, logDate.Format("2006-01-02")); err != nil {
+		t.Fatalf("failed to create log: %v", err)
+	}
+
+	before := counterValue(t, metrics.DaysFinalized)
+
+	// Marking a done day again must not count it twice
+	for i := 0; i < 2; i++ {
+		r := newJSONRequest(t, http.MethodPost, "/api/users/log/mark_status", MarkLoggingStatusReq{
+			Status:  "D",
+			LogDate: logDate,
+		})
+		r = r.WithContext(context.WithValue(r.Context(), ProfileIdContextKey, userId))
+		w := httptest.NewRecorder()
+
+		service.MarkLoggingStatusHandler(w, r)
+
+		expectCode(t, w, http.StatusOK)
+	}
+
+	if got := counterValue(t, metrics.DaysFinalized) - before; got != 1 {
+		t.Fatalf("counted %v finalized days, want 1", got)
+	}
+}
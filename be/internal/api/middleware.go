@@ -3,55 +3,360 @@ package api
 import (
 	"calometer/internal/lib"
 	"context"
-	"encoding/json"
+	"errors"
 	"net/http"
-	"os"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+	"go.uber.org/zap"
 )
 
 type contextKey string
 
 const TokenContextKey contextKey = "token"
 
+// ScopesContextKey is only set for requests authenticated with a personal
+// access token. Cookie sessions are not restricted by scopes.
+const ScopesContextKey contextKey = "scopes"
+
+// RoleContextKey holds the role of the authenticated user, taken from the
+// session token or looked up for access tokens.
+const RoleContextKey contextKey = "role"
+
+// CoachIdContextKey is set on the routes coaches use to read a client's data.
+// There UserIdContextKey and ProfileIdContextKey hold the client and their
+// shared profile, so the regular read handlers serve the client's data.
+const CoachIdContextKey contextKey = "coachId"
+
 type contextUserId string
 
 const UserIdContextKey contextUserId = "userId"
 
-func AuthMiddleWare(next http.Handler) http.Handler {
+type contextProfileId string
+
+// ProfileIdContextKey holds the profile whose data a request reads or writes.
+const ProfileIdContextKey contextProfileId = "profileId"
+
+// ProfileIdHeader selects one of the user's profiles, without it requests
+// use the default profile.
+const ProfileIdHeader = "X-Profile-Id"
+
+func (s *Service) AuthMiddleWare(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		resp := Response{}
 		resp.Code = make(map[int]string)
 
+		// Scripts and integrations authenticate with a personal access token
+		if bearer := lib.ExtractTokenFromHeader(r); strings.HasPrefix(bearer, lib.AccessTokenPrefix) {
+			auth, err := s.AccessTokens.AuthenticateAccessToken(r.Context(), bearer)
+			if err != nil {
+				if !errors.Is(err, lib.ErrAccessTokenInvalid) {
+					log.Info(
+						"failed to authenticate access token",
+						zap.Error(err),
+					)
+				}
+
+				resp.Code[http.StatusUnauthorized] = "Invalid access token."
+				writeResponse(w, &resp)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIdContextKey, auth.UserId)
+			ctx = context.WithValue(ctx, RoleContextKey, auth.Role)
+			ctx = context.WithValue(ctx, ScopesContextKey, auth.Scopes)
+			r = r.WithContext(ctx)
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		// Extract the token from the cookie
-		cookie, err := r.Cookie("token")
+		cookie, err := r.Cookie(tokenCookieName)
 		if err != nil {
 			// No cookie found
 			resp.Code[http.StatusUnauthorized] = "Session expired. Please login again."
-			json.NewEncoder(w).Encode(&resp)
+			writeResponse(w, &resp)
 			return
 		}
 
 		// Validate the JWT
-		if err := lib.ValidateToken(cookie.Value); err != nil {
+		if err := s.keys.ValidateToken(cookie.Value); err != nil {
 			// Token is invalid
 			resp.Code[http.StatusUnauthorized] = "Invalid token. Please login again."
-			json.NewEncoder(w).Encode(&resp)
+			writeResponse(w, &resp)
+			return
+		}
+
+		userId, err := s.keys.ExtractUserIdFromToken(cookie.Value)
+		if err != nil {
+			resp.Code[http.StatusUnauthorized] = "Invalid token. Please login again."
+			writeResponse(w, &resp)
+			return
+		}
+
+		role, err := s.keys.ExtractRoleFromToken(cookie.Value)
+		if err != nil {
+			resp.Code[http.StatusUnauthorized] = "Invalid token. Please login again."
+			writeResponse(w, &resp)
 			return
 		}
 
 		ctx := context.WithValue(r.Context(), TokenContextKey, cookie.Value)
+		ctx = context.WithValue(ctx, UserIdContextKey, *userId)
+		ctx = context.WithValue(ctx, RoleContextKey, role)
 		r = r.WithContext(ctx)
 
 		next.ServeHTTP(w, r)
 	})
 }
 
-func EnableCORS(next http.Handler) http.Handler {
+// SelectProfile puts the profile chosen with the X-Profile-Id header into the
+// context, after checking it belongs to the user.
+func (s *Service) SelectProfile(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", os.Getenv("FE_URL"))
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		resp := Response{}
+		resp.Code = make(map[int]string)
+
+		userId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+		if !ok {
+			log.Info(
+				"user id not found in context",
+			)
+
+			resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+			writeResponse(w, &resp)
+			return
+		}
+
+		// The default profile shares the user's id
+		profileId := userId
+
+		if header := r.Header.Get(ProfileIdHeader); header != "" {
+			selected, err := uuid.Parse(header)
+			if err != nil {
+				resp.Code[http.StatusBadRequest] = "Invalid profile id."
+				writeResponse(w, &resp)
+				return
+			}
+
+			if selected != userId {
+				belongs, err := s.Profiles.DoesProfileBelongToUser(r.Context(), userId, selected)
+				if err != nil {
+					log.Info(
+						"failed to check profile ownership by user id",
+						zap.String("userId", userId.String()),
+						zap.String("profileId", selected.String()),
+						zap.Error(err),
+					)
+
+					resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+					writeResponse(w, &resp)
+					return
+				}
+
+				if !*belongs {
+					resp.Code[http.StatusNotFound] = "Profile not found."
+					writeResponse(w, &resp)
+					return
+				}
+			}
+
+			profileId = selected
+		}
+
+		ctx := context.WithValue(r.Context(), ProfileIdContextKey, profileId)
+		r = r.WithContext(ctx)
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RequireScope rejects personal access tokens that were not granted scope.
+func RequireScope(scope string) alice.Constructor {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, ok := r.Context().Value(ScopesContextKey).([]string)
+			if ok && !slices.Contains(scopes, scope) {
+				resp := Response{}
+				resp.Code = make(map[int]string)
+				resp.Code[http.StatusForbidden] = "Access token is missing the " + scope + " scope."
+				writeResponse(w, &resp)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole only lets through users with one of the given roles.
+func RequireRole(roles ...string) alice.Constructor {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := r.Context().Value(RoleContextKey).(string)
+			if !slices.Contains(roles, role) {
+				resp := Response{}
+				resp.Code = make(map[int]string)
+				resp.Code[http.StatusForbidden] = "You are not allowed to access this resource."
+				writeResponse(w, &resp)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireCoachAccess checks that the coach has an accepted share of the
+// profile in the profile_id route variable and records the read.
+func (s *Service) RequireCoachAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := Response{}
+		resp.Code = make(map[int]string)
+
+		coachId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+		if !ok {
+			log.Info(
+				"user id not found in context",
+			)
+
+			resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+			writeResponse(w, &resp)
+			return
+		}
+
+		profileId, err := uuid.Parse(mux.Vars(r)["profile_id"])
+		if err != nil {
+			resp.Code[http.StatusNotFound] = "Client not found."
+			writeResponse(w, &resp)
+			return
+		}
+
+		share, err := s.Coaches.GetActiveShare(r.Context(), coachId, profileId)
+		if err != nil {
+			log.Info(
+				"failed to get active coach share",
+				zap.String("coachId", coachId.String()),
+				zap.String("profileId", profileId.String()),
+				zap.Error(err),
+			)
+
+			resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+			writeResponse(w, &resp)
+			return
+		}
+
+		if share == nil {
+			resp.Code[http.StatusForbidden] = "You don't have access to this client's data."
+			writeResponse(w, &resp)
+			return
+		}
+
+		resource := r.URL.Path
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				resource = template
+			}
+		}
+
+		// No read without a record of it
+		if err := s.Coaches.RecordCoachAccess(r.Context(), share.Id, resource); err != nil {
+			log.Info(
+				"failed to record coach access",
+				zap.String("coachId", coachId.String()),
+				zap.String("profileId", profileId.String()),
+				zap.Error(err),
+			)
+
+			resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+			writeResponse(w, &resp)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), CoachIdContextKey, coachId)
+		ctx = context.WithValue(ctx, UserIdContextKey, share.ClientId)
+		ctx = context.WithValue(ctx, ProfileIdContextKey, profileId)
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireSession only lets through requests made with a login session, so
+// access tokens can't be used to manage other credentials.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(ScopesContextKey).([]string); ok {
+			resp := Response{}
+			resp.Code = make(map[int]string)
+			resp.Code[http.StatusForbidden] = "This action requires a login session."
+			writeResponse(w, &resp)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// VerifyOrigin rejects state changing requests coming from other sites, so
+// they can't ride on the session cookie. Requests carrying a personal access
+// token have no ambient authority and are let through.
+func (s *Service) VerifyOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if strings.HasPrefix(lib.ExtractTokenFromHeader(r), lib.AccessTokenPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !s.isSameSiteRequest(r) {
+			log.Info(
+				"rejected cross-site request",
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.String("origin", r.Header.Get("Origin")),
+				zap.String("secFetchSite", r.Header.Get("Sec-Fetch-Site")),
+			)
+
+			resp := Response{}
+			resp.Code = make(map[int]string)
+			resp.Code[http.StatusForbidden] = "Cross-site request rejected."
+			writeResponse(w, &resp)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Service) isSameSiteRequest(r *http.Request) bool {
+	// Browsers send Origin on every cross-origin request that changes state,
+	// so when it is present it decides.
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if s.cors.IsAllowedOrigin(origin) {
+			return true
+		}
+
+		originURL, err := url.Parse(origin)
+		return err == nil && originURL.Host == r.Host
+	}
+
+	// Without Origin, only trust requests the browser marks as not coming
+	// from another site. Non-browser clients send neither header.
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestVerifyOrigin(t *testing.T) {
+	cfg := testConfig()
+	cfg.CORS.AllowedOrigins = []string{"https://app.example.com"}
+	service, _ := newTestService(t, cfg)
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		allowed bool
+	}{
+		{
+			name:    "foreign origin",
+			method:  http.MethodPost,
+			headers: map[string]string{"Origin": "https://evil.example.net"},
+		},
+		{
+			name:    "cross-site fetch without origin",
+			method:  http.MethodPost,
+			headers: map[string]string{"Sec-Fetch-Site": "cross-site"},
+		},
+		{
+			name:    "allowlisted origin",
+			method:  http.MethodPost,
+			headers: map[string]string{"Origin": "https://app.example.com"},
+			allowed: true,
+		},
+		{
+			name:    "same host origin",
+			method:  http.MethodPost,
+			headers: map[string]string{"Origin": "https://api.example.com"},
+			allowed: true,
+		},
+		{
+			name:    "get from a foreign origin",
+			method:  http.MethodGet,
+			headers: map[string]string{"Origin": "https://evil.example.net"},
+			allowed: true,
+		},
+		{
+			name:   "access token from a foreign origin",
+			method: http.MethodPost,
+			headers: map[string]string{
+				"Origin":        "https://evil.example.net",
+				"Authorization": "Bearer " + lib.AccessTokenPrefix + "token",
+			},
+			allowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached := false
+			handler := service.VerifyOrigin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reached = true
+			}))
+
+			r := httptest.NewRequest(tt.method, "https://api.example.com/api/log/create", nil)
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if reached != tt.allowed {
+				t.Fatalf("reached handler = %v, want %v", reached, tt.allowed)
+			}
+
+			if tt.allowed {
+				return
+			}
+
+			resp := Response{}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if _, ok := resp.Code[http.StatusForbidden]; !ok {
+				t.Fatalf("response code = %v, want %d", resp.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		scopes  []string
+		allowed bool
+	}{
+		{
+			name:    "session",
+			allowed: true,
+		},
+		{
+			name:    "access token with the scope",
+			scopes:  []string{lib.ScopeLogsRead, lib.ScopeLogsWrite},
+			allowed: true,
+		},
+		{
+			name:   "access token without the scope",
+			scopes: []string{lib.ScopeLogsRead},
+		},
+		{
+			name:   "access token without scopes",
+			scopes: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached := false
+			handler := RequireScope(lib.ScopeLogsWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reached = true
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, "/api/users/log/create", nil)
+			if tt.scopes != nil {
+				r = r.WithContext(context.WithValue(r.Context(), ScopesContextKey, tt.scopes))
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if reached != tt.allowed {
+				t.Fatalf("reached handler = %v, want %v", reached, tt.allowed)
+			}
+
+			if !tt.allowed {
+				expectCode(t, w, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    string
+		allowed bool
+	}{
+		{name: "admin", role: lib.RoleAdmin, allowed: true},
+		{name: "user", role: lib.RoleUser},
+		{name: "coach", role: lib.RoleCoach},
+		{name: "no role"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached := false
+			handler := RequireRole(lib.RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reached = true
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/admin/users/get", nil)
+			if tt.role != "" {
+				r = r.WithContext(context.WithValue(r.Context(), RoleContextKey, tt.role))
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if reached != tt.allowed {
+				t.Fatalf("reached handler = %v, want %v", reached, tt.allowed)
+			}
+
+			if !tt.allowed {
+				expectCode(t, w, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestRequireCoachAccess(t *testing.T) {
+	service, store := newSqliteTestService(t, testConfig())
+	ctx := context.Background()
+
+	clientId := createTestUser(t, store, "client", "client password")
+	coachId := createTestUser(t, store, "coach", "coach password")
+	if _, err := store.SetUserRole(ctx, coachId, lib.RoleCoach); err != nil {
+		t.Fatalf("failed to set role: %v", err)
+	}
+
+	profiles, err := store.GetProfiles(ctx, clientId)
+	if err != nil {
+		t.Fatalf("failed to get profiles: %v", err)
+	}
+	profileId := profiles[0].Id
+
+	var reachedUserId, reachedProfileId uuid.UUID
+	router := mux.NewRouter()
+	router.Handle("/api/coach/clients/{profile_id}/log/get", service.RequireCoachAccess(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reachedUserId, _ = r.Context().Value(UserIdContextKey).(uuid.UUID)
+		reachedProfileId, _ = r.Context().Value(ProfileIdContextKey).(uuid.UUID)
+	})))
+
+	// call reports whether the request reached the handler
+	call := func(profile string) bool {
+		t.Helper()
+
+		reachedUserId, reachedProfileId = uuid.Nil, uuid.Nil
+
+		r := httptest.NewRequest(http.MethodGet, "/api/coach/clients/"+profile+"/log/get", nil)
+		r = r.WithContext(context.WithValue(r.Context(), UserIdContextKey, coachId))
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, r)
+
+		if reachedUserId == uuid.Nil {
+			expectCode(t, w, http.StatusForbidden)
+			return false
+		}
+
+		return true
+	}
+
+	accessLog := func() []lib.CoachAccess {
+		t.Helper()
+
+		accesses, err := store.GetCoachAccessLog(ctx, clientId, 10)
+		if err != nil {
+			t.Fatalf("failed to get access log: %v", err)
+		}
+
+		return accesses
+	}
+
+	if call(profileId.String()) {
+		t.Fatal("coach without a share reached the client's data")
+	}
+
+	shareId, err := store.InviteCoach(ctx, clientId, profileId, "coach")
+	if err != nil {
+		t.Fatalf("failed to invite coach: %v", err)
+	}
+
+	if call(profileId.String()) {
+		t.Fatal("coach reached the client's data before accepting the invite")
+	}
+
+	if accesses := accessLog(); len(accesses) != 0 {
+		t.Fatalf("access log = %+v, want nothing before the invite is accepted", accesses)
+	}
+
+	if _, err := store.AcceptCoachInvite(ctx, coachId, *shareId); err != nil {
+		t.Fatalf("failed to accept invite: %v", err)
+	}
+
+	if !call(profileId.String()) {
+		t.Fatal("coach with an accepted share was denied")
+	}
+
+	// Handlers see the client's data, not the coach's
+	if reachedUserId != clientId || reachedProfileId != profileId {
+		t.Fatalf("handler got user %s profile %s, want %s %s", reachedUserId, reachedProfileId, clientId, profileId)
+	}
+
+	accesses := accessLog()
+	if len(accesses) != 1 {
+		t.Fatalf("access log has %d entries, want 1", len(accesses))
+	}
+
+	if accesses[0].CoachId != coachId || accesses[0].ProfileId != profileId ||
+		accesses[0].Resource != "/api/coach/clients/{profile_id}/log/get" {
+		t.Fatalf("access log entry = %+v", accesses[0])
+	}
+
+	otherProfileId, err := store.CreateProfile(ctx, clientId, "other")
+	if err != nil {
+		t.Fatalf("failed to create profile: %v", err)
+	}
+
+	if call(otherProfileId.String()) {
+		t.Fatal("share of one profile gave access to another")
+	}
+
+	if _, err := store.RevokeCoachShare(ctx, clientId, *shareId); err != nil {
+		t.Fatalf("failed to revoke share: %v", err)
+	}
+
+	if call(profileId.String()) {
+		t.Fatal("coach reached the client's data after the share was revoked")
+	}
+
+	if accesses := accessLog(); len(accesses) != 1 {
+		t.Fatalf("access log has %d entries, want only the accepted read", len(accesses))
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/coach/clients/not-a-profile/log/get", nil)
+	r = r.WithContext(context.WithValue(r.Context(), UserIdContextKey, coachId))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	expectCode(t, w, http.StatusNotFound)
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"calometer/internal/metrics"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func (s *Service) OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	provider := s.oidc
+	query := r.URL.Query()
+
+	cookie, err := r.Cookie(oidcFlowCookieName)
+	if err != nil {
+		s.redirectOIDCError(w, r, "expired")
+		return
+	}
+
+	// The flow cookie is single use
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookieName,
+		Value:    "",
+		Path:     oidcFlowCookiePath,
+		HttpOnly: true,
+		Secure:   s.cfg.IsProduction(),
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+	})
+
+	flow, err := s.keys.ParseOIDCFlowToken(cookie.Value)
+	if err != nil {
+		s.redirectOIDCError(w, r, "expired")
+		return
+	}
+
+	if query.Get("error") != "" {
+		s.redirectOIDCError(w, r, "denied")
+		return
+	}
+
+	if query.Get("state") == "" || query.Get("state") != flow.State {
+		s.redirectOIDCError(w, r, "invalid_state")
+		return
+	}
+
+	token, err := provider.Exchange(r.Context(), query.Get("code"), flow.CodeVerifier)
+	if err != nil {
+		log.Info(
+			"failed to exchange oidc authorization code",
+			zap.Error(err),
+		)
+
+		s.redirectOIDCError(w, r, "exchange_failed")
+		return
+	}
+
+	claims, err := provider.VerifyIDToken(r.Context(), token.IDToken, flow.Nonce)
+	if err != nil {
+		log.Info(
+			"failed to verify oidc id token",
+			zap.Error(err),
+		)
+
+		s.redirectOIDCError(w, r, "invalid_token")
+		return
+	}
+
+	userId, err := s.Users.GetUserIdByIdentity(r.Context(), provider.Name(), claims.Subject)
+	if err != nil {
+		log.Info(
+			"failed to get user id by identity",
+			zap.String("provider", provider.Name()),
+			zap.String("subject", claims.Subject),
+			zap.Error(err),
+		)
+
+		s.redirectOIDCError(w, r, "server_error")
+		return
+	}
+
+	switch {
+	case flow.LinkUserId != nil:
+		if userId != nil && *userId != *flow.LinkUserId {
+			s.redirectOIDCError(w, r, "identity_in_use")
+			return
+		}
+
+		if userId == nil {
+			if err := s.Users.LinkIdentity(r.Context(), *flow.LinkUserId, provider.Name(), claims.Subject, claims.Email); err != nil {
+				log.Info(
+					"failed to link identity to user",
+					zap.String("userId", flow.LinkUserId.String()),
+					zap.String("provider", provider.Name()),
+					zap.Error(err),
+				)
+
+				s.redirectOIDCError(w, r, "server_error")
+				return
+			}
+		}
+
+		userId = flow.LinkUserId
+	case userId == nil:
+		if !s.cfg.OIDC.AllowSignup {
+			s.redirectOIDCError(w, r, "no_account")
+			return
+		}
+
+		userId, err = lib.CreateUserForIdentity(
+			r.Context(),
+			s.Users,
+			claims.Name,
+			claims.PreferredUsername,
+			provider.Name(),
+			claims.Subject,
+			claims.Email,
+		)
+		if err != nil {
+			log.Info(
+				"failed to create user for identity",
+				zap.String("provider", provider.Name()),
+				zap.String("subject", claims.Subject),
+				zap.Error(err),
+			)
+
+			s.redirectOIDCError(w, r, "server_error")
+			return
+		}
+
+		metrics.Signups.WithLabelValues("oidc").Inc()
+	}
+
+	username, err := s.Users.GetUsernameById(r.Context(), *userId)
+	if err != nil {
+		log.Info(
+			"failed to get username by id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		s.redirectOIDCError(w, r, "server_error")
+		return
+	}
+
+	if err := s.startSession(r.Context(), w, *userId, *username); err != nil {
+		if errors.Is(err, lib.ErrUserDisabled) {
+			s.redirectOIDCError(w, r, "account_disabled")
+			return
+		}
+
+		log.Info(
+			"failed to start session for user id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		s.redirectOIDCError(w, r, "server_error")
+		return
+	}
+
+	// The login page picks up the new session and routes the user on
+	http.Redirect(w, r, s.cfg.FEURL+"/login", http.StatusFound)
+}
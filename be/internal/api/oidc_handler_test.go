@@ -0,0 +1,351 @@
+package api
+
+import (
+	"calometer/internal/oidc"
+	"calometer/internal/oidc/oidctest"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+const oidcTestFEURL = "https://app.example.com"
+
+// oidcEnv is a SQLite backed server logging in through a mock provider.
+type oidcEnv struct {
+	*integrationEnv
+	service  *Service
+	provider *oidctest.Server
+}
+
+func newOIDCEnv(t *testing.T) *oidcEnv {
+	t.Helper()
+
+	provider, err := oidctest.NewServer("calometer", "client secret")
+	if err != nil {
+		t.Fatalf("failed to start provider: %v", err)
+	}
+	t.Cleanup(provider.Close)
+
+	// The redirect URL has to be known before the service is created
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+
+	backend := openSqliteBackend(t)
+
+	cfg := testConfig()
+	cfg.Database = backend.cfg
+	cfg.FEURL = oidcTestFEURL
+	cfg.OIDC.Issuer = provider.URL
+	cfg.OIDC.ProviderName = "mock"
+	cfg.OIDC.ClientID = provider.ClientID
+	cfg.OIDC.ClientSecret = provider.ClientSecret
+	cfg.OIDC.RedirectURL = server.URL + "/api/users/oidc/callback"
+	cfg.OIDC.AllowSignup = true
+
+	service, err := NewService(cfg, NewStores(backend.store, backend.loginAttempts, backend.database))
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	server.Config.Handler = SetupRouter(service)
+
+	return &oidcEnv{
+		integrationEnv: &integrationEnv{
+			server:   server,
+			store:    backend.store,
+			suffix:   uuid.New().String()[:8],
+			clientIP: "192.0.2.1",
+		},
+		service:  service,
+		provider: provider,
+	}
+}
+
+// newBrowser is a client that doesn't follow redirects, so every hop of
+// the flow can be checked and tampered with.
+func (env *oidcEnv) newBrowser(t *testing.T) *apiClient {
+	t.Helper()
+
+	c := env.newClient(t)
+	c.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return c
+}
+
+// redirectOf requests target and returns where it redirects to.
+func (c *apiClient) redirectOf(target string) *url.URL {
+	c.t.Helper()
+
+	res, err := c.client.Get(target)
+	if err != nil {
+		c.t.Fatalf("GET %s failed: %v", target, err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusFound {
+		c.t.Fatalf("GET %s returned %d, want a redirect", target, res.StatusCode)
+	}
+
+	location, err := res.Location()
+	if err != nil {
+		c.t.Fatalf("GET %s: %v", target, err)
+	}
+
+	return location
+}
+
+// oidcLogin runs the flow from the login route through the provider back to
+// the callback, letting tamper change the authorization request and the
+// callback on the way, and returns where the callback redirects to.
+func (c *apiClient) oidcLogin(query string, tamperAuth func(url.Values), tamperCallback func(url.Values)) *url.URL {
+	c.t.Helper()
+
+	authURL := c.redirectOf(c.env.server.URL + "/api/users/oidc/login" + query)
+	if authURL.Query().Get("oidc_error") != "" {
+		return authURL
+	}
+
+	if tamperAuth != nil {
+		params := authURL.Query()
+		tamperAuth(params)
+		authURL.RawQuery = params.Encode()
+	}
+
+	callbackURL := c.redirectOf(authURL.String())
+
+	if tamperCallback != nil {
+		params := callbackURL.Query()
+		tamperCallback(params)
+		callbackURL.RawQuery = params.Encode()
+	}
+
+	return c.redirectOf(callbackURL.String())
+}
+
+func expectOIDCError(t *testing.T, location *url.URL, reason string) {
+	t.Helper()
+
+	if got := location.Query().Get("oidc_error"); got != reason {
+		t.Fatalf("redirected to %s, want oidc_error=%s", location, reason)
+	}
+}
+
+func expectOIDCLogin(t *testing.T, location *url.URL) {
+	t.Helper()
+
+	if location.String() != oidcTestFEURL+"/login" {
+		t.Fatalf("redirected to %s, want the login page", location)
+	}
+}
+
+func TestOIDCLoginHandlerStartsPKCEFlow(t *testing.T) {
+	env := newOIDCEnv(t)
+	c := env.newBrowser(t)
+
+	authURL := c.redirectOf(env.server.URL + "/api/users/oidc/login")
+
+	serverURL, err := url.Parse(env.server.URL + oidcFlowCookiePath)
+	if err != nil {
+		t.Fatalf("failed to parse server url: %v", err)
+	}
+
+	var flowCookie *http.Cookie
+	for _, cookie := range c.client.Jar.Cookies(serverURL) {
+		if cookie.Name == oidcFlowCookieName {
+			flowCookie = cookie
+		}
+	}
+	if flowCookie == nil {
+		t.Fatal("no flow cookie was set")
+	}
+
+	flow, err := env.service.keys.ParseOIDCFlowToken(flowCookie.Value)
+	if err != nil {
+		t.Fatalf("failed to parse flow cookie: %v", err)
+	}
+
+	// The browser only sees the challenge, the verifier stays in the cookie
+	query := authURL.Query()
+	want := map[string]string{
+		"state":                 flow.State,
+		"nonce":                 flow.Nonce,
+		"code_challenge":        oidc.CodeChallengeS256(flow.CodeVerifier),
+		"code_challenge_method": "S256",
+		"redirect_uri":          env.service.cfg.OIDC.RedirectURL,
+	}
+	for key, value := range want {
+		if query.Get(key) != value {
+			t.Fatalf("%s = %q, want %q", key, query.Get(key), value)
+		}
+	}
+
+	if query.Get("code_verifier") != "" {
+		t.Fatal("code verifier was sent to the provider")
+	}
+}
+
+func TestOIDCCallbackHandlerSignsUpAndLogsIn(t *testing.T) {
+	env := newOIDCEnv(t)
+	ctx := context.Background()
+
+	c := env.newBrowser(t)
+	expectOIDCLogin(t, c.oidcLogin("", nil, nil))
+
+	// The session cookies work for the API
+	c.call(http.MethodGet, "/api/users/profiles/get", nil, http.StatusOK, nil)
+
+	userId, err := env.store.GetUserIdByIdentity(ctx, "mock", env.provider.Subject)
+	if err != nil || userId == nil {
+		t.Fatalf("GetUserIdByIdentity() = %v, %v, want the new user", userId, err)
+	}
+
+	// Logging in again finds the same account
+	again := env.newBrowser(t)
+	expectOIDCLogin(t, again.oidcLogin("", nil, nil))
+
+	againId, err := env.store.GetUserIdByIdentity(ctx, "mock", env.provider.Subject)
+	if err != nil || againId == nil || *againId != *userId {
+		t.Fatalf("second login resolved to %v, want %s", againId, userId)
+	}
+}
+
+func TestOIDCCallbackHandlerRejectsUnknownIdentityWithoutSignup(t *testing.T) {
+	env := newOIDCEnv(t)
+	env.service.cfg.OIDC.AllowSignup = false
+
+	expectOIDCError(t, env.newBrowser(t).oidcLogin("", nil, nil), "no_account")
+}
+
+func TestOIDCCallbackHandlerRejectsTamperedFlows(t *testing.T) {
+	env := newOIDCEnv(t)
+
+	tests := []struct {
+		name           string
+		tamperAuth     func(url.Values)
+		tamperCallback func(url.Values)
+		reason         string
+	}{
+		{
+			name:           "state",
+			tamperCallback: func(params url.Values) { params.Set("state", "forged") },
+			reason:         "invalid_state",
+		},
+		{
+			name:           "missing state",
+			tamperCallback: func(params url.Values) { params.Del("state") },
+			reason:         "invalid_state",
+		},
+		{
+			name:       "nonce",
+			tamperAuth: func(params url.Values) { params.Set("nonce", "forged") },
+			reason:     "invalid_token",
+		},
+		{
+			name:       "code challenge",
+			tamperAuth: func(params url.Values) { params.Set("code_challenge", oidc.CodeChallengeS256("forged")) },
+			reason:     "exchange_failed",
+		},
+		{
+			name:           "code",
+			tamperCallback: func(params url.Values) { params.Set("code", "forged") },
+			reason:         "exchange_failed",
+		},
+		{
+			name:           "provider error",
+			tamperCallback: func(params url.Values) { params.Set("error", "access_denied") },
+			reason:         "denied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectOIDCError(t, env.newBrowser(t).oidcLogin("", tt.tamperAuth, tt.tamperCallback), tt.reason)
+		})
+	}
+}
+
+func TestOIDCCallbackHandlerNeedsFlowCookie(t *testing.T) {
+	env := newOIDCEnv(t)
+	c := env.newBrowser(t)
+
+	authURL := c.redirectOf(env.server.URL + "/api/users/oidc/login")
+	callbackURL := c.redirectOf(authURL.String())
+
+	// Another browser can't finish the flow
+	expectOIDCError(t, env.newBrowser(t).redirectOf(callbackURL.String()), "expired")
+
+	// The flow cookie is single use
+	expectOIDCLogin(t, c.redirectOf(callbackURL.String()))
+	expectOIDCError(t, c.redirectOf(callbackURL.String()), "expired")
+}
+
+func TestOIDCCallbackHandlerLinksIdentity(t *testing.T) {
+	env := newOIDCEnv(t)
+	ctx := context.Background()
+
+	alice := env.signUp(t, "alice")
+	alice.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	expectOIDCLogin(t, alice.oidcLogin("?link=true", nil, nil))
+
+	userId, err := env.store.GetUserIdByIdentity(ctx, "mock", env.provider.Subject)
+	if err != nil || userId == nil || *userId != alice.UserId {
+		t.Fatalf("identity linked to %v, want %s", userId, alice.UserId)
+	}
+
+	// Logging in through the provider now reaches alice's account
+	expectOIDCLogin(t, env.newBrowser(t).oidcLogin("", nil, nil))
+
+	bob := env.signUp(t, "bob")
+	bob.client.CheckRedirect = alice.client.CheckRedirect
+
+	expectOIDCError(t, bob.oidcLogin("?link=true", nil, nil), "identity_in_use")
+
+	// Linking needs a session
+	expectOIDCError(t, env.newBrowser(t).oidcLogin("?link=true", nil, nil), "not_logged_in")
+}
+
+func TestOIDCRoutesFailAloneWhenProviderIsDown(t *testing.T) {
+	provider, err := oidctest.NewServer("calometer", "client secret")
+	if err != nil {
+		t.Fatalf("failed to start provider: %v", err)
+	}
+	provider.Close()
+
+	cfg := testConfig()
+	cfg.FEURL = oidcTestFEURL
+	cfg.OIDC.Issuer = provider.URL
+	cfg.OIDC.ClientID = provider.ClientID
+	cfg.OIDC.RedirectURL = "https://api.example.com/api/users/oidc/callback"
+
+	// Creating the service doesn't reach out to the provider
+	service, _ := newTestService(t, cfg)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/users/oidc/login", nil)
+	w := httptest.NewRecorder()
+	SetupRouter(service).ServeHTTP(w, r)
+
+	if w.Code != http.StatusFound {
+		t.Fatalf("login returned %d, want a redirect", w.Code)
+	}
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("failed to parse redirect: %v", err)
+	}
+	expectOIDCError(t, location, "unavailable")
+
+	// Everything else keeps working
+	w = httptest.NewRecorder()
+	SetupRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("healthz returned %d while the provider is down", w.Code)
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"calometer/internal/oidc"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	oidcFlowCookieName = "oidc_flow"
+	oidcFlowCookiePath = "/api/users/oidc"
+)
+
+func (s *Service) OIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	provider := s.oidc
+
+	var flow lib.OIDCFlow
+	for _, value := range []*string{&flow.State, &flow.Nonce, &flow.CodeVerifier} {
+		random, err := oidc.RandomString()
+		if err != nil {
+			log.Info(
+				"failed to generate oidc flow values",
+				zap.Error(err),
+			)
+
+			s.redirectOIDCError(w, r, "server_error")
+			return
+		}
+		*value = random
+	}
+
+	// Logged in users link the identity to their account instead
+	if r.URL.Query().Get("link") == "true" {
+		cookie, err := r.Cookie(tokenCookieName)
+		if err != nil {
+			s.redirectOIDCError(w, r, "not_logged_in")
+			return
+		}
+
+		userId, err := s.keys.ExtractUserIdFromToken(cookie.Value)
+		if err != nil {
+			s.redirectOIDCError(w, r, "not_logged_in")
+			return
+		}
+
+		flow.LinkUserId = userId
+	}
+
+	flowToken, err := s.keys.GenerateOIDCFlowToken(flow)
+	if err != nil {
+		log.Info(
+			"failed to generate oidc flow token",
+			zap.Error(err),
+		)
+
+		s.redirectOIDCError(w, r, "server_error")
+		return
+	}
+
+	// Lax so the cookie comes back on the provider's redirect
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookieName,
+		Value:    flowToken,
+		Path:     oidcFlowCookiePath,
+		HttpOnly: true,
+		Secure:   s.cfg.IsProduction(),
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Now().Add(lib.OIDCFlowTTL),
+	})
+
+	authURL, err := provider.AuthCodeURL(r.Context(), flow.State, flow.Nonce, flow.CodeVerifier)
+	if err != nil {
+		log.Info(
+			"failed to build oidc authorization url",
+			zap.Error(err),
+		)
+
+		s.redirectOIDCError(w, r, "unavailable")
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// redirectOIDCError sends the browser back to the login page, since these
+// routes are reached through redirects rather than API calls.
+func (s *Service) redirectOIDCError(w http.ResponseWriter, r *http.Request, reason string) {
+	http.Redirect(w, r, s.cfg.FEURL+"/login?oidc_error="+url.QueryEscape(reason), http.StatusFound)
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSelectProfile(t *testing.T) {
+	service, store := newSqliteTestService(t, testConfig())
+
+	aliceId := createTestUser(t, store, "alice", "alice password")
+	bobId := createTestUser(t, store, "bob", "bob password")
+
+	aliceProfileId, err := store.CreateProfile(context.Background(), aliceId, "cut")
+	if err != nil {
+		t.Fatalf("failed to create profile: %v", err)
+	}
+
+	bobProfileId, err := store.CreateProfile(context.Background(), bobId, "bulk")
+	if err != nil {
+		t.Fatalf("failed to create profile: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		header  string
+		code    int
+		profile uuid.UUID
+	}{
+		{name: "no header", code: http.StatusOK, profile: aliceId},
+		{name: "default profile", header: aliceId.String(), code: http.StatusOK, profile: aliceId},
+		{name: "own profile", header: aliceProfileId.String(), code: http.StatusOK, profile: *aliceProfileId},
+		{name: "another user's profile", header: bobProfileId.String(), code: http.StatusNotFound},
+		{name: "another user's default profile", header: bobId.String(), code: http.StatusNotFound},
+		{name: "unknown profile", header: uuid.NewString(), code: http.StatusNotFound},
+		{name: "invalid id", header: "not a uuid", code: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var selected uuid.UUID
+			handler := service.SelectProfile(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				selected, _ = r.Context().Value(ProfileIdContextKey).(uuid.UUID)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/users/log/get", nil)
+			r = r.WithContext(context.WithValue(r.Context(), UserIdContextKey, aliceId))
+			if tt.header != "" {
+				r.Header.Set(ProfileIdHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if tt.code != http.StatusOK {
+				expectCode(t, w, tt.code)
+
+				if selected != uuid.Nil {
+					t.Fatalf("handler reached with profile %s", selected)
+				}
+				return
+			}
+
+			if selected != tt.profile {
+				t.Fatalf("selected profile = %s, want %s", selected, tt.profile)
+			}
+		})
+	}
+}
+
+func TestDeleteProfile(t *testing.T) {
+	service, store := newSqliteTestService(t, testConfig())
+	ctx := context.Background()
+
+	aliceId := createTestUser(t, store, "alice", "alice password")
+	bobId := createTestUser(t, store, "bob", "bob password")
+
+	bobProfileId, err := store.CreateProfile(ctx, bobId, "bulk")
+	if err != nil {
+		t.Fatalf("failed to create profile: %v", err)
+	}
+
+	deleteProfile := func(profileId uuid.UUID) *httptest.ResponseRecorder {
+		r := newJSONRequest(t, http.MethodDelete, "/api/users/profiles/delete", DeleteProfileReq{Id: profileId})
+		r = r.WithContext(context.WithValue(r.Context(), UserIdContextKey, aliceId))
+		w := httptest.NewRecorder()
+		service.DeleteProfileHandler(w, r)
+		return w
+	}
+
+	expectCode(t, deleteProfile(aliceId), http.StatusBadRequest)
+	expectCode(t, deleteProfile(*bobProfileId), http.StatusNotFound)
+
+	// The store protects default profiles too
+	deleted, err := store.DeleteProfile(ctx, aliceId, aliceId)
+	if err != nil {
+		t.Fatalf("failed to delete profile: %v", err)
+	}
+
+	if *deleted {
+		t.Fatal("deleted the default profile")
+	}
+
+	profileId, err := store.CreateProfile(ctx, aliceId, "cut")
+	if err != nil {
+		t.Fatalf("failed to create profile: %v", err)
+	}
+
+	expectCode(t, deleteProfile(*profileId), http.StatusOK)
+	expectCode(t, deleteProfile(*profileId), http.StatusNotFound)
+
+	for userId, want := range map[uuid.UUID]int{aliceId: 1, bobId: 2} {
+		profiles, err := store.GetProfiles(ctx, userId)
+		if err != nil {
+			t.Fatalf("failed to get profiles: %v", err)
+		}
+
+		if len(profiles) != want {
+			t.Fatalf("user has %d profiles, want %d", len(profiles), want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// QueryTimeouts bounds how long a request may spend on its queries. The
+// deadline is set on the request context, which every query runs with.
+type QueryTimeouts struct {
+	Default time.Duration
+	// Routes overrides Default by route path template
+	Routes map[string]time.Duration
+}
+
+// NewQueryTimeouts sets the default deadline. Routes that aggregate over
+// many rows get more time.
+func NewQueryTimeouts(timeout time.Duration) *QueryTimeouts {
+	return &QueryTimeouts{
+		Default: timeout,
+		Routes: map[string]time.Duration{
+			"/api/admin/users/get":               15 * time.Second,
+			"/api/groups/challenges/leaderboard": 15 * time.Second,
+		},
+	}
+}
+
+func (t *QueryTimeouts) timeoutFor(r *http.Request) time.Duration {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			if timeout, ok := t.Routes[template]; ok {
+				return timeout
+			}
+		}
+	}
+
+	return t.Default
+}
+
+// Handler cancels the request context once the route's deadline passes.
+func (t *QueryTimeouts) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), t.timeoutFor(r))
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
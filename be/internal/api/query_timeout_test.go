@@ -0,0 +1,121 @@
+package api
+
+import (
+	"calometer/internal/config"
+	"calometer/internal/db"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestQueryTimeoutsPerRoute(t *testing.T) {
+	timeouts := NewQueryTimeouts(time.Second)
+
+	var remaining time.Duration
+	handler := timeouts.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := r.Context().Deadline()
+		if !ok {
+			t.Fatal("request context has no deadline")
+		}
+
+		remaining = time.Until(deadline)
+	}))
+
+	router := mux.NewRouter()
+	router.Handle("/api/users/log/get", handler)
+	router.Handle("/api/groups/challenges/leaderboard", handler)
+
+	tests := []struct {
+		path string
+		want time.Duration
+	}{
+		{"/api/users/log/get", time.Second},
+		{"/api/groups/challenges/leaderboard", 15 * time.Second},
+	}
+
+	for _, tt := range tests {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if remaining > tt.want || remaining < tt.want-time.Second/2 {
+			t.Errorf("%s deadline in %s, want %s", tt.path, remaining, tt.want)
+		}
+	}
+}
+
+func TestQueryDeadlineCancelsQuery(t *testing.T) {
+	cfg := config.DatabaseConfig{
+		Driver:     "sqlite",
+		SQLitePath: filepath.Join(t.TempDir(), "calometer.db"),
+	}
+
+	conn, err := db.InitSQLite(cfg)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.CloseSQLite(conn) })
+
+	timeouts := NewQueryTimeouts(50 * time.Millisecond)
+
+	var queryErr error
+	var took time.Duration
+	handler := timeouts.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		// Never finishes on its own
+		var count int
+		queryErr = conn.QueryRowContext(r.Context(), `
+			WITH RECURSIVE numbers(n) AS (
+				SELECT 1
+				UNION ALL
+				SELECT n + 1 FROM numbers
+			)
+			SELECT COUNT(*) FROM numbers
+		`).Scan(&count)
+
+		took = time.Since(start)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/users/log/get", nil))
+
+	if queryErr == nil {
+		t.Fatal("query finished despite the deadline")
+	}
+
+	if took > 5*time.Second {
+		t.Fatalf("query was canceled after %s, want about the 50ms deadline", took)
+	}
+}
+
+func TestQueryDeadlineFailsRequest(t *testing.T) {
+	cfg := testConfig()
+	cfg.QueryTimeout = time.Nanosecond
+
+	service, store := newSqliteTestService(t, cfg)
+	userId := createTestUser(t, store, "alice", "alice password")
+
+	var ctxErr error
+	handler := service.queryTimeouts.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		ctxErr = r.Context().Err()
+
+		service.GetProfilesHandler(w, r)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/users/profiles/get", nil)
+	r = r.WithContext(context.WithValue(r.Context(), UserIdContextKey, userId))
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	if !errors.Is(ctxErr, context.DeadlineExceeded) {
+		t.Fatalf("request context error = %v, want %v", ctxErr, context.DeadlineExceeded)
+	}
+
+	expectCode(t, w, http.StatusInternalServerError)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// ReadyzHandler tells the orchestrator whether to route traffic here. The
+// database has to answer and be at the schema this build needs, a schema
+// ahead of it is fine as during startup.
+func (s *Service) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	if s.shuttingDown.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		resp.Code[http.StatusServiceUnavailable] = "Shutting down."
+		writeResponse(w, &resp)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.cfg.QueryTimeout)
+	defer cancel()
+
+	if err := s.Database.Ping(ctx); err != nil {
+		log.Info(
+			"failed to ping database",
+			zap.Error(err),
+		)
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+		resp.Code[http.StatusServiceUnavailable] = "Database is unreachable."
+		writeResponse(w, &resp)
+		return
+	}
+
+	schema, err := s.Database.GetSchemaStatus(ctx)
+	if err != nil {
+		log.Info(
+			"failed to read schema version",
+			zap.Error(err),
+		)
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+		resp.Code[http.StatusServiceUnavailable] = "Database schema version is unknown."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if schema.Dirty || schema.Version < schema.Latest {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		resp.Code[http.StatusServiceUnavailable] = "Database schema is not current."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	resp.Code[http.StatusOK] = "OK"
+	writeResponse(w, &resp)
+}
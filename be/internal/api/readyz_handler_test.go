@@ -0,0 +1,176 @@
+package api
+
+import (
+	"calometer/internal/db"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeHealth is a database in whatever state a test needs.
+type fakeHealth struct {
+	pingErr   error
+	status    db.MigrationStatus
+	statusErr error
+}
+
+func (h fakeHealth) Ping(ctx context.Context) error {
+	return h.pingErr
+}
+
+func (h fakeHealth) GetSchemaStatus(ctx context.Context) (*db.MigrationStatus, error) {
+	if h.statusErr != nil {
+		return nil, h.statusErr
+	}
+
+	status := h.status
+	return &status, nil
+}
+
+func TestReadyzHandler(t *testing.T) {
+	errDown := errors.New("connection refused")
+
+	tests := []struct {
+		name         string
+		health       fakeHealth
+		shuttingDown bool
+		code         int
+		message      string
+	}{
+		{
+			name:    "current",
+			health:  fakeHealth{status: db.MigrationStatus{Version: 5, Latest: 5}},
+			code:    http.StatusOK,
+			message: "OK",
+		},
+		{
+			name:    "schema ahead during a rolling deploy",
+			health:  fakeHealth{status: db.MigrationStatus{Version: 6, Latest: 5}},
+			code:    http.StatusOK,
+			message: "OK",
+		},
+		{
+			name:    "database down",
+			health:  fakeHealth{pingErr: errDown},
+			code:    http.StatusServiceUnavailable,
+			message: "Database is unreachable.",
+		},
+		{
+			name:    "schema unreadable",
+			health:  fakeHealth{statusErr: errDown},
+			code:    http.StatusServiceUnavailable,
+			message: "Database schema version is unknown.",
+		},
+		{
+			name:    "schema behind",
+			health:  fakeHealth{status: db.MigrationStatus{Version: 4, Latest: 5}},
+			code:    http.StatusServiceUnavailable,
+			message: "Database schema is not current.",
+		},
+		{
+			name:    "schema dirty",
+			health:  fakeHealth{status: db.MigrationStatus{Version: 5, Dirty: true, Latest: 5}},
+			code:    http.StatusServiceUnavailable,
+			message: "Database schema is not current.",
+		},
+		{
+			name:         "shutting down",
+			health:       fakeHealth{status: db.MigrationStatus{Version: 5, Latest: 5}},
+			shuttingDown: true,
+			code:         http.StatusServiceUnavailable,
+			message:      "Shutting down.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t, testConfig())
+			service.Database = tt.health
+			if tt.shuttingDown {
+				service.SetShuttingDown()
+			}
+
+			w := httptest.NewRecorder()
+			service.ReadyzHandler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d", w.Code, tt.code)
+			}
+
+			if resp := expectCode(t, w, tt.code); resp.Code[tt.code] != tt.message {
+				t.Fatalf("message = %q, want %q", resp.Code[tt.code], tt.message)
+			}
+		})
+	}
+}
+
+func TestReadyzHandlerMigratedDatabase(t *testing.T) {
+	service, _ := newSqliteTestService(t, testConfig())
+
+	w := httptest.NewRecorder()
+	service.ReadyzHandler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	expectCode(t, w, http.StatusOK)
+}
+
+func TestVersionHandler(t *testing.T) {
+	cfg := testConfig()
+	cfg.Database.Driver = "sqlite"
+
+	latest, err := db.LatestMigrationVersion(cfg.Database.Driver)
+	if err != nil {
+		t.Fatalf("failed to read latest migration: %v", err)
+	}
+
+	applied := uint(3)
+
+	tests := []struct {
+		name   string
+		health fakeHealth
+		schema *uint
+	}{
+		{
+			name:   "database up",
+			health: fakeHealth{status: db.MigrationStatus{Version: applied, Latest: latest}},
+			schema: &applied,
+		},
+		{
+			// The build is still reported
+			name:   "database down",
+			health: fakeHealth{statusErr: errors.New("connection refused")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t, cfg)
+			service.Database = tt.health
+
+			w := httptest.NewRecorder()
+			service.VersionHandler(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+
+			var resp struct {
+				Data VersionResp `json:"data"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if resp.Data.LatestSchemaVersion != latest {
+				t.Fatalf("latest schema version = %d, want %d", resp.Data.LatestSchemaVersion, latest)
+			}
+
+			if (resp.Data.SchemaVersion == nil) != (tt.schema == nil) ||
+				(tt.schema != nil && *resp.Data.SchemaVersion != *tt.schema) {
+				t.Fatalf("schema version = %v, want %v", resp.Data.SchemaVersion, tt.schema)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+func (s *Service) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	cookie, err := r.Cookie(refreshTokenCookieName)
+	if err != nil {
+		// No refresh cookie found
+		resp.Code[http.StatusUnauthorized] = "Session expired. Please login again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	userId, refreshToken, err := s.RefreshTokens.RotateRefreshToken(r.Context(), cookie.Value)
+	if err != nil {
+		if errors.Is(err, lib.ErrRefreshTokenReused) {
+			log.Warn(
+				"refresh token reuse detected, token family revoked",
+				zap.String("userId", userId.String()),
+			)
+		}
+
+		if errors.Is(err, lib.ErrRefreshTokenReused) || errors.Is(err, lib.ErrRefreshTokenInvalid) {
+			s.clearSessionCookies(w)
+			resp.Code[http.StatusUnauthorized] = "Session expired. Please login again."
+			writeResponse(w, &resp)
+			return
+		}
+
+		log.Info(
+			"failed to rotate refresh token",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	username, err := s.Users.GetUsernameById(r.Context(), *userId)
+	if err != nil {
+		log.Info(
+			"failed to get username by id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	// Roles are read again on every refresh, so changes apply within one
+	// access token lifetime
+	status, err := lib.GetActiveUserStatus(r.Context(), s.Users, *userId)
+	if err != nil {
+		if errors.Is(err, lib.ErrUserDisabled) {
+			s.clearSessionCookies(w)
+			resp.Code[http.StatusUnauthorized] = "This account has been disabled."
+			writeResponse(w, &resp)
+			return
+		}
+
+		log.Info(
+			"failed to get user status by user id",
+			zap.String("userId", userId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	token, err := s.keys.GenerateJWT(*userId, *username, status.Role)
+	if err != nil {
+		log.Info(
+			"failed to generate JWT for user id",
+			zap.String("userId", userId.String()),
+			zap.String("username", *username),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	s.setSessionCookies(w, token, refreshToken)
+
+	w.WriteHeader(http.StatusOK)
+	resp.Code[http.StatusOK] = "OK"
+	writeResponse(w, &resp)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type ReplyLogCommentReq struct {
+	ParentId uuid.UUID `json:"parent_id"`
+	Body     string    `json:"body"`
+}
+
+func (s *Service) ReplyLogCommentHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	_, profileId, authorId, _, ok := commentParticipants(r)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	var req ReplyLogCommentReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Info(
+			"failed to decode incoming json",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
+		writeResponse(w, &resp)
+		return
+	}
+
+	if !validateCommentBody(&resp, req.Body) {
+		writeResponse(w, &resp)
+		return
+	}
+
+	commentId, err := s.LogComments.ReplyToLogComment(r.Context(), profileId, authorId, req.ParentId, strings.TrimSpace(req.Body))
+	if err != nil {
+		if errors.Is(err, lib.ErrCommentNotFound) {
+			resp.Code[http.StatusNotFound] = "Comment not found."
+			writeResponse(w, &resp)
+			return
+		}
+
+		log.Info(
+			"failed to reply to log comment by id",
+			zap.String("userId", authorId.String()),
+			zap.String("parentId", req.ParentId.String()),
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	data := &LogCommentResp{
+		Id: *commentId,
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
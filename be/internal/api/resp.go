@@ -1,7 +1,10 @@
 package api
 
 import (
+	"calometer/internal/lib"
 	"calometer/internal/logger"
+	"encoding/json"
+	"net/http"
 )
 
 var log = logger.GetLogger()
@@ -10,3 +13,14 @@ type Response struct {
 	Code map[int]string `json:"code"`
 	Data interface{}    `json:"data,omitempty"`
 }
+
+type ValidationErrorsResp struct {
+	Errors []lib.ValidationError `json:"errors"`
+}
+
+// writeResponse encodes resp and records its code for the request metrics,
+// failures are reported in the code with a 200.
+func writeResponse(w http.ResponseWriter, resp *Response) {
+	recordResponseCode(w, resp.Code)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -1,35 +1,134 @@
 package api
 
 import (
+	"calometer/internal/lib"
+	"calometer/internal/metrics"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/justinas/alice"
 )
 
-func SetupRouter() *mux.Router {
+func SetupRouter(service *Service) *mux.Router {
 	router := mux.NewRouter()
 
+	// Count every matched request under its route template
+	router.Use(MetricsMiddleware)
+
 	// Middlewares
-	enableCORSMiddleware := alice.New(EnableCORS)
-	authMiddleware := enableCORSMiddleware.Append(AuthMiddleWare)
+	enableCORSMiddleware := alice.New(service.cors.Handler, service.VerifyOrigin, service.queryTimeouts.Handler)
+	authMiddleware := enableCORSMiddleware.Append(service.AuthMiddleWare, service.SelectProfile)
+	sessionMiddleware := authMiddleware.Append(RequireSession)
+	adminMiddleware := sessionMiddleware.Append(RequireRole(lib.RoleAdmin))
+	coachMiddleware := sessionMiddleware.Append(RequireRole(lib.RoleCoach))
+	coachClientMiddleware := coachMiddleware.Append(service.RequireCoachAccess)
+
+	// Personal access tokens may only use the routes their scopes allow
+	logsReadMiddleware := authMiddleware.Append(RequireScope(lib.ScopeLogsRead))
+	logsWriteMiddleware := authMiddleware.Append(RequireScope(lib.ScopeLogsWrite))
+	bodyDetailsReadMiddleware := authMiddleware.Append(RequireScope(lib.ScopeBodyDetailsRead))
+	bodyDetailsWriteMiddleware := authMiddleware.Append(RequireScope(lib.ScopeBodyDetailsWrite))
+	balanceReadMiddleware := authMiddleware.Append(RequireScope(lib.ScopeBalanceRead))
+
+	// Probes for the orchestrator, served without CORS so they work from
+	// anywhere
+	router.Handle("/healthz", http.HandlerFunc(HealthzHandler)).Methods(http.MethodGet)
+	router.Handle("/readyz", http.HandlerFunc(service.ReadyzHandler)).Methods(http.MethodGet)
+	router.Handle("/version", http.HandlerFunc(service.VersionHandler)).Methods(http.MethodGet)
+	// Unauthenticated like the probes, keep it off the public edge
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 
 	// Define routes
-	router.Handle("/api/users/signup", enableCORSMiddleware.Then(http.HandlerFunc(SignUpHandler))).Methods(http.MethodPost)
-	router.Handle("/api/users/login", enableCORSMiddleware.Then(http.HandlerFunc(LoginHandler))).Methods(http.MethodPost)
-	router.Handle("/api/users/logout", enableCORSMiddleware.Then(http.HandlerFunc(LogoutHandler))).Methods(http.MethodPost)
+	router.Handle("/.well-known/jwks.json", http.HandlerFunc(service.JWKSHandler)).Methods(http.MethodGet)
+
+	router.Handle("/api/users/signup", enableCORSMiddleware.Then(http.HandlerFunc(service.SignUpHandler))).Methods(http.MethodPost)
+	router.Handle("/api/users/login", enableCORSMiddleware.Then(http.HandlerFunc(service.LoginHandler))).Methods(http.MethodPost)
+	router.Handle("/api/users/login/2fa", enableCORSMiddleware.Then(http.HandlerFunc(service.LoginTwoFactorHandler))).Methods(http.MethodPost)
+	router.Handle("/api/users/token/refresh", enableCORSMiddleware.Then(http.HandlerFunc(service.RefreshTokenHandler))).Methods(http.MethodPost)
+	router.Handle("/api/users/logout", enableCORSMiddleware.Then(http.HandlerFunc(service.LogoutHandler))).Methods(http.MethodPost)
+
+	// Login through an external identity provider, when one is configured
+	if service.oidc != nil {
+		router.Handle("/api/users/oidc/login", http.HandlerFunc(service.OIDCLoginHandler)).Methods(http.MethodGet)
+		router.Handle("/api/users/oidc/callback", http.HandlerFunc(service.OIDCCallbackHandler)).Methods(http.MethodGet)
+	}
+
+	router.Handle("/api/users/access_token/create", sessionMiddleware.Then(http.HandlerFunc(service.CreateAccessTokenHandler))).Methods(http.MethodPost)
+	router.Handle("/api/users/access_token/get", sessionMiddleware.Then(http.HandlerFunc(service.GetAccessTokensHandler))).Methods(http.MethodGet)
+	router.Handle("/api/users/access_token/revoke", sessionMiddleware.Then(http.HandlerFunc(service.RevokeAccessTokenHandler))).Methods(http.MethodDelete)
+
+	router.Handle("/api/users/password/change", sessionMiddleware.Then(http.HandlerFunc(service.ChangePasswordHandler))).Methods(http.MethodPost)
+
+	router.Handle("/api/users/2fa/enroll", sessionMiddleware.Then(http.HandlerFunc(service.EnrollTOTPHandler))).Methods(http.MethodPost)
+	router.Handle("/api/users/2fa/verify", sessionMiddleware.Then(http.HandlerFunc(service.VerifyTOTPHandler))).Methods(http.MethodPost)
+	router.Handle("/api/users/2fa/disable", sessionMiddleware.Then(http.HandlerFunc(service.DisableTOTPHandler))).Methods(http.MethodPost)
+
+	router.Handle("/api/users/profiles/get", sessionMiddleware.Then(http.HandlerFunc(service.GetProfilesHandler))).Methods(http.MethodGet)
+	router.Handle("/api/users/profiles/create", sessionMiddleware.Then(http.HandlerFunc(service.CreateProfileHandler))).Methods(http.MethodPost)
+	router.Handle("/api/users/profiles/update", sessionMiddleware.Then(http.HandlerFunc(service.UpdateProfileHandler))).Methods(http.MethodPut)
+	router.Handle("/api/users/profiles/delete", sessionMiddleware.Then(http.HandlerFunc(service.DeleteProfileHandler))).Methods(http.MethodDelete)
+
+	router.Handle("/api/users/body_details/add", bodyDetailsWriteMiddleware.Then(http.HandlerFunc(service.AddBodyDetailsHandler))).Methods(http.MethodPost)
+	router.Handle("/api/users/body_details/exists", bodyDetailsReadMiddleware.Then(http.HandlerFunc(service.DoBodyDetailsExistHandler))).Methods(http.MethodGet)
+	router.Handle("/api/users/weigh_ins/get", bodyDetailsReadMiddleware.Then(http.HandlerFunc(service.GetWeighInsHandler))).Methods(http.MethodGet)
+	router.Handle("/api/users/weight_goal/set", bodyDetailsWriteMiddleware.Then(http.HandlerFunc(service.SetUserWeightGoalHandler))).Methods(http.MethodPost)
+
+	router.Handle("/api/users/log/create", logsWriteMiddleware.Then(http.HandlerFunc(service.CreateCalorieLogHandler))).Methods(http.MethodPost)
+	router.Handle("/api/users/log/get", logsReadMiddleware.Then(http.HandlerFunc(service.GetCalorieLogsHandler))).Methods(http.MethodGet)
+	router.Handle("/api/users/log/update", logsWriteMiddleware.Then(http.HandlerFunc(service.UpdateCalorieLogHandler))).Methods(http.MethodPut)
+	router.Handle("/api/users/log/mark_status", logsWriteMiddleware.Then(http.HandlerFunc(service.MarkLoggingStatusHandler))).Methods(http.MethodPost)
+	router.Handle("/api/users/log/delete", logsWriteMiddleware.Then(http.HandlerFunc(service.DeleteCalorieLogHandler))).Methods(http.MethodDelete)
+
+	router.Handle("/api/users/net_caloric_balance/get", balanceReadMiddleware.Then(http.HandlerFunc(service.GetNetCaloricBalanceHandler))).Methods(http.MethodGet)
+
+	router.Handle("/api/users/achievements/get", logsReadMiddleware.Then(http.HandlerFunc(service.GetAchievementsHandler))).Methods(http.MethodGet)
+	router.Handle("/api/users/streaks/get", logsReadMiddleware.Then(http.HandlerFunc(service.GetStreaksHandler))).Methods(http.MethodGet)
+
+	router.Handle("/api/users/coach/invite", sessionMiddleware.Then(http.HandlerFunc(service.InviteCoachHandler))).Methods(http.MethodPost)
+	router.Handle("/api/users/coach/get", sessionMiddleware.Then(http.HandlerFunc(service.GetCoachesHandler))).Methods(http.MethodGet)
+	router.Handle("/api/users/coach/revoke", sessionMiddleware.Then(http.HandlerFunc(service.RevokeCoachShareHandler))).Methods(http.MethodDelete)
+	router.Handle("/api/users/coach/access_log/get", sessionMiddleware.Then(http.HandlerFunc(service.GetCoachAccessLogHandler))).Methods(http.MethodGet)
+
+	router.Handle("/api/users/comments/get", sessionMiddleware.Then(http.HandlerFunc(service.GetLogCommentsHandler))).Methods(http.MethodGet)
+	router.Handle("/api/users/comments/new", sessionMiddleware.Then(http.HandlerFunc(service.GetNewFeedbackHandler))).Methods(http.MethodGet)
+	router.Handle("/api/users/comments/unread_counts", sessionMiddleware.Then(http.HandlerFunc(service.GetUnreadCommentCountsHandler))).Methods(http.MethodGet)
+	router.Handle("/api/users/comments/reply", sessionMiddleware.Then(http.HandlerFunc(service.ReplyLogCommentHandler))).Methods(http.MethodPost)
+	router.Handle("/api/users/comments/mark_read", sessionMiddleware.Then(http.HandlerFunc(service.MarkLogCommentsReadHandler))).Methods(http.MethodPost)
+
+	router.Handle("/api/groups/create", sessionMiddleware.Then(http.HandlerFunc(service.CreateGroupHandler))).Methods(http.MethodPost)
+	router.Handle("/api/groups/join", sessionMiddleware.Then(http.HandlerFunc(service.JoinGroupHandler))).Methods(http.MethodPost)
+	router.Handle("/api/groups/get", sessionMiddleware.Then(http.HandlerFunc(service.GetGroupsHandler))).Methods(http.MethodGet)
+	router.Handle("/api/groups/leave", sessionMiddleware.Then(http.HandlerFunc(service.LeaveGroupHandler))).Methods(http.MethodDelete)
+
+	router.Handle("/api/groups/challenges/create", sessionMiddleware.Then(http.HandlerFunc(service.CreateChallengeHandler))).Methods(http.MethodPost)
+	router.Handle("/api/groups/challenges/get", sessionMiddleware.Then(http.HandlerFunc(service.GetChallengesHandler))).Methods(http.MethodGet)
+	router.Handle("/api/groups/challenges/leaderboard", sessionMiddleware.Then(http.HandlerFunc(service.GetLeaderboardHandler))).Methods(http.MethodGet)
+
+	router.Handle("/api/coach/clients/get", coachMiddleware.Then(http.HandlerFunc(service.GetCoachClientsHandler))).Methods(http.MethodGet)
+	router.Handle("/api/coach/clients/accept", coachMiddleware.Then(http.HandlerFunc(service.AcceptCoachInviteHandler))).Methods(http.MethodPost)
+	router.Handle("/api/coach/clients/remove", coachMiddleware.Then(http.HandlerFunc(service.RevokeCoachShareHandler))).Methods(http.MethodDelete)
+
+	router.Handle("/api/coach/comments/unread_counts", coachMiddleware.Then(http.HandlerFunc(service.GetUnreadCommentCountsHandler))).Methods(http.MethodGet)
+
+	// A client's data as seen by their coach. The client's own data is only
+	// ever read here, coaches write nothing but comments.
+	router.Handle("/api/coach/clients/{profile_id}/log/get", coachClientMiddleware.Then(http.HandlerFunc(service.GetCalorieLogsHandler))).Methods(http.MethodGet)
+	router.Handle("/api/coach/clients/{profile_id}/weigh_ins/get", coachClientMiddleware.Then(http.HandlerFunc(service.GetWeighInsHandler))).Methods(http.MethodGet)
+	router.Handle("/api/coach/clients/{profile_id}/net_caloric_balance/get", coachClientMiddleware.Then(http.HandlerFunc(service.GetNetCaloricBalanceHandler))).Methods(http.MethodGet)
 
-	router.Handle("/api/users/body_details/add", authMiddleware.Then(http.HandlerFunc(AddBodyDetailsHandler))).Methods(http.MethodPost)
-	router.Handle("/api/users/body_details/exists", authMiddleware.Then(http.HandlerFunc(DoBodyDetailsExistHandler))).Methods(http.MethodGet)
-	router.Handle("/api/users/weight_goal/set", authMiddleware.Then(http.HandlerFunc(SetUserWeightGoalHandler))).Methods(http.MethodPost)
+	router.Handle("/api/coach/clients/{profile_id}/comments/get", coachClientMiddleware.Then(http.HandlerFunc(service.GetLogCommentsHandler))).Methods(http.MethodGet)
+	router.Handle("/api/coach/clients/{profile_id}/comments/create", coachClientMiddleware.Then(http.HandlerFunc(service.CreateLogCommentHandler))).Methods(http.MethodPost)
+	router.Handle("/api/coach/clients/{profile_id}/comments/reply", coachClientMiddleware.Then(http.HandlerFunc(service.ReplyLogCommentHandler))).Methods(http.MethodPost)
+	router.Handle("/api/coach/clients/{profile_id}/comments/mark_read", coachClientMiddleware.Then(http.HandlerFunc(service.MarkLogCommentsReadHandler))).Methods(http.MethodPost)
 
-	router.Handle("/api/users/log/create", authMiddleware.Then(http.HandlerFunc(CreateCalorieLogHandler))).Methods(http.MethodPost)
-	router.Handle("/api/users/log/get", authMiddleware.Then(http.HandlerFunc(GetCalorieLogsHandler))).Methods(http.MethodGet)
-	router.Handle("/api/users/log/update", authMiddleware.Then(http.HandlerFunc(UpdateCalorieLogHandler))).Methods(http.MethodPut)
-	router.Handle("/api/users/log/mark_status", authMiddleware.Then(http.HandlerFunc(MarkLoggingStatusHandler))).Methods(http.MethodPost)
-	router.Handle("/api/users/log/delete", authMiddleware.Then(http.HandlerFunc(DeleteCalorieLogHandler))).Methods(http.MethodDelete)
+	router.Handle("/api/admin/users/get", adminMiddleware.Then(http.HandlerFunc(service.AdminGetUsersHandler))).Methods(http.MethodGet)
+	router.Handle("/api/admin/users/disable", adminMiddleware.Then(http.HandlerFunc(service.AdminDisableUserHandler))).Methods(http.MethodPost)
+	router.Handle("/api/admin/users/enable", adminMiddleware.Then(http.HandlerFunc(service.AdminEnableUserHandler))).Methods(http.MethodPost)
+	router.Handle("/api/admin/users/password/reset", adminMiddleware.Then(http.HandlerFunc(service.AdminResetPasswordHandler))).Methods(http.MethodPost)
+	router.Handle("/api/admin/users/role/set", adminMiddleware.Then(http.HandlerFunc(service.AdminSetUserRoleHandler))).Methods(http.MethodPost)
 
-	router.Handle("/api/users/net_caloric_balance/get", authMiddleware.Then(http.HandlerFunc(GetNetCaloricBalanceHandler))).Methods(http.MethodGet)
+	// Answer preflights for every route registered above
+	router.Methods(http.MethodOptions).Handler(service.cors.PreflightHandler(router))
 
 	return router
 }
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"calometer/internal/config"
+	"calometer/internal/db"
+	"calometer/internal/lib"
+	"calometer/internal/oidc"
+	"fmt"
+	"sync/atomic"
+)
+
+// Stores are where the handlers keep their data, Postgres or SQLite in the
+// server and something else elsewhere.
+type Stores struct {
+	Users         lib.UserStore
+	TwoFactor     lib.TwoFactorStore
+	CalorieLogs   lib.CalorieLogStore
+	Balances      lib.BalanceStore
+	RefreshTokens lib.RefreshTokenStore
+	AccessTokens  lib.AccessTokenStore
+	Profiles      lib.ProfileStore
+	WeighIns      lib.WeighInStore
+	Coaches       lib.CoachStore
+	LogComments   lib.LogCommentStore
+	Groups        lib.GroupStore
+	Challenges    lib.ChallengeStore
+	Achievements  lib.AchievementStore
+	// LoginAttempts backs the login throttle when it isn't kept in memory
+	LoginAttempts lib.LoginAttemptStore
+	// Database answers the readiness and version probes
+	Database db.Health
+}
+
+// NewStores backs every feature with store.
+func NewStores(store lib.Store, loginAttempts lib.LoginAttemptStore, database db.Health) Stores {
+	return Stores{
+		Users:         store,
+		TwoFactor:     store,
+		CalorieLogs:   store,
+		Balances:      store,
+		RefreshTokens: store,
+		AccessTokens:  store,
+		Profiles:      store,
+		WeighIns:      store,
+		Coaches:       store,
+		LogComments:   store,
+		Groups:        store,
+		Challenges:    store,
+		Achievements:  store,
+		LoginAttempts: loginAttempts,
+		Database:      database,
+	}
+}
+
+// Service carries the stores the handlers work with and everything built
+// from the configuration, so handlers read no package state.
+type Service struct {
+	Stores
+
+	cfg            *config.Config
+	keys           *lib.KeyManager
+	loginThrottle  *lib.LoginThrottle
+	passwordPolicy *lib.PasswordPolicy
+	// oidc is nil when OpenID Connect login is not configured
+	oidc          *oidc.Provider
+	cors          *CORS
+	queryTimeouts *QueryTimeouts
+
+	// shuttingDown fails readiness once graceful shutdown starts, so the
+	// orchestrator stops sending traffic while in-flight requests drain
+	shuttingDown atomic.Bool
+}
+
+// NewService sets up signing keys, login throttling, the password policy,
+// the identity provider, CORS and query deadlines from cfg.
+func NewService(cfg *config.Config, stores Stores) (*Service, error) {
+	keys, err := lib.NewKeyManagerFromConfig(cfg.JWT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize signing keys: %w", err)
+	}
+
+	loginThrottle, err := lib.NewLoginThrottleFromConfig(cfg.LoginThrottle, stores.LoginAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize login throttle: %w", err)
+	}
+
+	passwordPolicy, err := lib.NewPasswordPolicyFromConfig(cfg.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize password policy: %w", err)
+	}
+
+	return &Service{
+		Stores:         stores,
+		cfg:            cfg,
+		keys:           keys,
+		loginThrottle:  loginThrottle,
+		passwordPolicy: passwordPolicy,
+		oidc:           oidc.NewProviderFromConfig(cfg.OIDC),
+		cors:           NewCORSFromConfig(cfg.CORS),
+		queryTimeouts:  NewQueryTimeouts(cfg.QueryTimeout),
+	}, nil
+}
+
+// SetShuttingDown marks the server as draining.
+func (s *Service) SetShuttingDown() {
+	s.shuttingDown.Store(true)
+}
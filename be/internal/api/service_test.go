@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bytes"
+	"calometer/internal/config"
+	"calometer/internal/lib"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// testConfig is the default configuration plus what it requires.
+func testConfig() *config.Config {
+	cfg := config.Default()
+	cfg.JWT.Secret = "test secret"
+
+	return cfg
+}
+
+// newTestService backs a Service configured with cfg by a fresh MemoryStore.
+func newTestService(t *testing.T, cfg *config.Config) (*Service, *lib.MemoryStore) {
+	t.Helper()
+
+	store := lib.NewMemoryStore()
+
+	service, err := NewService(cfg, Stores{
+		Users:       store,
+		TwoFactor:   store,
+		CalorieLogs: store,
+		Balances:    store,
+	})
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	return service, store
+}
+
+// newSqliteTestService backs a Service by a fresh SQLite database, for the
+// stores MemoryStore doesn't implement.
+func newSqliteTestService(t *testing.T, cfg *config.Config) (*Service, *lib.SqliteStore) {
+	t.Helper()
+
+	backend := openSqliteBackend(t)
+	store := backend.store.(*lib.SqliteStore)
+
+	service, err := NewService(cfg, NewStores(store, backend.loginAttempts, backend.database))
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	return service, store
+}
+
+func createTestUser(t *testing.T, store lib.UserStore, username string, password string) uuid.UUID {
+	t.Helper()
+
+	passwordHash, err := lib.HashPassword(password)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	userId, err := store.CreateUser(context.Background(), username, username, passwordHash)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	return *userId
+}
+
+func newJSONRequest(t *testing.T, method string, target string, body interface{}) *http.Request {
+	t.Helper()
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("failed to encode request: %v", err)
+	}
+
+	return httptest.NewRequest(method, target, bytes.NewReader(data))
+}
+
+// expectCode fails unless the response body carries the code.
+func expectCode(t *testing.T, w *httptest.ResponseRecorder, code int) Response {
+	t.Helper()
+
+	resp := Response{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if _, ok := resp.Code[code]; !ok {
+		t.Fatalf("response code = %v, want %d", resp.Code, code)
+	}
+
+	return resp
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// startSession issues a new access token and refresh token family for the
+// user and sets them as cookies. Disabled users get lib.ErrUserDisabled.
+func (s *Service) startSession(ctx context.Context, w http.ResponseWriter, userId uuid.UUID, username string) error {
+	status, err := lib.GetActiveUserStatus(ctx, s.Users, userId)
+	if err != nil {
+		return err
+	}
+
+	token, err := s.keys.GenerateJWT(userId, username, status.Role)
+	if err != nil {
+		return err
+	}
+
+	refreshToken, err := s.RefreshTokens.IssueRefreshToken(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	s.setSessionCookies(w, token, refreshToken)
+
+	return nil
+}
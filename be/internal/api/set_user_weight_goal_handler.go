@@ -1,10 +1,10 @@
 package api
 
 import (
-	"calometer/internal/lib"
 	"encoding/json"
 	"net/http"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -12,20 +12,20 @@ type SetUserWeightGoalReq struct {
 	Goal string `json:"goal"`
 }
 
-func SetUserWeightGoalHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Service) SetUserWeightGoalHandler(w http.ResponseWriter, r *http.Request) {
 	resp := Response{}
 	resp.Code = make(map[int]string)
 
-	// Retrieve the token from the context
-	tokenStr, ok := r.Context().Value(TokenContextKey).(string)
+	// Retrieve the profile id from the context
+	profileId, ok := r.Context().Value(ProfileIdContextKey).(uuid.UUID)
 	if !ok {
 		log.Info(
-			"token not found in context",
+			"profile id not found in context",
 		)
 
-		// Token is not present in context
+		// Profile id is not present in context
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
@@ -37,36 +37,23 @@ func SetUserWeightGoalHandler(w http.ResponseWriter, r *http.Request) {
 		)
 
 		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	userId, err := lib.ExtractUserIdFromToken(tokenStr)
-	if err != nil {
-		log.Info(
-			"failed to extract username from token",
-			zap.String("tokenStr", tokenStr),
-			zap.Error(err),
-		)
-
-		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
-		return
-	}
-
-	if err := lib.SetUserGoal(*userId, req.Goal); err != nil {
+	if err := s.Users.SetUserGoal(r.Context(), profileId, req.Goal); err != nil {
 		log.Info(
 			"failed to set user's goal by id",
-			zap.String("userId", userId.String()),
+			zap.String("profileId", profileId.String()),
 			zap.Error(err),
 		)
 
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	resp.Code[http.StatusOK] = "OK"
-	json.NewEncoder(w).Encode(&resp)
+	writeResponse(w, &resp)
 }
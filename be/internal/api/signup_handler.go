@@ -1,9 +1,8 @@
 package api
 
 import (
-	"calometer/internal/db"
 	"calometer/internal/lib"
-	"context"
+	"calometer/internal/metrics"
 	"encoding/json"
 	"net/http"
 
@@ -22,18 +21,18 @@ type SignupHandlerResp struct {
 	UserId uuid.UUID `json:"u_id"`
 }
 
-func SignUpHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Service) SignUpHandler(w http.ResponseWriter, r *http.Request) {
 	resp := Response{}
 	resp.Code = make(map[int]string)
 
-	cookie, err := r.Cookie("token")
+	cookie, err := r.Cookie(tokenCookieName)
 	if err == nil {
 		// Validate the JWT
-		if err := lib.ValidateToken(cookie.Value); err == nil {
+		if err := s.keys.ValidateToken(cookie.Value); err == nil {
 			// Token is valid, return a success response
 			w.WriteHeader(http.StatusOK)
 			resp.Code[http.StatusOK] = "Logged in successfully."
-			json.NewEncoder(w).Encode(&resp)
+			writeResponse(w, &resp)
 			return
 		}
 	}
@@ -42,32 +41,39 @@ func SignUpHandler(w http.ResponseWriter, r *http.Request) {
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
 		log.Info("failed to decode incoming json")
 		resp.Code[http.StatusBadRequest] = "Please enter correct details"
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
 	if user.Username == "" || user.Password == "" || user.Name == "" {
 		log.Info("invalid input data")
 		resp.Code[http.StatusBadRequest] = "Please enter correct details."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
-	doesExist, err := lib.DoesUserExists(user.Username)
+	if errs := s.passwordPolicy.Validate(user.Password, user.Username); len(errs) > 0 {
+		resp.Code[http.StatusBadRequest] = "Password does not meet the requirements."
+		resp.Data = &ValidationErrorsResp{Errors: errs}
+		writeResponse(w, &resp)
+		return
+	}
+
+	doesExist, err := s.Users.DoesUserExists(r.Context(), user.Username)
 	if err != nil {
 		log.Info(
 			"failed to check user's existence by username",
 			zap.String("username", user.Username),
 		)
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
 	if *doesExist {
 		log.Info("username already exists")
 		resp.Code[http.StatusConflict] = "Username already exists"
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
@@ -78,26 +84,20 @@ func SignUpHandler(w http.ResponseWriter, r *http.Request) {
 			zap.String("username", user.Username),
 		)
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
 	// Save user to the database
-	qStr := `
-	INSERT INTO users (name, username, password_hash)
-	VALUES ($1, $2, $3)
-	RETURNING id
-  `
-
-	var userId uuid.UUID
-	if err := db.GetPool().QueryRow(context.Background(), qStr, user.Name, user.Username, password).Scan(&userId); err != nil {
+	userId, err := s.Users.CreateUser(r.Context(), user.Name, user.Username, password)
+	if err != nil {
 		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
 			log.Info(
 				"username already exists",
 				zap.String("username", user.Username),
 			)
 			resp.Code[http.StatusConflict] = "Username already exists."
-			json.NewEncoder(w).Encode(&resp)
+			writeResponse(w, &resp)
 			return
 		}
 
@@ -106,17 +106,19 @@ func SignUpHandler(w http.ResponseWriter, r *http.Request) {
 			zap.String("username", user.Username),
 		)
 		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
-		json.NewEncoder(w).Encode(&resp)
+		writeResponse(w, &resp)
 		return
 	}
 
+	metrics.Signups.WithLabelValues("password").Inc()
+
 	w.WriteHeader(http.StatusOK)
 
 	data := &SignupHandlerResp{
-		UserId: userId,
+		UserId: *userId,
 	}
 
 	resp.Code[http.StatusOK] = "OK"
 	resp.Data = data
-	json.NewEncoder(w).Encode(&resp)
+	writeResponse(w, &resp)
 }
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// verifySecondFactor accepts either a current TOTP code or one of the user's
+// unused recovery codes.
+func (s *Service) verifySecondFactor(ctx context.Context, userId uuid.UUID, code string, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		used, err := s.TwoFactor.UseRecoveryCode(ctx, userId, recoveryCode)
+		if err != nil {
+			return false, err
+		}
+
+		return *used, nil
+	}
+
+	valid, err := s.TwoFactor.VerifyTOTPCode(ctx, userId, code)
+	if err != nil {
+		return false, err
+	}
+
+	return *valid, nil
+}
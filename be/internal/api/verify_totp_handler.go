@@ -0,0 +1,83 @@
+package api
+
+import (
+	"calometer/internal/lib"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type VerifyTOTPReq struct {
+	Code string `json:"code"`
+}
+
+type VerifyTOTPResp struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (s *Service) VerifyTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	// Retrieve the user id from the context
+	userId, ok := r.Context().Value(UserIdContextKey).(uuid.UUID)
+	if !ok {
+		log.Info(
+			"user id not found in context",
+		)
+
+		// User id is not present in context
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+
+	var req VerifyTOTPReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Info(
+			"failed to decode incoming json",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusBadRequest] = "Invalid JSON data."
+		writeResponse(w, &resp)
+		return
+	}
+
+	recoveryCodes, err := s.TwoFactor.VerifyTOTPEnrollment(r.Context(), userId, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, lib.ErrTOTPInvalidCode):
+			resp.Code[http.StatusBadRequest] = "Invalid code."
+		case errors.Is(err, lib.ErrTOTPNotEnrolled):
+			resp.Code[http.StatusConflict] = "Two-factor authentication enrollment not started."
+		case errors.Is(err, lib.ErrTOTPAlreadyEnabled):
+			resp.Code[http.StatusConflict] = "Two-factor authentication is already enabled."
+		default:
+			log.Info(
+				"failed to verify totp enrollment by user id",
+				zap.String("userId", userId.String()),
+				zap.Error(err),
+			)
+
+			resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		}
+
+		writeResponse(w, &resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	// Recovery codes are only ever shown once, only their hashes are stored
+	data := &VerifyTOTPResp{
+		RecoveryCodes: recoveryCodes,
+	}
+
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
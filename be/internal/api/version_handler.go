@@ -0,0 +1,58 @@
+package api
+
+import (
+	"calometer/internal/db"
+	"calometer/internal/version"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+type VersionResp struct {
+	GitSHA    string `json:"git_sha"`
+	BuildTime string `json:"build_time"`
+	// SchemaVersion is the applied migration, missing when the database
+	// can't be read
+	SchemaVersion *uint `json:"schema_version"`
+	// LatestSchemaVersion is the last migration this build carries
+	LatestSchemaVersion uint `json:"latest_schema_version"`
+}
+
+func (s *Service) VersionHandler(w http.ResponseWriter, r *http.Request) {
+	resp := Response{}
+	resp.Code = make(map[int]string)
+
+	data := &VersionResp{}
+	data.GitSHA, data.BuildTime = version.Get()
+
+	driver := s.cfg.Database.Driver
+
+	latest, err := db.LatestMigrationVersion(driver)
+	if err != nil {
+		log.Info(
+			"failed to read latest migration version",
+			zap.Error(err),
+		)
+
+		resp.Code[http.StatusInternalServerError] = "Something went wrong, please try again."
+		writeResponse(w, &resp)
+		return
+	}
+	data.LatestSchemaVersion = latest
+
+	// The build is still worth reporting while the database is down
+	schema, err := s.Database.GetSchemaStatus(r.Context())
+	if err != nil {
+		log.Info(
+			"failed to read schema version",
+			zap.Error(err),
+		)
+	} else {
+		data.SchemaVersion = &schema.Version
+	}
+
+	w.WriteHeader(http.StatusOK)
+	resp.Code[http.StatusOK] = "OK"
+	resp.Data = data
+	writeResponse(w, &resp)
+}
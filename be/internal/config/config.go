@@ -0,0 +1,376 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config is everything calometer can be configured with. Load fills it from
+// defaults, an optional file, the environment and flags, in that order, so
+// each later source overrides the earlier ones.
+type Config struct {
+	// AppEnv is "production" when cookies have to be Secure
+	AppEnv string
+	// FEURL is the frontend, users are sent back to it after OpenID
+	// Connect login
+	FEURL string
+
+	Server        ServerConfig
+	Database      DatabaseConfig
+	JWT           JWTConfig
+	Cookies       CookieConfig
+	CORS          CORSConfig
+	QueryTimeout  time.Duration
+	LoginThrottle LoginThrottleConfig
+	Password      PasswordConfig
+	OIDC          OIDCConfig
+	// TrustProxyHeaders takes the client IP from X-Forwarded-For, only
+	// enable it behind a proxy that sets the header
+	TrustProxyHeaders bool
+}
+
+type ServerConfig struct {
+	// Addr is the TCP address, empty to only listen on UnixSocket
+	Addr       string
+	UnixSocket string
+	// TLSCertFile and TLSKeyFile serve HTTPS when both are set
+	TLSCertFile string
+	TLSKeyFile  string
+
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout has to leave room for the longest query deadline
+	WriteTimeout   time.Duration
+	IdleTimeout    time.Duration
+	MaxHeaderBytes int
+	// ShutdownTimeout is how long in-flight requests get to finish
+	ShutdownTimeout time.Duration
+	// ShutdownDelay keeps accepting requests with readiness failing, so the
+	// orchestrator notices before connections are refused
+	ShutdownDelay time.Duration
+}
+
+type DatabaseConfig struct {
+	// Driver is "postgres" or "sqlite"
+	Driver     string
+	URL        string
+	SQLitePath string
+	// SlowQueryThreshold is when a query gets logged as slow
+	SlowQueryThreshold time.Duration
+	// MigrateOnStart applies pending migrations instead of refusing to
+	// start
+	MigrateOnStart bool
+}
+
+type JWTConfig struct {
+	// SigningAlg is HS256, RS256 or EdDSA, ignored when KeysDir is set
+	SigningAlg     string
+	Secret         string
+	PreviousSecret string
+	// PreviousSecretRetiredAt is when Secret replaced PreviousSecret, the
+	// grace period counts from it
+	PreviousSecretRetiredAt time.Time
+	// KeysDir holds <kid>.pem files, ActiveKid picks the signing key
+	KeysDir   string
+	ActiveKid string
+	// KeysRotatedAt is when ActiveKid took over signing, the grace period of
+	// the other keys in KeysDir counts from it
+	KeysRotatedAt  time.Time
+	KeyGracePeriod time.Duration
+}
+
+type CookieConfig struct {
+	// SameSite is "lax", "strict" or "none"
+	SameSite string
+}
+
+type CORSConfig struct {
+	// AllowedOrigins defaults to FEURL
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	MaxAge         time.Duration
+}
+
+type LoginThrottleConfig struct {
+	// Store is "memory", "postgres" or "sqlite"
+	Store string
+	// MaxFailures is per username, MaxIPFailures per client IP across all
+	// usernames
+	MaxFailures     int
+	MaxIPFailures   int
+	LockoutDuration time.Duration
+}
+
+type PasswordConfig struct {
+	MinLength      int
+	MaxLength      int
+	RequireUpper   bool
+	RequireLower   bool
+	RequireDigit   bool
+	RequireSymbol  bool
+	RejectUsername bool
+	// MaxRepeated rejects runs of more of the same character, 0 allows any
+	MaxRepeated int
+	// ContextWords are rejected like the username, the site name and such
+	ContextWords []string
+	// BreachedPasswordsFile lists SHA-1 hashes of leaked passwords
+	BreachedPasswordsFile string
+}
+
+type OIDCConfig struct {
+	// Issuer enables OpenID Connect login when set
+	Issuer       string
+	ProviderName string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// AllowSignup creates accounts for unknown identities
+	AllowSignup bool
+}
+
+// Default is the configuration before any source is read.
+func Default() *Config {
+	return &Config{
+		AppEnv: "development",
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderBytes:    64 << 10,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver:             "postgres",
+			SQLitePath:         "calometer.db",
+			SlowQueryThreshold: 500 * time.Millisecond,
+		},
+		JWT: JWTConfig{
+			SigningAlg:     "HS256",
+			KeyGracePeriod: time.Hour,
+		},
+		Cookies: CookieConfig{
+			SameSite: "lax",
+		},
+		CORS: CORSConfig{
+			MaxAge: 10 * time.Minute,
+		},
+		QueryTimeout: 5 * time.Second,
+		LoginThrottle: LoginThrottleConfig{
+			Store:           "memory",
+			MaxFailures:     5,
+			MaxIPFailures:   20,
+			LockoutDuration: 15 * time.Minute,
+		},
+		Password: PasswordConfig{
+			MinLength: 8,
+			// bcrypt ignores everything after 72 bytes
+			MaxLength:      72,
+			RejectUsername: true,
+			ContextWords:   []string{"calometer"},
+		},
+		OIDC: OIDCConfig{
+			ProviderName: "oidc",
+		},
+	}
+}
+
+func (c *Config) IsProduction() bool {
+	return c.AppEnv == "production"
+}
+
+// Load reads the configuration for a command started with args. Values
+// that don't parse are errors, what a command requires is left to Validate
+// or DatabaseConfig.Validate.
+//
+// The file is -config or CONFIG_FILE, holding KEY=VALUE lines with the same
+// keys as the environment. Without either, a .env file in the working
+// directory is read if there is one. Every key also has a flag, DB_URL is
+// -db-url.
+func Load(name string, args []string) (*Config, error) {
+	c := Default()
+	settings := c.settings()
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile := flags.String("config", "", "read settings from this file")
+
+	flagValues := map[string]string{}
+	for _, s := range settings {
+		key := s.key
+		flags.Func(flagName(key), s.usage, func(value string) error {
+			flagValues[key] = value
+			return nil
+		})
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	fileValues, err := readFile(*configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, s := range settings {
+		value, ok := flagValues[s.key]
+		if !ok {
+			value, ok = os.LookupEnv(s.key)
+		}
+		if !ok {
+			value, ok = fileValues[s.key]
+		}
+		if !ok {
+			continue
+		}
+
+		if err := s.set(value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: %w", s.key, value, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	if len(c.CORS.AllowedOrigins) == 0 && c.FEURL != "" {
+		c.CORS.AllowedOrigins = []string{c.FEURL}
+	}
+
+	return c, nil
+}
+
+func readFile(path string) (map[string]string, error) {
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+
+	if path == "" {
+		// The default file is optional
+		if _, err := os.Stat(".env"); err != nil {
+			return map[string]string{}, nil
+		}
+		path = ".env"
+	}
+
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	return values, nil
+}
+
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+// Validate reports every missing or inconsistent value the server needs at
+// once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if err := c.Database.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.Server.Addr == "" && c.Server.UnixSocket == "" {
+		errs = append(errs, errors.New("ADDR or UNIX_SOCKET is required"))
+	}
+
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		errs = append(errs, errors.New("TLS_CERT_FILE and TLS_KEY_FILE have to be set together"))
+	}
+
+	if c.Server.ShutdownDelay < 0 {
+		errs = append(errs, errors.New("SHUTDOWN_DELAY can't be negative"))
+	}
+
+	if c.Server.MaxHeaderBytes < 1 {
+		errs = append(errs, errors.New("MAX_HEADER_BYTES must be positive"))
+	}
+
+	if c.JWT.KeysDir == "" && c.JWT.SigningAlg == "HS256" && c.JWT.Secret == "" {
+		errs = append(errs, errors.New("JWT_SECRET is required unless JWT_KEYS_DIR or another JWT_SIGNING_ALG is set"))
+	}
+
+	if c.JWT.PreviousSecret != "" && c.JWT.PreviousSecretRetiredAt.IsZero() {
+		errs = append(errs, errors.New("JWT_PREVIOUS_SECRET_RETIRED_AT is required with JWT_PREVIOUS_SECRET"))
+	}
+
+	// Keys generated at startup log everyone out on every restart and
+	// differ between replicas
+	if c.JWT.KeysDir == "" && c.JWT.SigningAlg != "HS256" && c.AppEnv != "development" {
+		errs = append(errs, fmt.Errorf("JWT_KEYS_DIR is required for JWT_SIGNING_ALG %s outside development", c.JWT.SigningAlg))
+	}
+
+	switch c.Cookies.SameSite {
+	case "lax", "strict", "none":
+	default:
+		errs = append(errs, fmt.Errorf("COOKIE_SAME_SITE must be lax, strict or none, got %q", c.Cookies.SameSite))
+	}
+
+	if c.QueryTimeout <= 0 {
+		errs = append(errs, errors.New("QUERY_TIMEOUT must be positive"))
+	}
+
+	switch c.LoginThrottle.Store {
+	case "memory":
+	case "postgres", "sqlite":
+		// Attempts are kept in the database the server already uses
+		if c.LoginThrottle.Store != c.Database.Driver {
+			errs = append(errs, fmt.Errorf("LOGIN_THROTTLE_STORE must be memory or match DB_DRIVER %q, got %q", c.Database.Driver, c.LoginThrottle.Store))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("LOGIN_THROTTLE_STORE must be memory, postgres or sqlite, got %q", c.LoginThrottle.Store))
+	}
+
+	if c.LoginThrottle.MaxFailures < 1 {
+		errs = append(errs, errors.New("LOGIN_MAX_FAILURES must be at least 1"))
+	}
+
+	if c.LoginThrottle.MaxIPFailures < 1 {
+		errs = append(errs, errors.New("LOGIN_MAX_IP_FAILURES must be at least 1"))
+	}
+
+	if c.Password.MinLength < 0 || c.Password.MaxLength < c.Password.MinLength {
+		errs = append(errs, errors.New("PASSWORD_MAX_LENGTH must be at least PASSWORD_MIN_LENGTH"))
+	}
+
+	if c.Password.MaxRepeated < 0 {
+		errs = append(errs, errors.New("PASSWORD_MAX_REPEATED can't be negative"))
+	}
+
+	if c.OIDC.Issuer != "" && (c.OIDC.ClientID == "" || c.OIDC.RedirectURL == "") {
+		errs = append(errs, errors.New("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required when OIDC_ISSUER is set"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate is all the command line tools need, they only use the database.
+func (d DatabaseConfig) Validate() error {
+	switch d.Driver {
+	case "postgres":
+		if d.URL == "" {
+			return errors.New("DB_URL is required with the postgres driver")
+		}
+	case "sqlite":
+		if d.SQLitePath == "" {
+			return errors.New("SQLITE_PATH is required with the sqlite driver")
+		}
+	default:
+		return fmt.Errorf("DB_DRIVER must be postgres or sqlite, got %q", d.Driver)
+	}
+
+	return nil
+}
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateRequiresKeysDirForAsymmetricKeys(t *testing.T) {
+	for _, env := range []string{"development", "production"} {
+		cfg := Default()
+		cfg.AppEnv = env
+		cfg.JWT.SigningAlg = "EdDSA"
+
+		err := cfg.Validate()
+		rejected := err != nil && strings.Contains(err.Error(), "JWT_KEYS_DIR")
+
+		if rejected != (env != "development") {
+			t.Fatalf("%s: Validate() = %v", env, err)
+		}
+	}
+}
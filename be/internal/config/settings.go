@@ -0,0 +1,153 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting is a single key, read from the file and environment as is and
+// from flags in lower case with dashes.
+type setting struct {
+	key   string
+	usage string
+	set   func(value string) error
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		stringSetting("APP_ENV", "production enables Secure cookies", &c.AppEnv),
+		stringSetting("FE_URL", "frontend URL", &c.FEURL),
+		stringSetting("ADDR", "TCP address to listen on, empty to disable", &c.Server.Addr),
+		stringSetting("UNIX_SOCKET", "Unix socket path to listen on", &c.Server.UnixSocket),
+		stringSetting("TLS_CERT_FILE", "TLS certificate, enables HTTPS with TLS_KEY_FILE", &c.Server.TLSCertFile),
+		stringSetting("TLS_KEY_FILE", "TLS private key", &c.Server.TLSKeyFile),
+		durationSetting("READ_TIMEOUT", "time to read a whole request", &c.Server.ReadTimeout),
+		durationSetting("READ_HEADER_TIMEOUT", "time to read request headers", &c.Server.ReadHeaderTimeout),
+		durationSetting("WRITE_TIMEOUT", "time to write a response", &c.Server.WriteTimeout),
+		durationSetting("IDLE_TIMEOUT", "how long idle keep-alive connections stay open", &c.Server.IdleTimeout),
+		intSetting("MAX_HEADER_BYTES", "largest request header accepted", &c.Server.MaxHeaderBytes),
+		durationSetting("SHUTDOWN_TIMEOUT", "time in-flight requests get to finish on shutdown", &c.Server.ShutdownTimeout),
+		durationSetting("SHUTDOWN_DELAY", "time readiness fails before the server stops accepting connections", &c.Server.ShutdownDelay),
+
+		stringSetting("DB_DRIVER", "postgres or sqlite", &c.Database.Driver),
+		stringSetting("DB_URL", "Postgres connection URL", &c.Database.URL),
+		stringSetting("SQLITE_PATH", "SQLite database file", &c.Database.SQLitePath),
+		durationSetting("SLOW_QUERY_THRESHOLD", "log queries slower than this", &c.Database.SlowQueryThreshold),
+		boolSetting("MIGRATE_ON_START", "apply pending migrations at startup, otherwise run \"migrate up\" first", &c.Database.MigrateOnStart),
+
+		stringSetting("JWT_SIGNING_ALG", "HS256, RS256 or EdDSA", &c.JWT.SigningAlg),
+		stringSetting("JWT_SECRET", "HS256 signing secret", &c.JWT.Secret),
+		stringSetting("JWT_PREVIOUS_SECRET", "HS256 secret still accepted during rotation", &c.JWT.PreviousSecret),
+		timeSetting("JWT_PREVIOUS_SECRET_RETIRED_AT", "RFC 3339 time JWT_SECRET replaced JWT_PREVIOUS_SECRET", &c.JWT.PreviousSecretRetiredAt),
+		stringSetting("JWT_KEYS_DIR", "directory of <kid>.pem signing keys", &c.JWT.KeysDir),
+		stringSetting("JWT_ACTIVE_KID", "key in JWT_KEYS_DIR that signs new tokens", &c.JWT.ActiveKid),
+		timeSetting("JWT_KEYS_ROTATED_AT", "RFC 3339 time JWT_ACTIVE_KID replaced the other keys in JWT_KEYS_DIR", &c.JWT.KeysRotatedAt),
+		durationSetting("JWT_KEY_GRACE_PERIOD", "how long retired keys keep validating", &c.JWT.KeyGracePeriod),
+
+		{
+			key:   "COOKIE_SAME_SITE",
+			usage: "lax, strict or none",
+			set: func(value string) error {
+				c.Cookies.SameSite = strings.ToLower(value)
+				return nil
+			},
+		},
+
+		listSetting("CORS_ALLOWED_ORIGINS", "comma separated origins, FE_URL by default", &c.CORS.AllowedOrigins),
+		listSetting("CORS_ALLOWED_METHODS", "comma separated methods", &c.CORS.AllowedMethods),
+		listSetting("CORS_ALLOWED_HEADERS", "comma separated request headers", &c.CORS.AllowedHeaders),
+		durationSetting("CORS_MAX_AGE", "how long browsers cache preflights", &c.CORS.MaxAge),
+
+		durationSetting("QUERY_TIMEOUT", "default deadline for a request's queries", &c.QueryTimeout),
+		boolSetting("TRUST_PROXY_HEADERS", "take the client IP from X-Forwarded-For", &c.TrustProxyHeaders),
+
+		stringSetting("LOGIN_THROTTLE_STORE", "memory, postgres or sqlite", &c.LoginThrottle.Store),
+		intSetting("LOGIN_MAX_FAILURES", "failed logins for a username before a lockout", &c.LoginThrottle.MaxFailures),
+		intSetting("LOGIN_MAX_IP_FAILURES", "failed logins from an IP before a lockout", &c.LoginThrottle.MaxIPFailures),
+		durationSetting("LOGIN_LOCKOUT_DURATION", "how long a lockout lasts", &c.LoginThrottle.LockoutDuration),
+
+		intSetting("PASSWORD_MIN_LENGTH", "shortest password accepted", &c.Password.MinLength),
+		intSetting("PASSWORD_MAX_LENGTH", "longest password accepted", &c.Password.MaxLength),
+		boolSetting("PASSWORD_REQUIRE_UPPER", "require an upper case letter", &c.Password.RequireUpper),
+		boolSetting("PASSWORD_REQUIRE_LOWER", "require a lower case letter", &c.Password.RequireLower),
+		boolSetting("PASSWORD_REQUIRE_DIGIT", "require a digit", &c.Password.RequireDigit),
+		boolSetting("PASSWORD_REQUIRE_SYMBOL", "require a symbol", &c.Password.RequireSymbol),
+		boolSetting("PASSWORD_REJECT_USERNAME", "reject passwords containing the username", &c.Password.RejectUsername),
+		intSetting("PASSWORD_MAX_REPEATED", "longest run of one character, 0 for any", &c.Password.MaxRepeated),
+		listSetting("PASSWORD_CONTEXT_WORDS", "comma separated words passwords must not contain", &c.Password.ContextWords),
+		stringSetting("BREACHED_PASSWORDS_FILE", "file of SHA-1 hashes of leaked passwords", &c.Password.BreachedPasswordsFile),
+
+		stringSetting("OIDC_ISSUER", "OpenID Connect issuer, enables OIDC login", &c.OIDC.Issuer),
+		stringSetting("OIDC_PROVIDER_NAME", "name of the OpenID Connect provider", &c.OIDC.ProviderName),
+		stringSetting("OIDC_CLIENT_ID", "OpenID Connect client id", &c.OIDC.ClientID),
+		stringSetting("OIDC_CLIENT_SECRET", "OpenID Connect client secret", &c.OIDC.ClientSecret),
+		stringSetting("OIDC_REDIRECT_URL", "OpenID Connect callback URL", &c.OIDC.RedirectURL),
+		boolSetting("OIDC_ALLOW_SIGNUP", "create accounts for new OpenID Connect users", &c.OIDC.AllowSignup),
+	}
+}
+
+func stringSetting(key string, usage string, target *string) setting {
+	return setting{key: key, usage: usage, set: func(value string) error {
+		*target = value
+		return nil
+	}}
+}
+
+func boolSetting(key string, usage string, target *bool) setting {
+	return setting{key: key, usage: usage, set: func(value string) error {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*target = parsed
+		return nil
+	}}
+}
+
+func intSetting(key string, usage string, target *int) setting {
+	return setting{key: key, usage: usage, set: func(value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*target = parsed
+		return nil
+	}}
+}
+
+func durationSetting(key string, usage string, target *time.Duration) setting {
+	return setting{key: key, usage: usage, set: func(value string) error {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*target = parsed
+		return nil
+	}}
+}
+
+func timeSetting(key string, usage string, target *time.Time) setting {
+	return setting{key: key, usage: usage, set: func(value string) error {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return err
+		}
+		*target = parsed
+		return nil
+	}}
+}
+
+// listSetting splits comma separated values, dropping empty ones.
+func listSetting(key string, usage string, target *[]string) setting {
+	return setting{key: key, usage: usage, set: func(value string) error {
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*target = items
+		return nil
+	}}
+}
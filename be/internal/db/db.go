@@ -1,25 +1,24 @@
 package db
 
 import (
+	"calometer/internal/config"
 	"context"
-	"os"
 
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/joho/godotenv"
 )
 
-// Package-level variable to hold the connection pool
-var pool *pgxpool.Pool
-
 // init function to initialize the connection pool
-func Init() (*pgxpool.Pool, error) {
-	dbURL, err := GetDBUrl()
+func Init(cfg config.DatabaseConfig) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
 	if err != nil {
 		return nil, err
 	}
 
+	// Log slow and canceled queries
+	poolConfig.ConnConfig.Tracer = &queryTracer{slowThreshold: cfg.SlowQueryThreshold}
+
 	// Create a connection pool
-	pool, err = pgxpool.New(context.Background(), dbURL)
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -27,20 +26,8 @@ func Init() (*pgxpool.Pool, error) {
 	return pool, nil
 }
 
-func GetDBUrl() (string, error) {
-	if err := godotenv.Load(); err != nil {
-		return "", err
-	}
-
-	return os.Getenv("DB_URL"), nil
-}
-
 func Close(pool *pgxpool.Pool) {
 	if pool != nil {
 		pool.Close()
 	}
 }
-
-func GetPool() *pgxpool.Pool {
-	return pool
-}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Health checks the database the server was started with, through the
+// connections it already has open.
+type Health interface {
+	Ping(ctx context.Context) error
+	// GetSchemaStatus reads the applied migration from the table
+	// golang-migrate keeps instead of opening a new migrator, so it is cheap
+	// enough for every readiness probe.
+	GetSchemaStatus(ctx context.Context) (*MigrationStatus, error)
+}
+
+const schemaStatusQuery = `SELECT version, dirty FROM schema_migrations LIMIT 1`
+
+type pgHealth struct {
+	pool *pgxpool.Pool
+}
+
+func NewPgHealth(pool *pgxpool.Pool) Health {
+	return &pgHealth{pool: pool}
+}
+
+func (h *pgHealth) Ping(ctx context.Context) error {
+	return h.pool.Ping(ctx)
+}
+
+func (h *pgHealth) GetSchemaStatus(ctx context.Context) (*MigrationStatus, error) {
+	var version int64
+	var dirty bool
+
+	err := h.pool.QueryRow(ctx, schemaStatusQuery).Scan(&version, &dirty)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	return schemaStatus("postgres", version, dirty)
+}
+
+type sqliteHealth struct {
+	db *sql.DB
+}
+
+func NewSqliteHealth(db *sql.DB) Health {
+	return &sqliteHealth{db: db}
+}
+
+func (h *sqliteHealth) Ping(ctx context.Context) error {
+	return h.db.PingContext(ctx)
+}
+
+func (h *sqliteHealth) GetSchemaStatus(ctx context.Context) (*MigrationStatus, error) {
+	var version int64
+	var dirty bool
+
+	err := h.db.QueryRowContext(ctx, schemaStatusQuery).Scan(&version, &dirty)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	return schemaStatus("sqlite", version, dirty)
+}
+
+func schemaStatus(driver string, version int64, dirty bool) (*MigrationStatus, error) {
+	status := MigrationStatus{Dirty: dirty}
+
+	// golang-migrate stores -1 after forcing the first migration off
+	if version > 0 {
+		status.Version = uint(version)
+	}
+
+	latest, err := LatestMigrationVersion(driver)
+	if err != nil {
+		return nil, err
+	}
+	status.Latest = latest
+
+	return &status, nil
+}
@@ -0,0 +1,213 @@
+package db
+
+import (
+	"calometer/internal/config"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migrations are compiled into every binary so they run the same wherever
+// it is started from.
+//
+//go:embed migrations/*.sql
+var postgresMigrations embed.FS
+
+//go:embed sqlite_migrations/*.sql
+var sqliteMigrations embed.FS
+
+// MigrationStatus is where the schema stands compared to the migrations
+// this binary was built with.
+type MigrationStatus struct {
+	// Version is 0 before the first migration ran.
+	Version uint
+	// Dirty is set when the migration at Version failed part way.
+	Dirty  bool
+	Latest uint
+}
+
+func (s MigrationStatus) IsCurrent() bool {
+	return !s.Dirty && s.Version == s.Latest
+}
+
+// GetMigrationUrl returns the URL golang-migrate connects to.
+func GetMigrationUrl(cfg config.DatabaseConfig) string {
+	if cfg.Driver == "sqlite" {
+		return GetSQLiteUrl(cfg.SQLitePath)
+	}
+
+	return cfg.URL
+}
+
+func migrationSource(driver string) (source.Driver, error) {
+	switch driver {
+	case "postgres":
+		return iofs.New(postgresMigrations, "migrations")
+	case "sqlite":
+		return iofs.New(sqliteMigrations, "sqlite_migrations")
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", driver)
+	}
+}
+
+// NewMigrate returns a migrator running the embedded migrations for driver
+// against dbURL. Callers close it once done.
+func NewMigrate(driver string, dbURL string) (*migrate.Migrate, error) {
+	src, err := migrationSource(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.NewWithSourceInstance("iofs", src, dbURL)
+}
+
+// GetMigrationStatus reads the applied version through m, so no extra
+// connection is opened.
+func GetMigrationStatus(m *migrate.Migrate, driver string) (*MigrationStatus, error) {
+	var status MigrationStatus
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, err
+	}
+	status.Version = version
+	status.Dirty = dirty
+
+	status.Latest, err = LatestMigrationVersion(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// LatestMigrationVersion is the last migration embedded for driver.
+func LatestMigrationVersion(driver string) (uint, error) {
+	src, err := migrationSource(driver)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		version = next
+	}
+}
+
+// PreviousMigrationVersion is the migration before version, -1 when version
+// is the first one. Forcing it marks version as not applied.
+func PreviousMigrationVersion(driver string, version uint) (int, error) {
+	src, err := migrationSource(driver)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	prev, err := src.Prev(version)
+	if errors.Is(err, fs.ErrNotExist) {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return int(prev), nil
+}
+
+// CheckSchema makes sure the database is at the latest embedded migration
+// before the server starts. With MigrateOnStart pending migrations are applied
+// first, on Postgres while holding an advisory lock so replicas starting
+// together wait for the first one instead of racing it. A database ahead of
+// this binary is left alone, it happens during rolling deploys. pool is the
+// server's own, SQLite has none and passes nil.
+func CheckSchema(ctx context.Context, cfg config.DatabaseConfig, pool *pgxpool.Pool) (*MigrationStatus, error) {
+	m, err := NewMigrate(cfg.Driver, GetMigrationUrl(cfg))
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	if cfg.MigrateOnStart {
+		if cfg.Driver == "postgres" {
+			unlock, err := lockMigrations(ctx, pool)
+			if err != nil {
+				return nil, err
+			}
+			defer unlock()
+		}
+
+		if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return nil, fmt.Errorf("failed to apply migrations: %w", err)
+		}
+	}
+
+	status, err := GetMigrationStatus(m, cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case status.Dirty:
+		return status, fmt.Errorf(
+			"migration %d failed part way and left the database dirty, fix it and run the migrate force command",
+			status.Version,
+		)
+	case status.Version < status.Latest:
+		return status, fmt.Errorf(
+			"database schema is at version %d but this build needs %d, run the migrate up command or set MIGRATE_ON_START=true",
+			status.Version,
+			status.Latest,
+		)
+	}
+
+	return status, nil
+}
+
+// lockMigrations holds a session advisory lock on a pooled connection until
+// the returned func releases it.
+func lockMigrations(ctx context.Context, pool *pgxpool.Pool) (func(), error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	qStr := `SELECT pg_advisory_lock(hashtext('calometer_migrations'))`
+
+	if _, err := conn.Exec(ctx, qStr); err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	return func() {
+		qStr := `SELECT pg_advisory_unlock(hashtext('calometer_migrations'))`
+
+		// Dropping the connection ends the session and its lock if the
+		// unlock fails
+		if _, err := conn.Exec(context.Background(), qStr); err != nil {
+			conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}, nil
+}
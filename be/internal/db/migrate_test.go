@@ -0,0 +1,92 @@
+package db
+
+import (
+	"calometer/internal/config"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckSchema(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.DatabaseConfig{
+		Driver:     "sqlite",
+		SQLitePath: filepath.Join(t.TempDir(), "calometer.db"),
+	}
+
+	latest, err := LatestMigrationVersion(cfg.Driver)
+	if err != nil {
+		t.Fatalf("failed to read latest migration: %v", err)
+	}
+
+	expectErr := func(cfg config.DatabaseConfig, version uint, want string) {
+		t.Helper()
+
+		status, err := CheckSchema(ctx, cfg, nil)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("err = %v, want one containing %q", err, want)
+		}
+
+		if status != nil && status.Version != version {
+			t.Fatalf("version = %d, want %d", status.Version, version)
+		}
+	}
+
+	// A new database refuses to start until it is migrated
+	expectErr(cfg, 0, "run the migrate up command")
+
+	migrated := cfg
+	migrated.MigrateOnStart = true
+
+	status, err := CheckSchema(ctx, migrated, nil)
+	if err != nil {
+		t.Fatalf("failed to migrate on start: %v", err)
+	}
+
+	if !status.IsCurrent() || status.Version != latest {
+		t.Fatalf("status = %+v, want version %d", status, latest)
+	}
+
+	if _, err := CheckSchema(ctx, cfg, nil); err != nil {
+		t.Fatalf("current schema failed the check: %v", err)
+	}
+
+	// One migration behind, like after rolling back a deploy
+	m, err := NewMigrate(cfg.Driver, GetMigrationUrl(cfg))
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	if err := m.Steps(-1); err != nil {
+		t.Fatalf("failed to roll back a migration: %v", err)
+	}
+	m.Close()
+
+	previous, err := PreviousMigrationVersion(cfg.Driver, latest)
+	if err != nil {
+		t.Fatalf("failed to find the previous migration: %v", err)
+	}
+
+	expectErr(cfg, uint(previous), "run the migrate up command")
+
+	if _, err := CheckSchema(ctx, migrated, nil); err != nil {
+		t.Fatalf("failed to catch up on start: %v", err)
+	}
+
+	// A migration that failed part way needs an operator, migrating on start
+	// doesn't retry it
+	conn, err := sql.Open("sqlite", sqliteDSN(cfg.SQLitePath))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `UPDATE schema_migrations SET dirty = 1`); err != nil {
+		t.Fatalf("failed to mark the schema dirty: %v", err)
+	}
+
+	expectErr(cfg, latest, "left the database dirty")
+	expectErr(migrated, latest, "Dirty database")
+}
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS user_access_tokens;
+
+END;
\ No newline at end of file
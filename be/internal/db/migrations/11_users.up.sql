@@ -0,0 +1,21 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_access_tokens (
+  id UUID PRIMARY KEY DEFAULT UUID_GENERATE_V4(),
+  u_id UUID NOT NULL,
+  name TEXT NOT NULL,
+  token_hash TEXT NOT NULL,
+  scopes TEXT[] NOT NULL,
+  expires_at TIMESTAMP WITH TIME ZONE,
+  last_used_at TIMESTAMP WITH TIME ZONE,
+  revoked_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT unique_access_token_hash UNIQUE (token_hash)
+);
+
+-- Names only have to be unique among the tokens that are still usable
+CREATE UNIQUE INDEX IF NOT EXISTS unique_active_access_token_name
+ON user_access_tokens (u_id, name)
+WHERE revoked_at IS NULL;
+
+END;
\ No newline at end of file
@@ -0,0 +1,19 @@
+BEGIN;
+
+-- Keys are "user:<username>" or "ip:<address>"
+CREATE TABLE IF NOT EXISTS login_attempts (
+  key TEXT PRIMARY KEY,
+  failures INT NOT NULL DEFAULT 0,
+  last_failure_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  locked_until TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS login_lockouts (
+  id UUID PRIMARY KEY DEFAULT UUID_GENERATE_V4(),
+  key TEXT NOT NULL,
+  failures INT NOT NULL,
+  locked_until TIMESTAMP WITH TIME ZONE NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+END;
\ No newline at end of file
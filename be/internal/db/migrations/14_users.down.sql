@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS user_identities;
+
+END;
\ No newline at end of file
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS check_user_role;
+
+ALTER TABLE users DROP COLUMN IF EXISTS disabled_at;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+
+END;
\ No newline at end of file
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE users ADD CONSTRAINT check_user_role CHECK (role IN ('user', 'coach', 'admin'));
+
+END;
\ No newline at end of file
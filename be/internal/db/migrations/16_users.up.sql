@@ -0,0 +1,41 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_weigh_ins (
+  id UUID PRIMARY KEY DEFAULT UUID_GENERATE_V4(),
+  u_id UUID NOT NULL,
+  weighed_on DATE NOT NULL DEFAULT CURRENT_DATE,
+  weight_kg DECIMAL(5,2) NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT unique_user_weigh_in UNIQUE (u_id, weighed_on)
+);
+
+-- Start everyone's history with the weight they entered last
+INSERT INTO user_weigh_ins (u_id, weight_kg)
+SELECT u_id, weight_kg
+FROM user_body_details
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS coach_shares (
+  id UUID PRIMARY KEY DEFAULT UUID_GENERATE_V4(),
+  client_id UUID NOT NULL,
+  coach_id UUID NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  accepted_at TIMESTAMP WITH TIME ZONE,
+  revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_active_coach_share ON coach_shares (client_id, coach_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_coach_shares_coach_id ON coach_shares (coach_id);
+
+CREATE TABLE IF NOT EXISTS coach_access_log (
+  id UUID PRIMARY KEY DEFAULT UUID_GENERATE_V4(),
+  share_id UUID NOT NULL,
+  coach_id UUID NOT NULL,
+  client_id UUID NOT NULL,
+  resource TEXT NOT NULL,
+  accessed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_coach_access_log_client_id ON coach_access_log (client_id, accessed_at);
+
+END;
\ No newline at end of file
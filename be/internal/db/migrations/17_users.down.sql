@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS log_comment_reads;
+DROP TABLE IF EXISTS log_comments;
+
+END;
\ No newline at end of file
@@ -0,0 +1,28 @@
+BEGIN;
+
+-- Threads are between a client and one of their coaches, about either a
+-- single logged day or a week starting on Monday.
+CREATE TABLE IF NOT EXISTS log_comments (
+  id UUID PRIMARY KEY DEFAULT UUID_GENERATE_V4(),
+  client_id UUID NOT NULL,
+  coach_id UUID NOT NULL,
+  calorie_log_id UUID,
+  week_start DATE,
+  parent_id UUID,
+  author_id UUID NOT NULL,
+  body TEXT NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT check_comment_target CHECK ((calorie_log_id IS NULL) <> (week_start IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_log_comments_client_coach ON log_comments (client_id, coach_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_log_comments_calorie_log_id ON log_comments (calorie_log_id);
+
+CREATE TABLE IF NOT EXISTS log_comment_reads (
+  comment_id UUID NOT NULL,
+  u_id UUID NOT NULL,
+  read_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (comment_id, u_id)
+);
+
+END;
\ No newline at end of file
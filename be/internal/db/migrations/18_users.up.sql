@@ -0,0 +1,46 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_profiles (
+  id UUID PRIMARY KEY DEFAULT UUID_GENERATE_V4(),
+  u_id UUID NOT NULL,
+  name TEXT NOT NULL,
+  is_default BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT unique_profile_name UNIQUE (u_id, name)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_default_profile ON user_profiles (u_id) WHERE is_default;
+
+-- The default profile shares its user's id, so everything logged so far
+-- belongs to it once the columns are renamed.
+INSERT INTO user_profiles (id, u_id, name, is_default)
+SELECT id, id, name, TRUE
+FROM users
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE user_body_details RENAME COLUMN u_id TO p_id;
+ALTER TABLE user_weight_goal RENAME COLUMN u_id TO p_id;
+ALTER TABLE user_calorie_logs RENAME COLUMN u_id TO p_id;
+ALTER TABLE user_weigh_ins RENAME COLUMN u_id TO p_id;
+
+-- Coaches are shared a single profile, comment threads follow it
+ALTER TABLE coach_shares ADD COLUMN p_id UUID;
+UPDATE coach_shares SET p_id = client_id;
+ALTER TABLE coach_shares ALTER COLUMN p_id SET NOT NULL;
+
+DROP INDEX IF EXISTS unique_active_coach_share;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_active_coach_share ON coach_shares (p_id, coach_id) WHERE revoked_at IS NULL;
+
+ALTER TABLE coach_access_log ADD COLUMN p_id UUID;
+UPDATE coach_access_log SET p_id = client_id;
+ALTER TABLE coach_access_log ALTER COLUMN p_id SET NOT NULL;
+
+ALTER TABLE log_comments ADD COLUMN p_id UUID;
+UPDATE log_comments SET p_id = client_id;
+ALTER TABLE log_comments ALTER COLUMN p_id SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_log_comments_client_coach;
+CREATE INDEX IF NOT EXISTS idx_log_comments_profile_coach ON log_comments (p_id, coach_id, created_at);
+
+END;
\ No newline at end of file
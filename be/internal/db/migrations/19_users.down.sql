@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS challenges;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
+
+END;
\ No newline at end of file
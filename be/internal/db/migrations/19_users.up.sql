@@ -0,0 +1,36 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS groups (
+  id UUID PRIMARY KEY DEFAULT UUID_GENERATE_V4(),
+  name TEXT NOT NULL,
+  invite_code TEXT NOT NULL,
+  owner_id UUID NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT unique_invite_code UNIQUE (invite_code)
+);
+
+-- Members are profiles, so each person in a household competes on their own
+CREATE TABLE IF NOT EXISTS group_members (
+  group_id UUID NOT NULL,
+  p_id UUID NOT NULL,
+  joined_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (group_id, p_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_members_p_id ON group_members (p_id);
+
+CREATE TABLE IF NOT EXISTS challenges (
+  id UUID PRIMARY KEY DEFAULT UUID_GENERATE_V4(),
+  group_id UUID NOT NULL,
+  name TEXT NOT NULL,
+  metric TEXT NOT NULL CHECK (metric IN ('adherence', 'balance', 'weight_change')),
+  starts_on DATE NOT NULL,
+  ends_on DATE NOT NULL,
+  created_by UUID NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT check_challenge_dates CHECK (ends_on >= starts_on)
+);
+
+CREATE INDEX IF NOT EXISTS idx_challenges_group_id ON challenges (group_id);
+
+END;
\ No newline at end of file
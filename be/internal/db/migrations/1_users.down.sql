@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS users;
+
+END;
\ No newline at end of file
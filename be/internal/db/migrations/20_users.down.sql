@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS user_streaks;
+DROP TABLE IF EXISTS user_achievements;
+
+END;
\ No newline at end of file
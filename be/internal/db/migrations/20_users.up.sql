@@ -0,0 +1,21 @@
+BEGIN;
+
+-- Badges are derived from logging history and can be recomputed, earned_on
+-- is the day the history first met the rule
+CREATE TABLE IF NOT EXISTS user_achievements (
+  p_id UUID NOT NULL,
+  badge TEXT NOT NULL,
+  earned_on DATE NOT NULL,
+  awarded_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (p_id, badge)
+);
+
+CREATE TABLE IF NOT EXISTS user_streaks (
+  p_id UUID PRIMARY KEY,
+  current_streak INT NOT NULL DEFAULT 0,
+  longest_streak INT NOT NULL DEFAULT 0,
+  last_completed_on DATE,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+END;
\ No newline at end of file
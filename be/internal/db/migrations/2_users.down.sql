@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE users DROP COLUMN IF EXISTS username;
+
+-- Existing users had their email dropped, they come back with an empty one
+ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ALTER COLUMN email DROP DEFAULT;
+
+END;
\ No newline at end of file
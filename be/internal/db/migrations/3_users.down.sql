@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS user_body_details;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS unique_username;
+
+END;
\ No newline at end of file
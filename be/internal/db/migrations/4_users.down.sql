@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE user_body_details DROP COLUMN IF EXISTS bmr;
+
+END;
\ No newline at end of file
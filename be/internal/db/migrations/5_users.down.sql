@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS user_weight_goal;
+
+END;
\ No newline at end of file
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS user_calorie_logs;
+
+END;
\ No newline at end of file
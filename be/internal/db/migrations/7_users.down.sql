@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE user_calorie_logs
+ALTER COLUMN calories_burnt DROP DEFAULT;
+
+ALTER TABLE user_calorie_logs
+ALTER COLUMN calories_consumed DROP DEFAULT;
+
+END;
\ No newline at end of file
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS user_caloric_balance;
+
+ALTER TABLE user_calorie_logs DROP COLUMN IF EXISTS log_status;
+
+END;
\ No newline at end of file
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE user_caloric_balance DROP CONSTRAINT IF EXISTS unique_calorie_log_id;
+
+END;
\ No newline at end of file
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"calometer/internal/logger"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql   string
+	start time.Time
+}
+
+// queryTracer logs queries that took longer than slowThreshold and those
+// canceled because the request went away or ran out of time.
+type queryTracer struct {
+	slowThreshold time.Duration
+}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{
+		sql:   data.SQL,
+		start: time.Now(),
+	})
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	query, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+
+	duration := time.Since(query.start)
+	fields := []zap.Field{
+		zap.String("sql", compactSQL(query.sql)),
+		zap.Duration("duration", duration),
+	}
+
+	switch {
+	case errors.Is(data.Err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		logger.GetLogger().Warn("query timed out", fields...)
+	case errors.Is(data.Err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled):
+		logger.GetLogger().Info("query canceled", fields...)
+	case duration >= t.slowThreshold:
+		logger.GetLogger().Warn("slow query", fields...)
+	}
+}
+
+// compactSQL puts a query on one line for the logs.
+func compactSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"calometer/internal/config"
+	"context"
+	"database/sql"
+
+	_ "modernc.org/sqlite"
+)
+
+// InitSQLite opens the database file at cfg.SQLitePath. SQLite allows a single writer, so the pool is one connection and
+// writers queue up instead of failing with SQLITE_BUSY.
+func InitSQLite(cfg config.DatabaseConfig) (*sql.DB, error) {
+	conn, err := sql.Open("sqlite", sqliteDSN(cfg.SQLitePath))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetMaxOpenConns(1)
+
+	if err := conn.PingContext(context.Background()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// sqliteDSN is the database file with the pragmas every connection needs.
+func sqliteDSN(path string) string {
+	return path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_txlock=immediate"
+}
+
+// GetSQLiteUrl is the database URL golang-migrate expects.
+func GetSQLiteUrl(path string) string {
+	return "sqlite://" + sqliteDSN(path)
+}
+
+func CloseSQLite(conn *sql.DB) {
+	if conn != nil {
+		conn.Close()
+	}
+}
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS user_refresh_tokens (
+  id TEXT PRIMARY KEY NOT NULL,
+  u_id TEXT NOT NULL,
+  family_id TEXT NOT NULL,
+  token_hash TEXT NOT NULL,
+  expires_at TEXT NOT NULL,
+  revoked_at TEXT,
+  replaced_by TEXT,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT unique_refresh_token_hash UNIQUE (token_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_refresh_tokens_family_id ON user_refresh_tokens (family_id);
\ No newline at end of file
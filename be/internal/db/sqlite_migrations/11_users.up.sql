@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS user_access_tokens (
+  id TEXT PRIMARY KEY NOT NULL,
+  u_id TEXT NOT NULL,
+  name TEXT NOT NULL,
+  token_hash TEXT NOT NULL,
+  scopes TEXT NOT NULL, -- JSON array of scope names
+  expires_at TEXT,
+  last_used_at TEXT,
+  revoked_at TEXT,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT unique_access_token_hash UNIQUE (token_hash)
+);
+
+-- Names only have to be unique among the tokens that are still usable
+CREATE UNIQUE INDEX IF NOT EXISTS unique_active_access_token_name
+ON user_access_tokens (u_id, name)
+WHERE revoked_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS user_totp (
+  id TEXT PRIMARY KEY NOT NULL,
+  u_id TEXT UNIQUE NOT NULL,
+  secret TEXT NOT NULL,
+  enabled INTEGER NOT NULL DEFAULT 0,
+  last_used_step INTEGER NOT NULL DEFAULT 0, -- Rejects replays of an already used code
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  enabled_at TEXT
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+  id TEXT PRIMARY KEY NOT NULL,
+  u_id TEXT NOT NULL,
+  code_hash TEXT NOT NULL,
+  used_at TEXT,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_recovery_codes_u_id ON user_recovery_codes (u_id);
\ No newline at end of file
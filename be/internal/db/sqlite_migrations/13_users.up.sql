@@ -0,0 +1,15 @@
+-- Keys are "user:<username>" or "ip:<address>"
+CREATE TABLE IF NOT EXISTS login_attempts (
+  key TEXT PRIMARY KEY NOT NULL,
+  failures INTEGER NOT NULL DEFAULT 0,
+  last_failure_at TEXT NOT NULL,
+  locked_until TEXT
+);
+
+CREATE TABLE IF NOT EXISTS login_lockouts (
+  id TEXT PRIMARY KEY NOT NULL,
+  key TEXT NOT NULL,
+  failures INTEGER NOT NULL,
+  locked_until TEXT NOT NULL,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP
+);
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+  id TEXT PRIMARY KEY NOT NULL,
+  u_id TEXT NOT NULL,
+  provider TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  email TEXT,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT unique_provider_subject UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_u_id ON user_identities (u_id);
\ No newline at end of file
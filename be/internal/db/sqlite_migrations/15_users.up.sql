@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user'
+  CONSTRAINT check_user_role CHECK (role IN ('user', 'coach', 'admin'));
+ALTER TABLE users ADD COLUMN disabled_at TEXT;
\ No newline at end of file
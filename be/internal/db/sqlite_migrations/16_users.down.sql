@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS coach_access_log;
+DROP TABLE IF EXISTS coach_shares;
+DROP TABLE IF EXISTS user_weigh_ins;
\ No newline at end of file
@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS user_weigh_ins (
+  id TEXT PRIMARY KEY NOT NULL,
+  u_id TEXT NOT NULL,
+  weighed_on TEXT NOT NULL DEFAULT CURRENT_DATE,
+  weight_kg REAL NOT NULL,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT unique_user_weigh_in UNIQUE (u_id, weighed_on)
+);
+
+-- Start everyone's history with the weight they entered last, reusing the
+-- body details id as there is no uuid generator
+INSERT INTO user_weigh_ins (id, u_id, weight_kg)
+SELECT id, u_id, weight_kg
+FROM user_body_details
+WHERE true
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS coach_shares (
+  id TEXT PRIMARY KEY NOT NULL,
+  client_id TEXT NOT NULL,
+  coach_id TEXT NOT NULL,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  accepted_at TEXT,
+  revoked_at TEXT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_active_coach_share ON coach_shares (client_id, coach_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_coach_shares_coach_id ON coach_shares (coach_id);
+
+CREATE TABLE IF NOT EXISTS coach_access_log (
+  id TEXT PRIMARY KEY NOT NULL,
+  share_id TEXT NOT NULL,
+  coach_id TEXT NOT NULL,
+  client_id TEXT NOT NULL,
+  resource TEXT NOT NULL,
+  accessed_at TEXT DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_coach_access_log_client_id ON coach_access_log (client_id, accessed_at);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS log_comment_reads;
+DROP TABLE IF EXISTS log_comments;
\ No newline at end of file
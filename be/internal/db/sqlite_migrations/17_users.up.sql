@@ -0,0 +1,24 @@
+-- Threads are between a client and one of their coaches, about either a
+-- single logged day or a week starting on Monday.
+CREATE TABLE IF NOT EXISTS log_comments (
+  id TEXT PRIMARY KEY NOT NULL,
+  client_id TEXT NOT NULL,
+  coach_id TEXT NOT NULL,
+  calorie_log_id TEXT,
+  week_start TEXT,
+  parent_id TEXT,
+  author_id TEXT NOT NULL,
+  body TEXT NOT NULL,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT check_comment_target CHECK ((calorie_log_id IS NULL) <> (week_start IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_log_comments_client_coach ON log_comments (client_id, coach_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_log_comments_calorie_log_id ON log_comments (calorie_log_id);
+
+CREATE TABLE IF NOT EXISTS log_comment_reads (
+  comment_id TEXT NOT NULL,
+  u_id TEXT NOT NULL,
+  read_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (comment_id, u_id)
+);
\ No newline at end of file
@@ -0,0 +1,19 @@
+-- Only default profiles map back onto users, data logged under any other
+-- profile stays in the tables but no longer belongs to anyone.
+DROP INDEX IF EXISTS idx_log_comments_profile_coach;
+CREATE INDEX IF NOT EXISTS idx_log_comments_client_coach ON log_comments (client_id, coach_id, created_at);
+ALTER TABLE log_comments DROP COLUMN p_id;
+
+ALTER TABLE coach_access_log DROP COLUMN p_id;
+
+DROP INDEX IF EXISTS unique_active_coach_share;
+ALTER TABLE coach_shares DROP COLUMN p_id;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_active_coach_share ON coach_shares (client_id, coach_id) WHERE revoked_at IS NULL;
+
+ALTER TABLE user_weigh_ins RENAME COLUMN p_id TO u_id;
+ALTER TABLE user_calorie_logs RENAME COLUMN p_id TO u_id;
+ALTER TABLE user_weight_goal RENAME COLUMN p_id TO u_id;
+ALTER TABLE user_body_details RENAME COLUMN p_id TO u_id;
+
+DROP INDEX IF EXISTS unique_default_profile;
+DROP TABLE IF EXISTS user_profiles;
\ No newline at end of file
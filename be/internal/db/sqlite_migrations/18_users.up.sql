@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS user_profiles (
+  id TEXT PRIMARY KEY NOT NULL,
+  u_id TEXT NOT NULL,
+  name TEXT NOT NULL,
+  is_default INTEGER NOT NULL DEFAULT 0,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  updated_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT unique_profile_name UNIQUE (u_id, name)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_default_profile ON user_profiles (u_id) WHERE is_default;
+
+-- The default profile shares its user's id, so everything logged so far
+-- belongs to it once the columns are renamed.
+INSERT INTO user_profiles (id, u_id, name, is_default)
+SELECT id, id, name, 1
+FROM users
+WHERE true
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE user_body_details RENAME COLUMN u_id TO p_id;
+ALTER TABLE user_weight_goal RENAME COLUMN u_id TO p_id;
+ALTER TABLE user_calorie_logs RENAME COLUMN u_id TO p_id;
+ALTER TABLE user_weigh_ins RENAME COLUMN u_id TO p_id;
+
+-- Coaches are shared a single profile, comment threads follow it. SQLite
+-- can't make an added column NOT NULL, every insert sets p_id.
+ALTER TABLE coach_shares ADD COLUMN p_id TEXT;
+UPDATE coach_shares SET p_id = client_id;
+
+DROP INDEX IF EXISTS unique_active_coach_share;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_active_coach_share ON coach_shares (p_id, coach_id) WHERE revoked_at IS NULL;
+
+ALTER TABLE coach_access_log ADD COLUMN p_id TEXT;
+UPDATE coach_access_log SET p_id = client_id;
+
+ALTER TABLE log_comments ADD COLUMN p_id TEXT;
+UPDATE log_comments SET p_id = client_id;
+
+DROP INDEX IF EXISTS idx_log_comments_client_coach;
+CREATE INDEX IF NOT EXISTS idx_log_comments_profile_coach ON log_comments (p_id, coach_id, created_at);
\ No newline at end of file
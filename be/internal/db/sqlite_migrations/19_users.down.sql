@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS challenges;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
\ No newline at end of file
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS groups (
+  id TEXT PRIMARY KEY NOT NULL,
+  name TEXT NOT NULL,
+  invite_code TEXT NOT NULL,
+  owner_id TEXT NOT NULL,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT unique_invite_code UNIQUE (invite_code)
+);
+
+-- Members are profiles, so each person in a household competes on their own
+CREATE TABLE IF NOT EXISTS group_members (
+  group_id TEXT NOT NULL,
+  p_id TEXT NOT NULL,
+  joined_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (group_id, p_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_members_p_id ON group_members (p_id);
+
+CREATE TABLE IF NOT EXISTS challenges (
+  id TEXT PRIMARY KEY NOT NULL,
+  group_id TEXT NOT NULL,
+  name TEXT NOT NULL,
+  metric TEXT NOT NULL CHECK (metric IN ('adherence', 'balance', 'weight_change')),
+  starts_on TEXT NOT NULL,
+  ends_on TEXT NOT NULL,
+  created_by TEXT NOT NULL,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT check_challenge_dates CHECK (ends_on >= starts_on)
+);
+
+CREATE INDEX IF NOT EXISTS idx_challenges_group_id ON challenges (group_id);
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- SQLite has no uuid type or generator, ids are uuid strings the
+-- application creates. Timestamps are UTC "YYYY-MM-DD HH:MM:SS" text and
+-- dates "YYYY-MM-DD" text.
+CREATE TABLE IF NOT EXISTS users (
+  id TEXT PRIMARY KEY NOT NULL,
+  name TEXT NOT NULL,
+  email TEXT NOT NULL,
+  password_hash TEXT NOT NULL,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  updated_at TEXT DEFAULT CURRENT_TIMESTAMP
+);
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- Badges are derived from logging history and can be recomputed, earned_on
+-- is the day the history first met the rule
+CREATE TABLE IF NOT EXISTS user_achievements (
+  p_id TEXT NOT NULL,
+  badge TEXT NOT NULL,
+  earned_on TEXT NOT NULL,
+  awarded_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (p_id, badge)
+);
+
+CREATE TABLE IF NOT EXISTS user_streaks (
+  p_id TEXT PRIMARY KEY NOT NULL,
+  current_streak INTEGER NOT NULL DEFAULT 0,
+  longest_streak INTEGER NOT NULL DEFAULT 0,
+  last_completed_on TEXT,
+  updated_at TEXT DEFAULT CURRENT_TIMESTAMP
+);
\ No newline at end of file
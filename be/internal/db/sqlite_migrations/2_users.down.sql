@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN username;
+
+-- Existing users had their email dropped, they come back with an empty one
+ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN email;
+
+ALTER TABLE users ADD COLUMN username TEXT NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_body_details;
+
+DROP INDEX IF EXISTS unique_username;
\ No newline at end of file
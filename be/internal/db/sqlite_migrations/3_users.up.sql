@@ -0,0 +1,10 @@
+CREATE UNIQUE INDEX IF NOT EXISTS unique_username ON users (username);
+
+CREATE TABLE IF NOT EXISTS user_body_details (
+  id TEXT PRIMARY KEY NOT NULL,
+  u_id TEXT UNIQUE NOT NULL,
+  age INTEGER NOT NULL,
+  height_cm INTEGER NOT NULL,
+  weight_kg REAL NOT NULL,
+  gender TEXT NOT NULL CHECK (gender IN ('M', 'F'))
+);
\ No newline at end of file
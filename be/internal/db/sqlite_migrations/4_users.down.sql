@@ -0,0 +1 @@
+ALTER TABLE user_body_details DROP COLUMN bmr;
\ No newline at end of file
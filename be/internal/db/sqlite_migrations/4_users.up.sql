@@ -0,0 +1 @@
+ALTER TABLE user_body_details ADD COLUMN bmr REAL NOT NULL DEFAULT 0;
\ No newline at end of file
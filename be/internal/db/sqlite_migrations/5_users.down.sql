@@ -0,0 +1 @@
+DROP TABLE IF EXISTS user_weight_goal;
\ No newline at end of file
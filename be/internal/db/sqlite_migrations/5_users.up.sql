@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS user_weight_goal (
+  id TEXT PRIMARY KEY NOT NULL,
+  u_id TEXT UNIQUE NOT NULL,
+  goal TEXT NOT NULL CHECK (goal IN ('G', 'L')) -- G == Gain, L == Lose
+);
\ No newline at end of file
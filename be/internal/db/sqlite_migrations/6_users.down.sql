@@ -0,0 +1 @@
+DROP TABLE IF EXISTS user_calorie_logs;
\ No newline at end of file
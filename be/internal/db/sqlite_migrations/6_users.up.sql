@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_calorie_logs (
+  id TEXT PRIMARY KEY NOT NULL,
+  u_id TEXT NOT NULL,
+  log_date TEXT NOT NULL DEFAULT CURRENT_DATE,
+  calories_burnt REAL,
+  calories_consumed REAL,
+  tdee REAL,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  updated_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT unique_user_log UNIQUE (u_id, log_date)
+);
\ No newline at end of file
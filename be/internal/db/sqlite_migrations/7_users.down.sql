@@ -0,0 +1,20 @@
+-- SQLite can't change a column default in place, so the table is rebuilt
+CREATE TABLE user_calorie_logs_old (
+  id TEXT PRIMARY KEY NOT NULL,
+  u_id TEXT NOT NULL,
+  log_date TEXT NOT NULL DEFAULT CURRENT_DATE,
+  calories_burnt REAL,
+  calories_consumed REAL,
+  tdee REAL,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  updated_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT unique_user_log UNIQUE (u_id, log_date)
+);
+
+INSERT INTO user_calorie_logs_old
+SELECT id, u_id, log_date, calories_burnt, calories_consumed, tdee, created_at, updated_at
+FROM user_calorie_logs;
+
+DROP TABLE user_calorie_logs;
+
+ALTER TABLE user_calorie_logs_old RENAME TO user_calorie_logs;
\ No newline at end of file
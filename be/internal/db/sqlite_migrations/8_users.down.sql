@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_caloric_balance;
+
+ALTER TABLE user_calorie_logs DROP COLUMN log_status;
\ No newline at end of file
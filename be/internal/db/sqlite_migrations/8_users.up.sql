@@ -0,0 +1,8 @@
+ALTER TABLE user_calorie_logs
+ADD COLUMN log_status TEXT DEFAULT 'P' CHECK (log_status IN ('P', 'D')); -- P = pending, D = Done.
+
+CREATE TABLE IF NOT EXISTS user_caloric_balance (
+  id TEXT PRIMARY KEY NOT NULL,
+  calorie_log_id TEXT NOT NULL,
+  caloric_balance REAL NOT NULL
+);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS unique_calorie_log_id;
\ No newline at end of file
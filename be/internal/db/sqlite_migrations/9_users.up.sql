@@ -0,0 +1 @@
+CREATE UNIQUE INDEX IF NOT EXISTS unique_calorie_log_id ON user_caloric_balance (calorie_log_id);
\ No newline at end of file
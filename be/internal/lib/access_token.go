@@ -0,0 +1,194 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// AccessTokenPrefix makes personal access tokens recognizable, both for the
+// auth middleware and for secret scanners.
+const AccessTokenPrefix = "cm_pat_"
+
+const (
+	ScopeLogsRead         = "logs:read"
+	ScopeLogsWrite        = "logs:write"
+	ScopeBodyDetailsRead  = "body_details:read"
+	ScopeBodyDetailsWrite = "body_details:write"
+	ScopeBalanceRead      = "balance:read"
+)
+
+var AccessTokenScopes = []string{
+	ScopeLogsRead,
+	ScopeLogsWrite,
+	ScopeBodyDetailsRead,
+	ScopeBodyDetailsWrite,
+	ScopeBalanceRead,
+}
+
+var ErrAccessTokenInvalid = errors.New("access token is invalid, expired or revoked")
+
+type UserAccessToken struct {
+	Id         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type AccessTokenAuth struct {
+	UserId uuid.UUID
+	Role   string
+	Scopes []string
+}
+
+func IsValidScope(scope string) bool {
+	return slices.Contains(AccessTokenScopes, scope)
+}
+
+func (s *PgStore) CreateAccessToken(
+	ctx context.Context,
+	userId uuid.UUID,
+	name string,
+	scopes []string,
+	expiresAt *time.Time,
+) (*uuid.UUID, string, error) {
+	secret, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	token := AccessTokenPrefix + secret
+
+	var tokenId uuid.UUID
+
+	qStr := `
+		INSERT INTO user_access_tokens (
+			u_id,
+			name,
+			token_hash,
+			scopes,
+			expires_at
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			$4,
+			$5
+		) RETURNING id
+	`
+
+	if err := s.pool.QueryRow(
+		ctx,
+		qStr,
+		userId,
+		name,
+		HashOpaqueToken(token),
+		scopes,
+		expiresAt,
+	).Scan(&tokenId); err != nil {
+		return nil, "", err
+	}
+
+	return &tokenId, token, nil
+}
+
+func (s *PgStore) GetAccessTokens(ctx context.Context, userId uuid.UUID) ([]UserAccessToken, error) {
+	tokens := []UserAccessToken{}
+
+	qStr := `
+		SELECT
+			id,
+			name,
+			scopes,
+			expires_at,
+			last_used_at,
+			created_at
+		FROM user_access_tokens
+		WHERE u_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at
+	`
+
+	rows, err := s.pool.Query(ctx, qStr, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var token UserAccessToken
+
+		if err := rows.Scan(
+			&token.Id,
+			&token.Name,
+			&token.Scopes,
+			&token.ExpiresAt,
+			&token.LastUsedAt,
+			&token.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *PgStore) RevokeAccessToken(ctx context.Context, userId uuid.UUID, tokenId uuid.UUID) (*bool, error) {
+	qStr := `
+		UPDATE user_access_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND u_id = $2 AND revoked_at IS NULL
+	`
+
+	tag, err := s.pool.Exec(ctx, qStr, tokenId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked := tag.RowsAffected() > 0
+
+	return &revoked, nil
+}
+
+// AuthenticateAccessToken resolves a personal access token to its owner, their
+// role and granted scopes, recording when it was last used. Tokens of disabled
+// users are invalid.
+func (s *PgStore) AuthenticateAccessToken(ctx context.Context, token string) (*AccessTokenAuth, error) {
+	var auth AccessTokenAuth
+
+	qStr := `
+		UPDATE user_access_tokens t
+		SET last_used_at = CURRENT_TIMESTAMP
+		FROM users u
+		WHERE t.token_hash = $1
+			AND t.revoked_at IS NULL
+			AND (t.expires_at IS NULL OR t.expires_at > CURRENT_TIMESTAMP)
+			AND u.id = t.u_id
+			AND u.disabled_at IS NULL
+		RETURNING t.u_id, u.role, t.scopes
+	`
+
+	if err := s.pool.QueryRow(
+		ctx,
+		qStr,
+		HashOpaqueToken(token),
+	).Scan(&auth.UserId, &auth.Role, &auth.Scopes); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrAccessTokenInvalid
+		}
+
+		return nil, err
+	}
+
+	return &auth, nil
+}
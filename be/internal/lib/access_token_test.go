@@ -0,0 +1,103 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAuthenticateAccessToken(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSqliteStore(t)
+	userId := createTestUser(t, store, "alice")
+
+	_, token, err := store.CreateAccessToken(ctx, userId, "script", []string{ScopeLogsRead}, nil)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	auth, err := store.AuthenticateAccessToken(ctx, token)
+	if err != nil {
+		t.Fatalf("failed to authenticate token: %v", err)
+	}
+	if auth.UserId != userId {
+		t.Fatalf("token authenticated user %s, want %s", auth.UserId, userId)
+	}
+	if !slices.Equal(auth.Scopes, []string{ScopeLogsRead}) {
+		t.Fatalf("token has scopes %v, want %v", auth.Scopes, []string{ScopeLogsRead})
+	}
+
+	if _, err := store.AuthenticateAccessToken(ctx, AccessTokenPrefix+"unknown"); !errors.Is(err, ErrAccessTokenInvalid) {
+		t.Fatalf("authenticating an unknown token returned %v, want %v", err, ErrAccessTokenInvalid)
+	}
+}
+
+func TestAuthenticateExpiredAccessToken(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSqliteStore(t)
+	userId := createTestUser(t, store, "alice")
+
+	expiresAt := time.Now().Add(-time.Minute)
+	_, token, err := store.CreateAccessToken(ctx, userId, "script", []string{ScopeLogsRead}, &expiresAt)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	if _, err := store.AuthenticateAccessToken(ctx, token); !errors.Is(err, ErrAccessTokenInvalid) {
+		t.Fatalf("authenticating an expired token returned %v, want %v", err, ErrAccessTokenInvalid)
+	}
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSqliteStore(t)
+	userId := createTestUser(t, store, "alice")
+	otherId := createTestUser(t, store, "bob")
+
+	tokenId, token, err := store.CreateAccessToken(ctx, userId, "script", []string{ScopeLogsRead}, nil)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	// Only the owner may revoke a token
+	revoked, err := store.RevokeAccessToken(ctx, otherId, *tokenId)
+	if err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	if *revoked {
+		t.Fatal("another user revoked the token")
+	}
+
+	revoked, err = store.RevokeAccessToken(ctx, userId, *tokenId)
+	if err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	if !*revoked {
+		t.Fatal("owner could not revoke the token")
+	}
+
+	if _, err := store.AuthenticateAccessToken(ctx, token); !errors.Is(err, ErrAccessTokenInvalid) {
+		t.Fatalf("authenticating a revoked token returned %v, want %v", err, ErrAccessTokenInvalid)
+	}
+}
+
+func TestAuthenticateAccessTokenOfDisabledUser(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSqliteStore(t)
+	userId := createTestUser(t, store, "alice")
+
+	_, token, err := store.CreateAccessToken(ctx, userId, "script", []string{ScopeLogsRead}, nil)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	if _, err := store.SetUserDisabled(ctx, userId, true); err != nil {
+		t.Fatalf("failed to disable user: %v", err)
+	}
+
+	if _, err := store.AuthenticateAccessToken(ctx, token); !errors.Is(err, ErrAccessTokenInvalid) {
+		t.Fatalf("authenticating a disabled user's token returned %v, want %v", err, ErrAccessTokenInvalid)
+	}
+}
@@ -0,0 +1,507 @@
+package lib
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Events that can earn badges.
+const (
+	EventDayCompleted = "day_completed"
+	// EventDayReopened earns nothing but can break a streak.
+	EventDayReopened = "day_reopened"
+	EventWeighIn     = "weigh_in"
+)
+
+// ProgressHistory is everything badges are awarded from, so any rule can be
+// evaluated again later from what is stored.
+type ProgressHistory struct {
+	// CompletedDays are the days marked as done, oldest first
+	CompletedDays []CompletedDay
+	// WeighInDays are oldest first
+	WeighInDays []time.Time
+	// Goal is the profile's current weight goal, empty when not set
+	Goal string
+}
+
+type CompletedDay struct {
+	LogDate        time.Time
+	CaloricBalance float64
+}
+
+// AchievementRule awards Badge when EarnedOn finds the day the history first
+// met the rule. Rules are checked on the events they list, and all of them
+// when achievements are recomputed.
+type AchievementRule struct {
+	Badge       string
+	Name        string
+	Description string
+	Events      []string
+	EarnedOn    func(history *ProgressHistory) *time.Time
+}
+
+type Achievement struct {
+	Badge       string     `json:"badge"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	EarnedOn    *string    `json:"earned_on"`
+	AwardedAt   *time.Time `json:"awarded_at,omitempty"`
+}
+
+var AchievementRules = []AchievementRule{
+	{
+		Badge:       "first_day",
+		Name:        "First Day",
+		Description: "Completed your first day of logging.",
+		Events:      []string{EventDayCompleted},
+		EarnedOn:    nthCompletedDay(1, nil),
+	},
+	{
+		Badge:       "days_50",
+		Name:        "Half Century",
+		Description: "Completed 50 days of logging.",
+		Events:      []string{EventDayCompleted},
+		EarnedOn:    nthCompletedDay(50, nil),
+	},
+	{
+		Badge:       "first_goal_day",
+		Name:        "On Target",
+		Description: "Finished a day in line with your weight goal.",
+		Events:      []string{EventDayCompleted},
+		EarnedOn:    nthCompletedDay(1, isGoalDay),
+	},
+	{
+		Badge:       "goal_days_30",
+		Name:        "Sharpshooter",
+		Description: "Finished 30 days in line with your weight goal.",
+		Events:      []string{EventDayCompleted},
+		EarnedOn:    nthCompletedDay(30, isGoalDay),
+	},
+	{
+		Badge:       "first_weigh_in",
+		Name:        "Stepping On",
+		Description: "Recorded your first weigh-in.",
+		Events:      []string{EventWeighIn},
+		EarnedOn: func(history *ProgressHistory) *time.Time {
+			if len(history.WeighInDays) == 0 {
+				return nil
+			}
+
+			return &history.WeighInDays[0]
+		},
+	},
+	{
+		Badge:       "streak_7",
+		Name:        "One Week Streak",
+		Description: "Completed 7 days in a row.",
+		Events:      []string{EventDayCompleted},
+		EarnedOn:    streakOf(7),
+	},
+	{
+		Badge:       "streak_30",
+		Name:        "One Month Streak",
+		Description: "Completed 30 days in a row.",
+		Events:      []string{EventDayCompleted},
+		EarnedOn:    streakOf(30),
+	},
+	{
+		Badge:       "streak_100",
+		Name:        "Hundred Day Streak",
+		Description: "Completed 100 days in a row.",
+		Events:      []string{EventDayCompleted},
+		EarnedOn:    streakOf(100),
+	},
+}
+
+// isGoalDay is a deficit for losing weight and a surplus for gaining it.
+func isGoalDay(history *ProgressHistory, day CompletedDay) bool {
+	switch history.Goal {
+	case "L":
+		return day.CaloricBalance > 0
+	case "G":
+		return day.CaloricBalance < 0
+	}
+
+	return false
+}
+
+func nthCompletedDay(n int, matches func(*ProgressHistory, CompletedDay) bool) func(*ProgressHistory) *time.Time {
+	return func(history *ProgressHistory) *time.Time {
+		count := 0
+		for i, day := range history.CompletedDays {
+			if matches != nil && !matches(history, day) {
+				continue
+			}
+
+			count++
+			if count == n {
+				return &history.CompletedDays[i].LogDate
+			}
+		}
+
+		return nil
+	}
+}
+
+func streakOf(n int) func(*ProgressHistory) *time.Time {
+	return func(history *ProgressHistory) *time.Time {
+		run := 0
+		for i, day := range history.CompletedDays {
+			if i > 0 && isNextDay(history.CompletedDays[i-1].LogDate, day.LogDate) {
+				run++
+			} else {
+				run = 1
+			}
+
+			if run == n {
+				return &history.CompletedDays[i].LogDate
+			}
+		}
+
+		return nil
+	}
+}
+
+func completedDates(history *ProgressHistory) []time.Time {
+	dates := make([]time.Time, 0, len(history.CompletedDays))
+	for _, day := range history.CompletedDays {
+		dates = append(dates, day.LogDate)
+	}
+
+	return dates
+}
+
+func loadProgressHistory(ctx context.Context, tx pgx.Tx, profileId uuid.UUID) (*ProgressHistory, error) {
+	history := &ProgressHistory{}
+
+	qStr := `
+		SELECT l.log_date, COALESCE(b.caloric_balance, 0)::float8
+		FROM user_calorie_logs l
+		LEFT JOIN user_caloric_balance b ON b.calorie_log_id = l.id
+		WHERE l.p_id = $1 AND l.log_status = 'D'
+		ORDER BY l.log_date
+	`
+
+	rows, err := tx.Query(ctx, qStr, profileId)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var day CompletedDay
+		if err := rows.Scan(&day.LogDate, &day.CaloricBalance); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		history.CompletedDays = append(history.CompletedDays, day)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	qStr = `
+		SELECT weighed_on
+		FROM user_weigh_ins
+		WHERE p_id = $1
+		ORDER BY weighed_on
+	`
+
+	rows, err = tx.Query(ctx, qStr, profileId)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var weighedOn time.Time
+		if err := rows.Scan(&weighedOn); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		history.WeighInDays = append(history.WeighInDays, weighedOn)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	qStr = `
+		SELECT goal
+		FROM user_weight_goal
+		WHERE p_id = $1
+	`
+
+	if err := tx.QueryRow(ctx, qStr, profileId).Scan(&history.Goal); err != nil && err != pgx.ErrNoRows {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// EvaluateAchievements updates the profile's streaks after the event and
+// awards the badges of the rules listening to it. Only newly awarded badges
+// are returned.
+func (s *PgStore) EvaluateAchievements(ctx context.Context, profileId uuid.UUID, event string) ([]Achievement, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialize evaluations of the same profile
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, profileId.String()); err != nil {
+		return nil, err
+	}
+
+	history, err := loadProgressHistory(ctx, tx, profileId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := saveStreaks(ctx, tx, profileId, ComputeStreaks(completedDates(history), time.Now())); err != nil {
+		return nil, err
+	}
+
+	awarded := []Achievement{}
+
+	qStr := `
+		INSERT INTO user_achievements (
+			p_id,
+			badge,
+			earned_on
+		) VALUES (
+			$1,
+			$2,
+			$3
+		) ON CONFLICT DO NOTHING
+	`
+
+	for _, badge := range earnedBadges(history, event) {
+		tag, err := tx.Exec(ctx, qStr, profileId, badge.rule.Badge, badge.earnedOn)
+		if err != nil {
+			return nil, err
+		}
+
+		if tag.RowsAffected() == 1 {
+			awarded = append(awarded, badge.rule.achievement(badge.earnedOn, nil))
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return awarded, nil
+}
+
+// RecomputeAchievements checks every rule against the profile's whole
+// history. Badges the current rules no longer award are taken away, earned
+// ones keep the time they were first awarded.
+func (s *PgStore) RecomputeAchievements(ctx context.Context, profileId uuid.UUID) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, profileId.String()); err != nil {
+		return err
+	}
+
+	history, err := loadProgressHistory(ctx, tx, profileId)
+	if err != nil {
+		return err
+	}
+
+	if err := saveStreaks(ctx, tx, profileId, ComputeStreaks(completedDates(history), time.Now())); err != nil {
+		return err
+	}
+
+	earned := []string{}
+
+	qStr := `
+		INSERT INTO user_achievements (
+			p_id,
+			badge,
+			earned_on
+		) VALUES (
+			$1,
+			$2,
+			$3
+		) ON CONFLICT (p_id, badge) DO UPDATE
+		SET earned_on = $3
+	`
+
+	for _, badge := range earnedBadges(history, "") {
+		if _, err := tx.Exec(ctx, qStr, profileId, badge.rule.Badge, badge.earnedOn); err != nil {
+			return err
+		}
+
+		earned = append(earned, badge.rule.Badge)
+	}
+
+	qStr = `
+		DELETE FROM user_achievements
+		WHERE p_id = $1 AND NOT (badge = ANY($2))
+	`
+
+	if _, err := tx.Exec(ctx, qStr, profileId, earned); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RecomputeAllAchievements runs RecomputeAchievements for every profile and
+// returns how many were recomputed.
+func RecomputeAllAchievements(ctx context.Context, achievements AchievementStore) (int, error) {
+	profileIds, err := achievements.GetAllProfileIds(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, profileId := range profileIds {
+		if err := achievements.RecomputeAchievements(ctx, profileId); err != nil {
+			return i, err
+		}
+	}
+
+	return len(profileIds), nil
+}
+
+func (s *PgStore) GetAllProfileIds(ctx context.Context) ([]uuid.UUID, error) {
+	qStr := `
+		SELECT id
+		FROM user_profiles
+		ORDER BY created_at
+	`
+
+	rows, err := s.pool.Query(ctx, qStr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profileIds := []uuid.UUID{}
+	for rows.Next() {
+		var profileId uuid.UUID
+		if err := rows.Scan(&profileId); err != nil {
+			return nil, err
+		}
+
+		profileIds = append(profileIds, profileId)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return profileIds, nil
+}
+
+// achievementAward is when a profile earned a badge and when it was
+// awarded.
+type achievementAward struct {
+	earnedOn  time.Time
+	awardedAt time.Time
+}
+
+// GetAchievements lists every badge in the current rules, with the earned
+// ones filled in.
+func (s *PgStore) GetAchievements(ctx context.Context, profileId uuid.UUID) ([]Achievement, error) {
+	awards := make(map[string]achievementAward)
+
+	qStr := `
+		SELECT badge, earned_on, awarded_at
+		FROM user_achievements
+		WHERE p_id = $1
+	`
+
+	rows, err := s.pool.Query(ctx, qStr, profileId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var badge string
+		var a achievementAward
+
+		if err := rows.Scan(&badge, &a.earnedOn, &a.awardedAt); err != nil {
+			return nil, err
+		}
+
+		awards[badge] = a
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return achievementsFromAwards(awards), nil
+}
+
+func achievementsFromAwards(awards map[string]achievementAward) []Achievement {
+	achievements := make([]Achievement, 0, len(AchievementRules))
+	for _, rule := range AchievementRules {
+		a, ok := awards[rule.Badge]
+		if !ok {
+			achievements = append(achievements, Achievement{
+				Badge:       rule.Badge,
+				Name:        rule.Name,
+				Description: rule.Description,
+			})
+			continue
+		}
+
+		achievements = append(achievements, rule.achievement(a.earnedOn, &a.awardedAt))
+	}
+
+	return achievements
+}
+
+type earnedBadge struct {
+	rule     AchievementRule
+	earnedOn time.Time
+}
+
+// earnedBadges checks the history against the rules listening to event, or
+// against every rule when event is empty.
+func earnedBadges(history *ProgressHistory, event string) []earnedBadge {
+	badges := []earnedBadge{}
+
+	for _, rule := range AchievementRules {
+		if event != "" && !slices.Contains(rule.Events, event) {
+			continue
+		}
+
+		earnedOn := rule.EarnedOn(history)
+		if earnedOn == nil {
+			continue
+		}
+
+		badges = append(badges, earnedBadge{rule: rule, earnedOn: *earnedOn})
+	}
+
+	return badges
+}
+
+func (rule AchievementRule) achievement(earnedOn time.Time, awardedAt *time.Time) Achievement {
+	formatted := earnedOn.Format("2006-01-02")
+
+	return Achievement{
+		Badge:       rule.Badge,
+		Name:        rule.Name,
+		Description: rule.Description,
+		EarnedOn:    &formatted,
+		AwardedAt:   awardedAt,
+	}
+}
@@ -0,0 +1,250 @@
+package lib
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestAchievementRules(t *testing.T) {
+	history := &ProgressHistory{Goal: "L"}
+	for i, day := range []string{"2024-03-01", "2024-03-02", "2024-03-04", "2024-03-05", "2024-03-06", "2024-03-07", "2024-03-08", "2024-03-09", "2024-03-10"} {
+		// Only every other day is a deficit
+		balance := -100.0
+		if i%2 == 1 {
+			balance = 100
+		}
+
+		history.CompletedDays = append(history.CompletedDays, CompletedDay{LogDate: date(t, day), CaloricBalance: balance})
+	}
+
+	want := map[string]string{
+		"first_day":      "2024-03-01",
+		"first_goal_day": "2024-03-02",
+		// The gap on 2024-03-03 restarts the count
+		"streak_7": "2024-03-10",
+	}
+
+	badges := earnedBadges(history, EventDayCompleted)
+	if len(badges) != len(want) {
+		t.Fatalf("earned %d badges, want %d", len(badges), len(want))
+	}
+
+	for _, badge := range badges {
+		if earnedOn := badge.earnedOn.Format("2006-01-02"); earnedOn != want[badge.rule.Badge] {
+			t.Errorf("%s earned on %s, want %q", badge.rule.Badge, earnedOn, want[badge.rule.Badge])
+		}
+	}
+
+	// Weigh-ins don't earn logging badges
+	if badges := earnedBadges(history, EventWeighIn); len(badges) != 0 {
+		t.Fatalf("weigh-in earned %d badges, want 0", len(badges))
+	}
+}
+
+// achievementFixture logs and completes days up to today for a profile.
+type achievementFixture struct {
+	store     *SqliteStore
+	profileId uuid.UUID
+	today     time.Time
+}
+
+func (f achievementFixture) day(daysAgo int) string {
+	return time.Date(f.today.Year(), f.today.Month(), f.today.Day()-daysAgo, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+}
+
+func (f achievementFixture) complete(t *testing.T, daysAgo int) []Achievement {
+	t.Helper()
+
+	ctx := context.Background()
+	day := f.day(daysAgo)
+
+	exists, err := f.store.DoesLogExistForTheDay(ctx, f.profileId, day)
+	if err != nil {
+		t.Fatalf("failed to check log: %v", err)
+	}
+
+	if !*exists {
+		if err := f.store.CreateUserLog(ctx, f.profileId, 2000, day); err != nil {
+			t.Fatalf("failed to create log: %v", err)
+		}
+	}
+
+	if _, _, err := f.store.FinalizeCalorieLog(ctx, f.profileId, day); err != nil {
+		t.Fatalf("failed to finalize log: %v", err)
+	}
+
+	awarded, err := f.store.EvaluateAchievements(ctx, f.profileId, EventDayCompleted)
+	if err != nil {
+		t.Fatalf("failed to evaluate achievements: %v", err)
+	}
+
+	return awarded
+}
+
+func (f achievementFixture) reopen(t *testing.T, daysAgo int) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	if err := f.store.ReopenCalorieLog(ctx, f.profileId, f.day(daysAgo)); err != nil {
+		t.Fatalf("failed to reopen log: %v", err)
+	}
+
+	if _, err := f.store.EvaluateAchievements(ctx, f.profileId, EventDayReopened); err != nil {
+		t.Fatalf("failed to evaluate achievements: %v", err)
+	}
+}
+
+func (f achievementFixture) expectStreaks(t *testing.T, current int, longest int) {
+	t.Helper()
+
+	streaks, err := f.store.GetStreaks(context.Background(), f.profileId)
+	if err != nil {
+		t.Fatalf("failed to get streaks: %v", err)
+	}
+
+	if streaks.Current != current || streaks.Longest != longest {
+		t.Fatalf("streaks = %d current %d longest, want %d %d", streaks.Current, streaks.Longest, current, longest)
+	}
+}
+
+func (f achievementFixture) earned(t *testing.T) map[string]Achievement {
+	t.Helper()
+
+	achievements, err := f.store.GetAchievements(context.Background(), f.profileId)
+	if err != nil {
+		t.Fatalf("failed to get achievements: %v", err)
+	}
+
+	earned := map[string]Achievement{}
+	for _, achievement := range achievements {
+		if achievement.EarnedOn != nil {
+			earned[achievement.Badge] = achievement
+		}
+	}
+
+	return earned
+}
+
+func TestStreaksAcrossReopenedDays(t *testing.T) {
+	store := newTestSqliteStore(t)
+	f := achievementFixture{
+		store:     store,
+		profileId: createTestUser(t, store, "alice"),
+		today:     time.Now(),
+	}
+
+	f.expectStreaks(t, 0, 0)
+
+	var awarded []Achievement
+	for daysAgo := 6; daysAgo >= 0; daysAgo-- {
+		awarded = append(awarded, f.complete(t, daysAgo)...)
+	}
+
+	f.expectStreaks(t, 7, 7)
+
+	badges := map[string]bool{}
+	for _, achievement := range awarded {
+		badges[achievement.Badge] = true
+	}
+
+	if len(badges) != 2 || !badges["first_day"] || !badges["streak_7"] {
+		t.Fatalf("awarded %v, want first_day and streak_7 once each", awarded)
+	}
+
+	// Completing a day again awards nothing new
+	if awarded := f.complete(t, 0); len(awarded) != 0 {
+		t.Fatalf("awarded %v for a day that was already complete", awarded)
+	}
+
+	f.reopen(t, 3)
+	f.expectStreaks(t, 3, 3)
+
+	// Reopening doesn't take badges away, only recomputing does
+	if earned := f.earned(t); len(earned) != 2 {
+		t.Fatalf("earned %v after reopening, want both badges kept", earned)
+	}
+
+	f.complete(t, 3)
+	f.expectStreaks(t, 7, 7)
+
+	f.reopen(t, 0)
+	f.reopen(t, 1)
+
+	// A streak ending two days ago is broken, the longest one stays
+	f.expectStreaks(t, 0, 5)
+}
+
+func TestRecomputeAchievements(t *testing.T) {
+	store := newTestSqliteStore(t)
+	ctx := context.Background()
+	f := achievementFixture{
+		store:     store,
+		profileId: createTestUser(t, store, "alice"),
+		today:     time.Now(),
+	}
+
+	for daysAgo := 7; daysAgo >= 0; daysAgo-- {
+		f.complete(t, daysAgo)
+	}
+
+	before := f.earned(t)
+	if _, ok := before["streak_7"]; !ok {
+		t.Fatalf("earned %v, want streak_7", before)
+	}
+
+	f.reopen(t, 7)
+	f.reopen(t, 3)
+
+	if err := store.RecomputeAchievements(ctx, f.profileId); err != nil {
+		t.Fatalf("failed to recompute achievements: %v", err)
+	}
+
+	after := f.earned(t)
+	if _, ok := after["streak_7"]; ok {
+		t.Fatal("recompute kept streak_7 after the streak was broken")
+	}
+
+	firstDay, ok := after["first_day"]
+	if !ok {
+		t.Fatalf("earned %v, want first_day", after)
+	}
+
+	// The badge moves to the first day still complete, but keeps when it
+	// was first awarded
+	if *firstDay.EarnedOn != f.day(6) {
+		t.Fatalf("first_day earned on %s, want %s", *firstDay.EarnedOn, f.day(6))
+	}
+
+	if !firstDay.AwardedAt.Equal(*before["first_day"].AwardedAt) {
+		t.Fatalf("first_day awarded at %s, want %s", firstDay.AwardedAt, before["first_day"].AwardedAt)
+	}
+
+	f.expectStreaks(t, 3, 3)
+
+	// Recomputing is repeatable
+	if err := store.RecomputeAchievements(ctx, f.profileId); err != nil {
+		t.Fatalf("failed to recompute achievements: %v", err)
+	}
+
+	if again := f.earned(t); len(again) != len(after) {
+		t.Fatalf("earned %v after recomputing again, want %v", again, after)
+	}
+
+	other := createTestUser(t, store, "bob")
+	recomputed, err := RecomputeAllAchievements(ctx, store)
+	if err != nil {
+		t.Fatalf("failed to recompute all achievements: %v", err)
+	}
+
+	if recomputed != 2 {
+		t.Fatalf("recomputed %d profiles, want 2", recomputed)
+	}
+
+	if earned := (achievementFixture{store: store, profileId: other}).earned(t); len(earned) != 0 {
+		t.Fatalf("profile without history earned %v", earned)
+	}
+}
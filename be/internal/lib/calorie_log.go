@@ -1,35 +1,35 @@
 package lib
 
 import (
-	"calometer/internal/db"
 	"context"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
-func DoesLogExistForTheDay(userId uuid.UUID, logDate string) (*bool, error) {
+func (s *PgStore) DoesLogExistForTheDay(ctx context.Context, profileId uuid.UUID, logDate string) (*bool, error) {
 	var logExists bool
 
 	qStr := `
 		SELECT EXISTS (
 			SELECT 1
 			FROM user_calorie_logs
-			WHERE u_id = $1 AND log_date = $2
+			WHERE p_id = $1 AND log_date = $2
 		)
 	`
 
-	if err := db.GetPool().QueryRow(context.Background(), qStr, userId, logDate).Scan(&logExists); err != nil {
+	if err := s.pool.QueryRow(ctx, qStr, profileId, logDate).Scan(&logExists); err != nil {
 		return nil, err
 	}
 
 	return &logExists, nil
 }
 
-func CreateUserLog(userId uuid.UUID, bmr float64, logDate string) error {
+func (s *PgStore) CreateUserLog(ctx context.Context, profileId uuid.UUID, bmr float64, logDate string) error {
 	qStr := `
 		INSERT INTO user_calorie_logs (
-			u_id,
+			p_id,
 			tdee,
 			log_date
 		) VALUES (
@@ -38,26 +38,26 @@ func CreateUserLog(userId uuid.UUID, bmr float64, logDate string) error {
 			$3
 		)
 	`
-	if _, err := db.GetPool().Exec(context.Background(), qStr, userId, bmr, logDate); err != nil {
+	if _, err := s.pool.Exec(ctx, qStr, profileId, bmr, logDate); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func LogCaloriesConsumed(userId uuid.UUID, caloriesConsumed float64, logDate string) error {
+func (s *PgStore) LogCaloriesConsumed(ctx context.Context, profileId uuid.UUID, caloriesConsumed float64, logDate string) error {
 	qStr := `
 		UPDATE user_calorie_logs
 		SET
 			calories_consumed = COALESCE(user_calorie_logs.calories_consumed, 0) + $2,
 			updated_at = CURRENT_TIMESTAMP
-		WHERE u_id = $1 AND log_date = $3
+		WHERE p_id = $1 AND log_date = $3
 		`
 
-	if _, err := db.GetPool().Exec(
-		context.Background(),
+	if _, err := s.pool.Exec(
+		ctx,
 		qStr,
-		userId,
+		profileId,
 		caloriesConsumed,
 		logDate,
 	); err != nil {
@@ -67,19 +67,19 @@ func LogCaloriesConsumed(userId uuid.UUID, caloriesConsumed float64, logDate str
 	return nil
 }
 
-func LogCaloriesBurnt(userId uuid.UUID, caloriesBurnt float64, logDate string) error {
+func (s *PgStore) LogCaloriesBurnt(ctx context.Context, profileId uuid.UUID, caloriesBurnt float64, logDate string) error {
 	qStr := `
 		UPDATE user_calorie_logs
 		SET
 			calories_burnt = COALESCE(user_calorie_logs.calories_burnt, 0) + $2,
 			updated_at = CURRENT_TIMESTAMP
-		WHERE u_id = $1 AND log_date = $3
+		WHERE p_id = $1 AND log_date = $3
 		`
 
-	if _, err := db.GetPool().Exec(
-		context.Background(),
+	if _, err := s.pool.Exec(
+		ctx,
 		qStr,
-		userId,
+		profileId,
 		caloriesBurnt,
 		logDate,
 	); err != nil {
@@ -89,49 +89,49 @@ func LogCaloriesBurnt(userId uuid.UUID, caloriesBurnt float64, logDate string) e
 	return nil
 }
 
-func FetchCaloriesConsumedForTheDay(userId uuid.UUID, logDate string) (*float64, error) {
+func (s *PgStore) FetchCaloriesConsumedForTheDay(ctx context.Context, profileId uuid.UUID, logDate string) (*float64, error) {
 	var caloriesConsumed float64
 
 	qStr := `
 		SELECT calories_consumed
 		FROM user_calorie_logs
-		WHERE u_id = $1 AND log_date = $2
+		WHERE p_id = $1 AND log_date = $2
 	`
 
-	if err := db.GetPool().QueryRow(context.Background(), qStr, userId, logDate).Scan(&caloriesConsumed); err != nil {
+	if err := s.pool.QueryRow(ctx, qStr, profileId, logDate).Scan(&caloriesConsumed); err != nil {
 		return nil, err
 	}
 
 	return &caloriesConsumed, nil
 }
 
-func FetchCaloriesBurntForTheDay(userId uuid.UUID, logDate string) (*float64, error) {
+func (s *PgStore) FetchCaloriesBurntForTheDay(ctx context.Context, profileId uuid.UUID, logDate string) (*float64, error) {
 	var caloriesBurnt float64
 
 	qStr := `
 		SELECT calories_burnt
 		FROM user_calorie_logs
-		WHERE u_id = $1 AND log_date = $2
+		WHERE p_id = $1 AND log_date = $2
 	`
 
-	if err := db.GetPool().QueryRow(context.Background(), qStr, userId, logDate).Scan(&caloriesBurnt); err != nil {
+	if err := s.pool.QueryRow(ctx, qStr, profileId, logDate).Scan(&caloriesBurnt); err != nil {
 		return nil, err
 	}
 
 	return &caloriesBurnt, nil
 }
 
-func AddCaloriesBurntInTDEE(userId uuid.UUID, logDate string, caloriesBurnt float64) error {
+func (s *PgStore) AddCaloriesBurntInTDEE(ctx context.Context, profileId uuid.UUID, logDate string, caloriesBurnt float64) error {
 	qStr := `
 		UPDATE user_calorie_logs
 		SET tdee = user_calorie_logs.tdee + $3
-		WHERE u_id = $1 AND log_date = $2
+		WHERE p_id = $1 AND log_date = $2
 	`
 
-	if _, err := db.GetPool().Exec(
-		context.Background(),
+	if _, err := s.pool.Exec(
+		ctx,
 		qStr,
-		userId,
+		profileId,
 		logDate,
 		caloriesBurnt,
 	); err != nil {
@@ -141,36 +141,110 @@ func AddCaloriesBurntInTDEE(userId uuid.UUID, logDate string, caloriesBurnt floa
 	return nil
 }
 
-func MarkLoggingStatus(userId uuid.UUID, logDate string, logStatus string) error {
-	qStr := `
-		UPDATE user_calorie_logs
-		SET log_status = $3
-		WHERE u_id = $1 AND log_date = $2
-	`
+// FinalizeCalorieLog marks the day as done and stores its caloric balance
+// in one transaction. Finalizing a day again recalculates the balance, so
+// retries are safe. Without a log for the day it fails with pgx.ErrNoRows.
+func (s *PgStore) FinalizeCalorieLog(ctx context.Context, profileId uuid.UUID, logDate string) (*float64, bool, error) {
+	var caloricBalance float64
+	var finalized bool
 
-	if _, err := db.GetPool().Exec(
-		context.Background(),
-		qStr,
-		userId,
-		logDate,
-		logStatus,
-	); err != nil {
-		return err
+	err := runSerializable(ctx, s.pool, func(ctx context.Context, tx pgx.Tx) error {
+		var logId uuid.UUID
+		var previousStatus string
+
+		qStr := `
+			SELECT log_status
+			FROM user_calorie_logs
+			WHERE p_id = $1 AND log_date = $2
+		`
+
+		if err := tx.QueryRow(ctx, qStr, profileId, logDate).Scan(&previousStatus); err != nil {
+			return err
+		}
+
+		finalized = previousStatus != "D"
+
+		qStr = `
+			UPDATE user_calorie_logs
+			SET
+				log_status = 'D',
+				updated_at = CURRENT_TIMESTAMP
+			WHERE p_id = $1 AND log_date = $2
+			RETURNING id, (tdee - calories_consumed) AS caloric_balance
+		`
+
+		if err := tx.QueryRow(ctx, qStr, profileId, logDate).Scan(&logId, &caloricBalance); err != nil {
+			return err
+		}
+
+		qStr = `
+			INSERT INTO user_caloric_balance (
+				calorie_log_id,
+				caloric_balance
+			) VALUES (
+				$1,
+				$2
+			) ON CONFLICT (calorie_log_id) DO UPDATE
+			SET caloric_balance = $2
+		`
+
+		if _, err := tx.Exec(ctx, qStr, logId, caloricBalance); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
 	}
 
-	return nil
+	return &caloricBalance, finalized, nil
+}
+
+// ReopenCalorieLog puts a finalized day back to pending, its balance no
+// longer counts until it is finalized again. Reopening a pending day changes
+// nothing. Without a log for the day it fails with pgx.ErrNoRows.
+func (s *PgStore) ReopenCalorieLog(ctx context.Context, profileId uuid.UUID, logDate string) error {
+	return runSerializable(ctx, s.pool, func(ctx context.Context, tx pgx.Tx) error {
+		var logId uuid.UUID
+
+		qStr := `
+			UPDATE user_calorie_logs
+			SET
+				log_status = 'P',
+				updated_at = CURRENT_TIMESTAMP
+			WHERE p_id = $1 AND log_date = $2
+			RETURNING id
+		`
+
+		if err := tx.QueryRow(ctx, qStr, profileId, logDate).Scan(&logId); err != nil {
+			return err
+		}
+
+		qStr = `
+			UPDATE user_caloric_balance
+			SET caloric_balance = 0.00
+			WHERE calorie_log_id = $1
+		`
+
+		if _, err := tx.Exec(ctx, qStr, logId); err != nil {
+			return err
+		}
+
+		return nil
+	})
 }
 
-func GetCalorieLogId(userId uuid.UUID, logDate string) (*uuid.UUID, error) {
+func (s *PgStore) GetCalorieLogId(ctx context.Context, profileId uuid.UUID, logDate string) (*uuid.UUID, error) {
 	var calorieLogId uuid.UUID
 
 	qStr := `
 		SELECT id
 		FROM user_calorie_logs
-		WHERE u_id = $1 AND log_date = $2
+		WHERE p_id = $1 AND log_date = $2
 	`
 
-	if err := db.GetPool().QueryRow(context.Background(), qStr, userId, logDate).Scan(&calorieLogId); err != nil {
+	if err := s.pool.QueryRow(ctx, qStr, profileId, logDate).Scan(&calorieLogId); err != nil {
 		return nil, err
 	}
 
@@ -186,7 +260,7 @@ type UserCalorieLogs struct {
 	LogStatus        string
 }
 
-func GetCalorieLogs(userId uuid.UUID) (map[string][]UserCalorieLogs, error) {
+func (s *PgStore) GetCalorieLogs(ctx context.Context, profileId uuid.UUID) (map[string][]UserCalorieLogs, error) {
 	monthlyLogs := make(map[string][]UserCalorieLogs)
 
 	qStr := `
@@ -198,11 +272,11 @@ func GetCalorieLogs(userId uuid.UUID) (map[string][]UserCalorieLogs, error) {
 			updated_at,
 			log_status
 		FROM user_calorie_logs
-		WHERE u_id = $1
+		WHERE p_id = $1
 		ORDER BY log_date
 	`
 
-	rows, err := db.GetPool().Query(context.Background(), qStr, userId)
+	rows, err := s.pool.Query(ctx, qStr, profileId)
 	if err != nil {
 		return nil, err
 	}
@@ -239,8 +313,9 @@ func GetCalorieLogs(userId uuid.UUID) (map[string][]UserCalorieLogs, error) {
 	return monthlyLogs, nil
 }
 
-func UpdateCalorieLog(
-	userId uuid.UUID,
+func (s *PgStore) UpdateCalorieLog(
+	ctx context.Context,
+	profileId uuid.UUID,
 	logDate string,
 	caloriesConsumed float64,
 	caloriesBurnt float64,
@@ -250,13 +325,13 @@ func UpdateCalorieLog(
 		SET
 			calories_consumed = user_calorie_logs.calories_consumed + $3,
 			calories_burnt = user_calorie_logs.calories_burnt + $4
-		WHERE u_id = $1 AND log_date = $2
+		WHERE p_id = $1 AND log_date = $2
 	`
 
-	if _, err := db.GetPool().Exec(
-		context.Background(),
+	if _, err := s.pool.Exec(
+		ctx,
 		qStr,
-		userId,
+		profileId,
 		logDate,
 		caloriesConsumed,
 		caloriesBurnt,
@@ -267,29 +342,29 @@ func UpdateCalorieLog(
 	return nil
 }
 
-func CheckLogStatusByIdAndDate(userId uuid.UUID, logDate string) (*string, error) {
+func (s *PgStore) CheckLogStatusByIdAndDate(ctx context.Context, profileId uuid.UUID, logDate string) (*string, error) {
 	var logStatus string
 
 	qStr := `
 		SELECT log_status
 		FROM user_calorie_logs
-		WHERE u_id = $1 AND log_date = $2
+		WHERE p_id = $1 AND log_date = $2
 	`
 
-	if err := db.GetPool().QueryRow(context.Background(), qStr, userId, logDate).Scan(&logStatus); err != nil {
+	if err := s.pool.QueryRow(ctx, qStr, profileId, logDate).Scan(&logStatus); err != nil {
 		return nil, err
 	}
 
-	return &logDate, nil
+	return &logStatus, nil
 }
 
-func DeleteCalorieLog(userId uuid.UUID, logDate string) error {
+func (s *PgStore) DeleteCalorieLog(ctx context.Context, profileId uuid.UUID, logDate string) error {
 	qStr := `
 		DELETE FROM user_calorie_logs
-		WHERE u_id = $1 AND log_date = $2
+		WHERE p_id = $1 AND log_date = $2
 	`
 
-	if _, err := db.GetPool().Exec(context.Background(), qStr, userId, logDate); err != nil {
+	if _, err := s.pool.Exec(ctx, qStr, profileId, logDate); err != nil {
 		return err
 	}
 
@@ -0,0 +1,130 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func TestFinalizeAndReopenCalorieLog(t *testing.T) {
+	backends := map[string]func(t *testing.T) Store{
+		"sqlite":   func(t *testing.T) Store { return newTestSqliteStore(t) },
+		"postgres": func(t *testing.T) Store { return newTestPgStore(t) },
+	}
+
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			testFinalizeAndReopenCalorieLog(t, open(t))
+		})
+	}
+}
+
+func testFinalizeAndReopenCalorieLog(t *testing.T, store Store) {
+	ctx := context.Background()
+	profileId := createTestUser(t, store, "finalize-"+uuid.NewString()[:8])
+
+	const day = "2024-03-06"
+
+	if _, _, err := store.FinalizeCalorieLog(ctx, profileId, day); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("finalized a day without a log, err = %v", err)
+	}
+
+	if err := store.CreateUserLog(ctx, profileId, 2000, day); err != nil {
+		t.Fatalf("failed to create log: %v", err)
+	}
+
+	if err := store.LogCaloriesConsumed(ctx, profileId, 1500, day); err != nil {
+		t.Fatalf("failed to log calories: %v", err)
+	}
+
+	expectNet := func(want float64) {
+		t.Helper()
+
+		net, err := store.GetNetCaloricBalance(ctx, profileId)
+		if err != nil {
+			t.Fatalf("failed to get net balance: %v", err)
+		}
+
+		if *net != want {
+			t.Fatalf("net balance = %v, want %v", *net, want)
+		}
+	}
+
+	balance, wasPending, err := store.FinalizeCalorieLog(ctx, profileId, day)
+	if err != nil {
+		t.Fatalf("failed to finalize log: %v", err)
+	}
+
+	if !wasPending {
+		t.Fatal("first finalize reported the day as already done")
+	}
+
+	// Retrying returns the same balance without counting it twice
+	again, wasPending, err := store.FinalizeCalorieLog(ctx, profileId, day)
+	if err != nil {
+		t.Fatalf("failed to finalize log again: %v", err)
+	}
+
+	if wasPending {
+		t.Fatal("second finalize reported the day as pending")
+	}
+
+	if *again != *balance {
+		t.Fatalf("second finalize balance = %v, want %v", *again, *balance)
+	}
+
+	expectNet(*balance)
+
+	for i := 0; i < 2; i++ {
+		if err := store.ReopenCalorieLog(ctx, profileId, day); err != nil {
+			t.Fatalf("failed to reopen log: %v", err)
+		}
+
+		status, err := store.CheckLogStatusByIdAndDate(ctx, profileId, day)
+		if err != nil {
+			t.Fatalf("failed to check status: %v", err)
+		}
+
+		if *status != "P" {
+			t.Fatalf("status after reopening = %q, want P", *status)
+		}
+
+		expectNet(0)
+	}
+
+	// Only one of concurrent finalizes sees the day as pending. No more of
+	// them than a serializable transaction has attempts, each round of
+	// conflicts lets one through.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	pending := 0
+	for i := 0; i < maxSerializableAttempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, wasPending, err := store.FinalizeCalorieLog(ctx, profileId, day)
+			if err != nil {
+				t.Errorf("failed to finalize log: %v", err)
+				return
+			}
+
+			if wasPending {
+				mu.Lock()
+				pending++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if pending != 1 {
+		t.Fatalf("%d concurrent finalizes saw the day as pending, want 1", pending)
+	}
+
+	expectNet(*balance)
+}
@@ -0,0 +1,307 @@
+package lib
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// A challenge ranks the members of a group by one of these metrics over its
+// date range.
+const (
+	// ChallengeMetricAdherence counts the days marked as done.
+	ChallengeMetricAdherence = "adherence"
+	// ChallengeMetricBalance sums the caloric balance of the days marked as
+	// done, so a larger deficit ranks higher.
+	ChallengeMetricBalance = "balance"
+	// ChallengeMetricWeightChange is the percentage of body weight lost.
+	ChallengeMetricWeightChange = "weight_change"
+)
+
+var ChallengeMetrics = []string{
+	ChallengeMetricAdherence,
+	ChallengeMetricBalance,
+	ChallengeMetricWeightChange,
+}
+
+type Challenge struct {
+	Id        uuid.UUID `json:"id"`
+	GroupId   uuid.UUID `json:"group_id"`
+	Name      string    `json:"name"`
+	Metric    string    `json:"metric"`
+	StartsOn  string    `json:"starts_on"`
+	EndsOn    string    `json:"ends_on"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LeaderboardEntry only carries the aggregate score of a member, what they
+// logged day to day stays private. Score is nil when there is nothing to
+// score yet, e.g. no weigh-ins in the challenge range.
+type LeaderboardEntry struct {
+	Rank   int      `json:"rank"`
+	Name   string   `json:"name"`
+	Score  *float64 `json:"score"`
+	IsSelf bool     `json:"is_self"`
+}
+
+func IsValidChallengeMetric(metric string) bool {
+	return slices.Contains(ChallengeMetrics, metric)
+}
+
+func (s *PgStore) CreateChallenge(
+	ctx context.Context,
+	groupId uuid.UUID,
+	createdBy uuid.UUID,
+	name string,
+	metric string,
+	startsOn string,
+	endsOn string,
+) (*uuid.UUID, error) {
+	var challengeId uuid.UUID
+
+	qStr := `
+		INSERT INTO challenges (
+			group_id,
+			name,
+			metric,
+			starts_on,
+			ends_on,
+			created_by
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			$4,
+			$5,
+			$6
+		)
+		RETURNING id
+	`
+
+	if err := s.pool.QueryRow(
+		ctx,
+		qStr,
+		groupId,
+		name,
+		metric,
+		startsOn,
+		endsOn,
+		createdBy,
+	).Scan(&challengeId); err != nil {
+		return nil, err
+	}
+
+	return &challengeId, nil
+}
+
+func (s *PgStore) GetChallenges(ctx context.Context, groupId uuid.UUID) ([]Challenge, error) {
+	challenges := []Challenge{}
+
+	qStr := `
+		SELECT id, group_id, name, metric, starts_on, ends_on, created_at
+		FROM challenges
+		WHERE group_id = $1
+		ORDER BY starts_on DESC, created_at DESC
+	`
+
+	rows, err := s.pool.Query(ctx, qStr, groupId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		challenge, err := scanChallenge(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		challenges = append(challenges, *challenge)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return challenges, nil
+}
+
+// GetChallenge returns nil when there is no such challenge.
+func (s *PgStore) GetChallenge(ctx context.Context, challengeId uuid.UUID) (*Challenge, error) {
+	qStr := `
+		SELECT id, group_id, name, metric, starts_on, ends_on, created_at
+		FROM challenges
+		WHERE id = $1
+	`
+
+	challenge, err := scanChallenge(s.pool.QueryRow(ctx, qStr, challengeId))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+func scanChallenge(row pgx.Row) (*Challenge, error) {
+	var challenge Challenge
+	var startsOn, endsOn time.Time
+
+	if err := row.Scan(
+		&challenge.Id,
+		&challenge.GroupId,
+		&challenge.Name,
+		&challenge.Metric,
+		&startsOn,
+		&endsOn,
+		&challenge.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	challenge.StartsOn = startsOn.Format("2006-01-02")
+	challenge.EndsOn = endsOn.Format("2006-01-02")
+
+	return &challenge, nil
+}
+
+var leaderboardQueries = map[string]string{
+	ChallengeMetricAdherence: `
+		SELECT p.id, p.name, COUNT(l.id)::float8
+		FROM group_members m
+		JOIN user_profiles p ON p.id = m.p_id
+		LEFT JOIN user_calorie_logs l
+			ON l.p_id = m.p_id
+			AND l.log_status = 'D'
+			AND l.log_date BETWEEN $2 AND $3
+		WHERE m.group_id = $1
+		GROUP BY p.id, p.name
+	`,
+	ChallengeMetricBalance: `
+		SELECT p.id, p.name, COALESCE(SUM(b.caloric_balance), 0)::float8
+		FROM group_members m
+		JOIN user_profiles p ON p.id = m.p_id
+		LEFT JOIN user_calorie_logs l
+			ON l.p_id = m.p_id
+			AND l.log_status = 'D'
+			AND l.log_date BETWEEN $2 AND $3
+		LEFT JOIN user_caloric_balance b ON b.calorie_log_id = l.id
+		WHERE m.group_id = $1
+		GROUP BY p.id, p.name
+	`,
+	// The starting weight is the last weigh-in up to the first day, or the
+	// first one within the range for members who only weighed in later.
+	ChallengeMetricWeightChange: `
+		SELECT
+			p.id,
+			p.name,
+			CASE WHEN last_w.weighed_on > first_w.weighed_on
+				THEN ((first_w.weight_kg - last_w.weight_kg) / first_w.weight_kg * 100)::float8
+			END
+		FROM group_members m
+		JOIN user_profiles p ON p.id = m.p_id
+		LEFT JOIN LATERAL (
+			SELECT weighed_on, weight_kg
+			FROM user_weigh_ins
+			WHERE p_id = m.p_id AND weighed_on <= $3
+			ORDER BY
+				CASE WHEN weighed_on <= $2 THEN 0 ELSE 1 END,
+				ABS(weighed_on - $2::date)
+			LIMIT 1
+		) first_w ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT weighed_on, weight_kg
+			FROM user_weigh_ins
+			WHERE p_id = m.p_id AND weighed_on <= $3
+			ORDER BY weighed_on DESC
+			LIMIT 1
+		) last_w ON TRUE
+		WHERE m.group_id = $1
+	`,
+}
+
+func (s *PgStore) GetChallengeScores(ctx context.Context, challenge Challenge, viewerProfileId uuid.UUID) ([]LeaderboardEntry, error) {
+	entries := []LeaderboardEntry{}
+
+	rows, err := s.pool.Query(
+		ctx,
+		leaderboardQueries[challenge.Metric],
+		challenge.GroupId,
+		challenge.StartsOn,
+		challenge.EndsOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry LeaderboardEntry
+		var profileId uuid.UUID
+
+		if err := rows.Scan(&profileId, &entry.Name, &entry.Score); err != nil {
+			return nil, err
+		}
+
+		entry.IsSelf = profileId == viewerProfileId
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetLeaderboard ranks the group's members for the challenge, highest score
+// first. Members with equal scores share a rank.
+func GetLeaderboard(ctx context.Context, challenges ChallengeStore, challenge Challenge, viewerProfileId uuid.UUID) ([]LeaderboardEntry, error) {
+	entries, err := challenges.GetChallengeScores(ctx, challenge, viewerProfileId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		if entries[i].Score != nil {
+			rounded := math.Round(*entries[i].Score*100) / 100
+			entries[i].Score = &rounded
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].Score, entries[j].Score
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+
+		return *a > *b
+	})
+
+	for i := range entries {
+		entries[i].Rank = i + 1
+
+		prev := i - 1
+		if prev >= 0 && sameScore(entries[prev].Score, entries[i].Score) {
+			entries[i].Rank = entries[prev].Rank
+		}
+	}
+
+	return entries, nil
+}
+
+func sameScore(a *float64, b *float64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return *a == *b
+}
@@ -0,0 +1,284 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// fixedScores is a ChallengeStore that returns the same scores for every
+// challenge.
+type fixedScores struct {
+	ChallengeStore
+	entries []LeaderboardEntry
+}
+
+func (f fixedScores) GetChallengeScores(ctx context.Context, challenge Challenge, viewerProfileId uuid.UUID) ([]LeaderboardEntry, error) {
+	return slices.Clone(f.entries), nil
+}
+
+func score(value float64) *float64 {
+	return &value
+}
+
+func TestGetLeaderboardRanking(t *testing.T) {
+	store := fixedScores{entries: []LeaderboardEntry{
+		{Name: "no weigh-ins"},
+		{Name: "three", Score: score(3)},
+		{Name: "five", Score: score(5.004)},
+		{Name: "also three", Score: score(2.996)},
+		{Name: "negative", Score: score(-1.5)},
+		{Name: "also no weigh-ins"},
+	}}
+
+	entries, err := GetLeaderboard(context.Background(), store, Challenge{}, uuid.Nil)
+	if err != nil {
+		t.Fatalf("failed to get leaderboard: %v", err)
+	}
+
+	// Scores are rounded to two decimals before they are compared, members
+	// without a score come last and share the last rank
+	want := []struct {
+		rank  int
+		name  string
+		score *float64
+	}{
+		{1, "five", score(5)},
+		{2, "three", score(3)},
+		{2, "also three", score(3)},
+		{4, "negative", score(-1.5)},
+		{5, "no weigh-ins", nil},
+		{5, "also no weigh-ins", nil},
+	}
+
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+
+	for i, w := range want {
+		entry := entries[i]
+		if entry.Rank != w.rank || entry.Name != w.name || !sameScore(entry.Score, w.score) {
+			t.Errorf("entry %d = %d %q %v, want %d %q %v", i, entry.Rank, entry.Name, entry.Score, w.rank, w.name, w.score)
+		}
+	}
+}
+
+func TestLeaderboardEntryOnlyExposesAggregates(t *testing.T) {
+	data, err := json.Marshal(LeaderboardEntry{Rank: 1, Name: "alice", Score: score(2)})
+	if err != nil {
+		t.Fatalf("failed to encode entry: %v", err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("failed to decode entry: %v", err)
+	}
+
+	var keys []string
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	// Adding a field here has to be a decision, members only agreed to
+	// share their rank
+	if want := []string{"is_self", "name", "rank", "score"}; !slices.Equal(keys, want) {
+		t.Fatalf("entry fields = %v, want %v", keys, want)
+	}
+}
+
+// leaderboardBackend is a store plus a way to backdate weigh-ins, which the
+// store only records for today.
+type leaderboardBackend struct {
+	store          Store
+	insertWeighIn  func(t *testing.T, profileId uuid.UUID, weighedOn string, weightKg float64)
+	usernameSuffix string
+}
+
+func sqliteLeaderboardBackend(t *testing.T) leaderboardBackend {
+	store := newTestSqliteStore(t)
+
+	return leaderboardBackend{
+		store: store,
+		insertWeighIn: func(t *testing.T, profileId uuid.UUID, weighedOn string, weightKg float64) {
+			t.Helper()
+
+			qStr := `
+				INSERT INTO user_weigh_ins (id, p_id, weighed_on, weight_kg)
+				VALUES (?1, ?2, ?3, ?4)`
+
+			if _, err := store.db.ExecContext(context.Background(), qStr, uuid.New(), profileId, weighedOn, weightKg); err != nil {
+				t.Fatalf("failed to insert weigh-in: %v", err)
+			}
+		},
+	}
+}
+
+// pgLeaderboardBackend runs the lateral joins of leaderboardQueries, which
+// SQLite has no equivalent of.
+func pgLeaderboardBackend(t *testing.T) leaderboardBackend {
+	store := newTestPgStore(t)
+
+	return leaderboardBackend{
+		store: store,
+		insertWeighIn: func(t *testing.T, profileId uuid.UUID, weighedOn string, weightKg float64) {
+			t.Helper()
+
+			qStr := `
+				INSERT INTO user_weigh_ins (id, p_id, weighed_on, weight_kg)
+				VALUES ($1, $2, $3::date, $4)`
+
+			if _, err := store.pool.Exec(context.Background(), qStr, uuid.New(), profileId, weighedOn, weightKg); err != nil {
+				t.Fatalf("failed to insert weigh-in: %v", err)
+			}
+		},
+		usernameSuffix: "-" + uuid.NewString()[:8],
+	}
+}
+
+func TestChallengeScores(t *testing.T) {
+	backends := map[string]func(t *testing.T) leaderboardBackend{
+		"sqlite":   sqliteLeaderboardBackend,
+		"postgres": pgLeaderboardBackend,
+	}
+
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			testChallengeScores(t, open(t))
+		})
+	}
+}
+
+func testChallengeScores(t *testing.T, backend leaderboardBackend) {
+	ctx := context.Background()
+	store := backend.store
+
+	profiles := map[string]uuid.UUID{}
+	for _, name := range []string{"alice", "bob", "carol", "outsider"} {
+		// Default profiles share the user's id
+		profiles[name] = createTestUser(t, store, name+backend.usernameSuffix)
+	}
+
+	groupId, err := store.CreateGroup(ctx, profiles["alice"], profiles["alice"], "group")
+	if err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+
+	groups, err := store.GetGroups(ctx, profiles["alice"], profiles["alice"])
+	if err != nil || len(groups) != 1 {
+		t.Fatalf("failed to get groups: %v %v", groups, err)
+	}
+
+	for _, name := range []string{"bob", "carol"} {
+		if _, err := store.JoinGroup(ctx, profiles[name], groups[0].InviteCode); err != nil {
+			t.Fatalf("failed to join group: %v", err)
+		}
+	}
+
+	// Only days marked as done within 2024-03-04 and 2024-03-10 count
+	balances := map[string]float64{}
+	logDay := func(name string, date string, consumed float64, finalize bool) {
+		t.Helper()
+
+		profileId := profiles[name]
+		if err := store.CreateUserLog(ctx, profileId, 2000, date); err != nil {
+			t.Fatalf("failed to create log: %v", err)
+		}
+
+		if err := store.LogCaloriesConsumed(ctx, profileId, consumed, date); err != nil {
+			t.Fatalf("failed to log calories: %v", err)
+		}
+
+		if !finalize {
+			return
+		}
+
+		balance, _, err := store.FinalizeCalorieLog(ctx, profileId, date)
+		if err != nil {
+			t.Fatalf("failed to finalize log: %v", err)
+		}
+
+		if date >= "2024-03-04" && date <= "2024-03-10" {
+			balances[name] += *balance
+		}
+	}
+
+	logDay("alice", "2024-03-03", 1500, true)
+	logDay("alice", "2024-03-04", 1500, true)
+	logDay("alice", "2024-03-10", 1700, true)
+	logDay("alice", "2024-03-11", 1500, true)
+	logDay("alice", "2024-03-06", 1000, false)
+	logDay("bob", "2024-03-05", 1800, true)
+	logDay("outsider", "2024-03-05", 1000, true)
+
+	// alice's start is her last weigh-in before the challenge, her last one
+	// is after it. bob only weighed in during the challenge and gained,
+	// carol only weighed in once.
+	backend.insertWeighIn(t, profiles["alice"], "2024-03-01", 100)
+	backend.insertWeighIn(t, profiles["alice"], "2024-03-03", 98)
+	backend.insertWeighIn(t, profiles["alice"], "2024-03-08", 96.04)
+	backend.insertWeighIn(t, profiles["alice"], "2024-03-12", 90)
+	backend.insertWeighIn(t, profiles["bob"], "2024-03-05", 80)
+	backend.insertWeighIn(t, profiles["bob"], "2024-03-09", 80.8)
+	backend.insertWeighIn(t, profiles["carol"], "2024-03-06", 70)
+	backend.insertWeighIn(t, profiles["outsider"], "2024-03-04", 100)
+	backend.insertWeighIn(t, profiles["outsider"], "2024-03-08", 50)
+
+	tests := []struct {
+		metric string
+		want   map[string]*float64
+	}{
+		{ChallengeMetricAdherence, map[string]*float64{"alice": score(2), "bob": score(1), "carol": score(0)}},
+		{ChallengeMetricBalance, map[string]*float64{"alice": score(balances["alice"]), "bob": score(balances["bob"]), "carol": score(0)}},
+		{ChallengeMetricWeightChange, map[string]*float64{"alice": score(2), "bob": score(-1), "carol": nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.metric, func(t *testing.T) {
+			challengeId, err := store.CreateChallenge(ctx, *groupId, profiles["alice"], tt.metric, tt.metric, "2024-03-04", "2024-03-10")
+			if err != nil {
+				t.Fatalf("failed to create challenge: %v", err)
+			}
+
+			challenge, err := store.GetChallenge(ctx, *challengeId)
+			if err != nil {
+				t.Fatalf("failed to get challenge: %v", err)
+			}
+
+			entries, err := GetLeaderboard(ctx, store, *challenge, profiles["bob"])
+			if err != nil {
+				t.Fatalf("failed to get leaderboard: %v", err)
+			}
+
+			if len(entries) != len(tt.want) {
+				t.Fatalf("got %d entries, want the %d members", len(entries), len(tt.want))
+			}
+
+			for i, entry := range entries {
+				name := strings.TrimSuffix(entry.Name, backend.usernameSuffix)
+				if i > 0 && entry.Rank < entries[i-1].Rank {
+					t.Fatalf("entries aren't ranked: %+v", entries)
+				}
+
+				want, ok := tt.want[name]
+				if !ok {
+					t.Fatalf("unexpected entry %q", name)
+				}
+
+				if (entry.Score == nil) != (want == nil) ||
+					(want != nil && math.Abs(*entry.Score-*want) > 0.01) {
+					t.Errorf("%s scored %v, want %v", name, entry.Score, want)
+				}
+
+				if entry.IsSelf != (name == "bob") {
+					t.Errorf("%s has is_self %v", name, entry.IsSelf)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrCoachNotFound = errors.New("no active coach with this username")
+
+// CoachShare grants a coach read access to one of a client's profiles once
+// accepted. Revoked shares are kept for the access log's sake.
+type CoachShare struct {
+	Id             uuid.UUID  `json:"id"`
+	ClientId       uuid.UUID  `json:"client_id"`
+	ClientName     string     `json:"client_name"`
+	ClientUsername string     `json:"client_username"`
+	ProfileId      uuid.UUID  `json:"profile_id"`
+	ProfileName    string     `json:"profile_name"`
+	CoachId        uuid.UUID  `json:"coach_id"`
+	CoachName      string     `json:"coach_name"`
+	CoachUsername  string     `json:"coach_username"`
+	CreatedAt      time.Time  `json:"created_at"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+}
+
+type ActiveCoachShare struct {
+	Id       uuid.UUID
+	ClientId uuid.UUID
+}
+
+type CoachAccess struct {
+	CoachId       uuid.UUID `json:"coach_id"`
+	ProfileId     uuid.UUID `json:"profile_id"`
+	CoachUsername string    `json:"coach_username"`
+	Resource      string    `json:"resource"`
+	AccessedAt    time.Time `json:"accessed_at"`
+}
+
+// InviteCoach creates a pending share of the profile for the coach with the
+// given username. An open invite or share of the profile with the same coach
+// fails with a unique violation.
+func (s *PgStore) InviteCoach(ctx context.Context, clientId uuid.UUID, profileId uuid.UUID, coachUsername string) (*uuid.UUID, error) {
+	var shareId uuid.UUID
+
+	qStr := `
+		INSERT INTO coach_shares (
+			client_id,
+			p_id,
+			coach_id
+		)
+		SELECT $1, $2, id
+		FROM users
+		WHERE username = $3
+			AND role = 'coach'
+			AND disabled_at IS NULL
+			AND id <> $1
+		RETURNING id
+	`
+
+	if err := s.pool.QueryRow(
+		ctx,
+		qStr,
+		clientId,
+		profileId,
+		coachUsername,
+	).Scan(&shareId); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrCoachNotFound
+		}
+
+		return nil, err
+	}
+
+	return &shareId, nil
+}
+
+// GetClientShares lists the coaches a client has invited or shares with.
+func (s *PgStore) GetClientShares(ctx context.Context, clientId uuid.UUID) ([]CoachShare, error) {
+	return s.getCoachShares(ctx, `s.client_id = $1`, clientId)
+}
+
+// GetCoachShares lists a coach's clients and open invites.
+func (s *PgStore) GetCoachShares(ctx context.Context, coachId uuid.UUID) ([]CoachShare, error) {
+	return s.getCoachShares(ctx, `s.coach_id = $1`, coachId)
+}
+
+func (s *PgStore) getCoachShares(ctx context.Context, condition string, userId uuid.UUID) ([]CoachShare, error) {
+	shares := []CoachShare{}
+
+	qStr := `
+		SELECT
+			s.id,
+			s.client_id,
+			client.name,
+			client.username,
+			s.p_id,
+			profile.name,
+			s.coach_id,
+			coach.name,
+			coach.username,
+			s.created_at,
+			s.accepted_at
+		FROM coach_shares s
+		JOIN users client ON client.id = s.client_id
+		JOIN users coach ON coach.id = s.coach_id
+		JOIN user_profiles profile ON profile.id = s.p_id
+		WHERE ` + condition + ` AND s.revoked_at IS NULL
+		ORDER BY s.created_at
+	`
+
+	rows, err := s.pool.Query(ctx, qStr, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var share CoachShare
+		if err := rows.Scan(
+			&share.Id,
+			&share.ClientId,
+			&share.ClientName,
+			&share.ClientUsername,
+			&share.ProfileId,
+			&share.ProfileName,
+			&share.CoachId,
+			&share.CoachName,
+			&share.CoachUsername,
+			&share.CreatedAt,
+			&share.AcceptedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		shares = append(shares, share)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+// AcceptCoachInvite returns false when the coach has no such pending invite.
+func (s *PgStore) AcceptCoachInvite(ctx context.Context, coachId uuid.UUID, shareId uuid.UUID) (*bool, error) {
+	qStr := `
+		UPDATE coach_shares
+		SET accepted_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+			AND coach_id = $2
+			AND accepted_at IS NULL
+			AND revoked_at IS NULL
+	`
+
+	tag, err := s.pool.Exec(ctx, qStr, shareId, coachId)
+	if err != nil {
+		return nil, err
+	}
+
+	accepted := tag.RowsAffected() == 1
+
+	return &accepted, nil
+}
+
+// RevokeCoachShare ends a share, or declines an invite, from either side.
+// It returns false when the user is not part of such an active share.
+func (s *PgStore) RevokeCoachShare(ctx context.Context, userId uuid.UUID, shareId uuid.UUID) (*bool, error) {
+	qStr := `
+		UPDATE coach_shares
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+			AND (client_id = $2 OR coach_id = $2)
+			AND revoked_at IS NULL
+	`
+
+	tag, err := s.pool.Exec(ctx, qStr, shareId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked := tag.RowsAffected() == 1
+
+	return &revoked, nil
+}
+
+// GetActiveShare returns nil when the coach may not read the profile's data.
+func (s *PgStore) GetActiveShare(ctx context.Context, coachId uuid.UUID, profileId uuid.UUID) (*ActiveCoachShare, error) {
+	var share ActiveCoachShare
+
+	qStr := `
+		SELECT id, client_id
+		FROM coach_shares
+		WHERE coach_id = $1
+			AND p_id = $2
+			AND accepted_at IS NOT NULL
+			AND revoked_at IS NULL
+	`
+
+	if err := s.pool.QueryRow(
+		ctx,
+		qStr,
+		coachId,
+		profileId,
+	).Scan(&share.Id, &share.ClientId); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &share, nil
+}
+
+func (s *PgStore) RecordCoachAccess(ctx context.Context, shareId uuid.UUID, resource string) error {
+	qStr := `
+		INSERT INTO coach_access_log (
+			share_id,
+			coach_id,
+			client_id,
+			p_id,
+			resource
+		)
+		SELECT id, coach_id, client_id, p_id, $2
+		FROM coach_shares
+		WHERE id = $1
+	`
+
+	if _, err := s.pool.Exec(ctx, qStr, shareId, resource); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetCoachAccessLog lets a client see what their coaches looked at.
+func (s *PgStore) GetCoachAccessLog(ctx context.Context, clientId uuid.UUID, limit int) ([]CoachAccess, error) {
+	accesses := []CoachAccess{}
+
+	qStr := `
+		SELECT
+			a.coach_id,
+			a.p_id,
+			u.username,
+			a.resource,
+			a.accessed_at
+		FROM coach_access_log a
+		JOIN users u ON u.id = a.coach_id
+		WHERE a.client_id = $1
+		ORDER BY a.accessed_at DESC
+		LIMIT $2
+	`
+
+	rows, err := s.pool.Query(ctx, qStr, clientId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var access CoachAccess
+		if err := rows.Scan(
+			&access.CoachId,
+			&access.ProfileId,
+			&access.CoachUsername,
+			&access.Resource,
+			&access.AccessedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		accesses = append(accesses, access)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return accesses, nil
+}
@@ -0,0 +1,218 @@
+package lib
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Invite codes leave out characters that are easily mistaken for another.
+const (
+	inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	inviteCodeLength   = 8
+)
+
+var ErrGroupNotFound = errors.New("no group with this invite code")
+
+type Group struct {
+	Id          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	InviteCode  string    `json:"invite_code"`
+	IsOwner     bool      `json:"is_owner"`
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func generateInviteCode() (string, error) {
+	bytes := make([]byte, inviteCodeLength)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	code := make([]byte, inviteCodeLength)
+	for i, b := range bytes {
+		code[i] = inviteCodeAlphabet[int(b)%len(inviteCodeAlphabet)]
+	}
+
+	return string(code), nil
+}
+
+// CreateGroup creates a group owned by the user with the profile as its first
+// member.
+func (s *PgStore) CreateGroup(ctx context.Context, ownerId uuid.UUID, profileId uuid.UUID, name string) (*uuid.UUID, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var groupId uuid.UUID
+
+	qStr := `
+		INSERT INTO groups (name, invite_code, owner_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (invite_code) DO NOTHING
+		RETURNING id`
+
+	// Retry the unlikely invite code collisions
+	for attempt := 0; ; attempt++ {
+		inviteCode, err := generateInviteCode()
+		if err != nil {
+			return nil, err
+		}
+
+		err = tx.QueryRow(ctx, qStr, name, inviteCode, ownerId).Scan(&groupId)
+		if err == nil {
+			break
+		}
+
+		if err != pgx.ErrNoRows || attempt == 2 {
+			return nil, err
+		}
+	}
+
+	qStr = `
+		INSERT INTO group_members (group_id, p_id)
+		VALUES ($1, $2)`
+
+	if _, err := tx.Exec(ctx, qStr, groupId, profileId); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &groupId, nil
+}
+
+// JoinGroup adds the profile to the group with the invite code. Joining
+// twice is not an error.
+func (s *PgStore) JoinGroup(ctx context.Context, profileId uuid.UUID, inviteCode string) (*uuid.UUID, error) {
+	var groupId uuid.UUID
+
+	qStr := `
+		SELECT id
+		FROM groups
+		WHERE invite_code = UPPER($1)`
+
+	if err := s.pool.QueryRow(ctx, qStr, inviteCode).Scan(&groupId); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrGroupNotFound
+		}
+
+		return nil, err
+	}
+
+	qStr = `
+		INSERT INTO group_members (group_id, p_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`
+
+	if _, err := s.pool.Exec(ctx, qStr, groupId, profileId); err != nil {
+		return nil, err
+	}
+
+	return &groupId, nil
+}
+
+// GetGroups lists the groups the profile is a member of.
+func (s *PgStore) GetGroups(ctx context.Context, userId uuid.UUID, profileId uuid.UUID) ([]Group, error) {
+	groups := []Group{}
+
+	qStr := `
+		SELECT
+			g.id,
+			g.name,
+			g.invite_code,
+			g.owner_id = $1,
+			(SELECT COUNT(*) FROM group_members WHERE group_id = g.id),
+			g.created_at
+		FROM groups g
+		JOIN group_members m ON m.group_id = g.id
+		WHERE m.p_id = $2
+		ORDER BY g.created_at
+	`
+
+	rows, err := s.pool.Query(ctx, qStr, userId, profileId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var group Group
+		if err := rows.Scan(
+			&group.Id,
+			&group.Name,
+			&group.InviteCode,
+			&group.IsOwner,
+			&group.MemberCount,
+			&group.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// LeaveGroup returns false when the profile wasn't a member.
+func (s *PgStore) LeaveGroup(ctx context.Context, groupId uuid.UUID, profileId uuid.UUID) (*bool, error) {
+	qStr := `
+		DELETE FROM group_members
+		WHERE group_id = $1 AND p_id = $2`
+
+	tag, err := s.pool.Exec(ctx, qStr, groupId, profileId)
+	if err != nil {
+		return nil, err
+	}
+
+	left := tag.RowsAffected() == 1
+
+	return &left, nil
+}
+
+func (s *PgStore) IsGroupMember(ctx context.Context, groupId uuid.UUID, profileId uuid.UUID) (*bool, error) {
+	var isMember bool
+
+	qStr := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM group_members
+			WHERE group_id = $1 AND p_id = $2
+		)`
+
+	if err := s.pool.QueryRow(ctx, qStr, groupId, profileId).Scan(&isMember); err != nil {
+		return nil, err
+	}
+
+	return &isMember, nil
+}
+
+func (s *PgStore) IsGroupOwner(ctx context.Context, groupId uuid.UUID, userId uuid.UUID) (*bool, error) {
+	var isOwner bool
+
+	qStr := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM groups
+			WHERE id = $1 AND owner_id = $2
+		)`
+
+	if err := s.pool.QueryRow(ctx, qStr, groupId, userId).Scan(&isOwner); err != nil {
+		return nil, err
+	}
+
+	return &isOwner, nil
+}
@@ -0,0 +1,152 @@
+package lib
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *PgStore) GetUserIdByIdentity(ctx context.Context, provider string, subject string) (*uuid.UUID, error) {
+	var userId uuid.UUID
+
+	qStr := `
+		SELECT u_id
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2
+	`
+
+	if err := s.pool.QueryRow(ctx, qStr, provider, subject).Scan(&userId); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &userId, nil
+}
+
+const linkIdentityQuery = `
+		INSERT INTO user_identities (
+			u_id,
+			provider,
+			subject,
+			email
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			NULLIF($4, '')
+		)
+	`
+
+func (s *PgStore) LinkIdentity(ctx context.Context, userId uuid.UUID, provider string, subject string, email string) error {
+	if _, err := s.pool.Exec(
+		ctx,
+		linkIdentityQuery,
+		userId,
+		provider,
+		subject,
+		email,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *PgStore) CreateUserWithIdentity(
+	ctx context.Context,
+	name string,
+	username string,
+	passwordHash string,
+	provider string,
+	subject string,
+	email string,
+) (*uuid.UUID, error) {
+	var userId uuid.UUID
+
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, createUserQuery, name, username, passwordHash).Scan(&userId); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, linkIdentityQuery, userId, provider, subject, email); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &userId, nil
+}
+
+// Users created through an identity provider have no password. bcrypt never
+// matches this value, so password login stays impossible for them.
+const noPasswordHash = "!"
+
+var usernameDisallowedChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// CreateUserForIdentity signs up a new user from an external identity,
+// deriving a free username from the provider's preferred username or email.
+func CreateUserForIdentity(
+	ctx context.Context,
+	users UserStore,
+	name string,
+	preferredUsername string,
+	provider string,
+	subject string,
+	email string,
+) (*uuid.UUID, error) {
+	username, err := availableUsername(ctx, users, preferredUsername, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = username
+	}
+
+	return users.CreateUserWithIdentity(ctx, name, username, noPasswordHash, provider, subject, email)
+}
+
+func availableUsername(ctx context.Context, users UserStore, preferredUsername string, email string) (string, error) {
+	base := preferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+
+	base = usernameDisallowedChars.ReplaceAllString(strings.ToLower(base), "")
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		exists, err := users.DoesUserExists(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+
+		if !*exists {
+			return candidate, nil
+		}
+
+		suffix := make([]byte, 3)
+		if _, err := rand.Read(suffix); err != nil {
+			return "", err
+		}
+		candidate = base + "-" + hex.EncodeToString(suffix)
+	}
+
+	return "", errors.New("failed to find an available username")
+}
@@ -1,11 +1,8 @@
 package lib
 
 import (
-	"calometer/internal/db"
-	"context"
 	"errors"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -29,16 +26,36 @@ func ExtractTokenFromHeader(r *http.Request) string {
 	return ""
 }
 
-func ValidateToken(tokenStr string) error {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		// Validate the algorithm used to sign the token
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrInvalidKey
-		}
+const (
+	TwoFactorChallengeTTL = 5 * time.Minute
+	OIDCFlowTTL           = 10 * time.Minute
+)
+
+// Token types keep a two-factor challenge token from being used as a
+// session token and vice versa.
+const (
+	tokenTypeAccess             = "access"
+	tokenTypeTwoFactorChallenge = "2fa_challenge"
+	tokenTypeOIDCFlow           = "oidc_flow"
+)
+
+var ErrWrongTokenType = errors.New("token has the wrong type")
+
+func (km *KeyManager) parseToken(tokenStr string, tokenType string) (*jwt.Token, error) {
+	token, err := jwt.Parse(tokenStr, km.Keyfunc, jwt.WithValidMethods(km.Methods()))
+	if err != nil {
+		return nil, err
+	}
 
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+	if claims, ok := token.Claims.(jwt.MapClaims); !ok || claims["typ"] != tokenType {
+		return nil, ErrWrongTokenType
+	}
 
+	return token, nil
+}
+
+func (km *KeyManager) ValidateToken(tokenStr string) error {
+	token, err := km.parseToken(tokenStr, tokenTypeAccess)
 	if err != nil || !token.Valid {
 		return err
 	}
@@ -46,15 +63,17 @@ func ValidateToken(tokenStr string) error {
 	return nil
 }
 
-func GenerateJWT(userId uuid.UUID, username string) (string, error) {
+func (km *KeyManager) GenerateJWT(userId uuid.UUID, username string, role string) (string, error) {
 	claims := jwt.MapClaims{
 		"u_id":     userId,
 		"username": username,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(),
+		"role":     role,
+		"typ":      tokenTypeAccess,
+		"iat":      time.Now().Unix(),
+		"exp":      time.Now().Add(AccessTokenTTL).Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenStr, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	tokenStr, err := km.Sign(claims)
 	if err != nil {
 		return "", err
 	}
@@ -62,31 +81,33 @@ func GenerateJWT(userId uuid.UUID, username string) (string, error) {
 	return tokenStr, nil
 }
 
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// GenerateTwoFactorChallengeToken is handed out after a correct password
+// for users with two-factor authentication, and has to be exchanged together
+// with a valid code for a session.
+func (km *KeyManager) GenerateTwoFactorChallengeToken(userId uuid.UUID, username string) (string, error) {
+	claims := jwt.MapClaims{
+		"u_id":     userId,
+		"username": username,
+		"typ":      tokenTypeTwoFactorChallenge,
+		"iat":      time.Now().Unix(),
+		"exp":      time.Now().Add(TwoFactorChallengeTTL).Unix(),
+	}
+
+	tokenStr, err := km.Sign(claims)
 	if err != nil {
 		return "", err
 	}
 
-	return string(bytes), nil
+	return tokenStr, nil
 }
 
-func GetHashedPass(username string) (string, error) {
-	qStr := `
-		SELECT password_hash
-		FROM users
-		WHERE username = $1`
-
-	var passwordHash string
-	if err := db.GetPool().QueryRow(
-		context.Background(),
-		qStr,
-		username,
-	).Scan(&passwordHash); err != nil {
+func HashPassword(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
 		return "", err
 	}
 
-	return passwordHash, nil
+	return string(bytes), nil
 }
 
 func CheckPasswordValidity(password, passwordHash string) error {
@@ -100,16 +121,84 @@ func CheckPasswordValidity(password, passwordHash string) error {
 	return nil
 }
 
-func ExtractUserIdFromToken(tokenStr string) (*uuid.UUID, error) {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		// Check if the signing method is the expected one
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrInvalidKey
+// OIDCFlow is the state kept in a cookie between redirecting to the
+// identity provider and handling its callback.
+type OIDCFlow struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+	// LinkUserId is set when a logged in user links a new identity.
+	LinkUserId *uuid.UUID
+}
+
+func (km *KeyManager) GenerateOIDCFlowToken(flow OIDCFlow) (string, error) {
+	claims := jwt.MapClaims{
+		"state":         flow.State,
+		"nonce":         flow.Nonce,
+		"code_verifier": flow.CodeVerifier,
+		"typ":           tokenTypeOIDCFlow,
+		"iat":           time.Now().Unix(),
+		"exp":           time.Now().Add(OIDCFlowTTL).Unix(),
+	}
+
+	if flow.LinkUserId != nil {
+		claims["u_id"] = *flow.LinkUserId
+	}
+
+	return km.Sign(claims)
+}
+
+func (km *KeyManager) ParseOIDCFlowToken(tokenStr string) (*OIDCFlow, error) {
+	token, err := km.parseToken(tokenStr, tokenTypeOIDCFlow)
+	if err != nil || !token.Valid {
+		return nil, err
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+
+	flow := OIDCFlow{}
+	flow.State, _ = claims["state"].(string)
+	flow.Nonce, _ = claims["nonce"].(string)
+	flow.CodeVerifier, _ = claims["code_verifier"].(string)
+
+	if userIdStr, ok := claims["u_id"].(string); ok {
+		userId, err := uuid.Parse(userIdStr)
+		if err != nil {
+			return nil, err
 		}
-		// Return the secret used to sign the token
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
 
+		flow.LinkUserId = &userId
+	}
+
+	return &flow, nil
+}
+
+func (km *KeyManager) ExtractUserIdFromToken(tokenStr string) (*uuid.UUID, error) {
+	return km.extractUserId(tokenStr, tokenTypeAccess)
+}
+
+// ExtractRoleFromToken falls back to RoleUser for tokens issued before
+// roles existed.
+func (km *KeyManager) ExtractRoleFromToken(tokenStr string) (string, error) {
+	token, err := km.parseToken(tokenStr, tokenTypeAccess)
+	if err != nil {
+		return "", err
+	}
+
+	role, ok := token.Claims.(jwt.MapClaims)["role"].(string)
+	if !ok || !IsValidRole(role) {
+		return RoleUser, nil
+	}
+
+	return role, nil
+}
+
+func (km *KeyManager) ExtractUserIdFromChallengeToken(tokenStr string) (*uuid.UUID, error) {
+	return km.extractUserId(tokenStr, tokenTypeTwoFactorChallenge)
+}
+
+func (km *KeyManager) extractUserId(tokenStr string, tokenType string) (*uuid.UUID, error) {
+	token, err := km.parseToken(tokenStr, tokenType)
 	if err != nil || !token.Valid {
 		return nil, err
 	}
@@ -0,0 +1,385 @@
+package lib
+
+import (
+	"calometer/internal/config"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownSigningKey = errors.New("token was signed with an unknown or expired key")
+	ErrNoActiveKey       = errors.New("no active signing key configured")
+)
+
+type SigningKey struct {
+	Kid    string
+	Method jwt.SigningMethod
+
+	signKey   interface{}
+	verifyKey interface{}
+
+	// RetiredAt is set once a newer key took over signing. Retired keys keep
+	// validating tokens until the grace period is over.
+	RetiredAt *time.Time
+}
+
+type KeyManager struct {
+	mu          sync.RWMutex
+	keys        map[string]*SigningKey
+	activeKid   string
+	gracePeriod time.Duration
+}
+
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+func NewKeyManager(gracePeriod time.Duration) *KeyManager {
+	return &KeyManager{
+		keys:        make(map[string]*SigningKey),
+		gracePeriod: gracePeriod,
+	}
+}
+
+// AddKey registers a key for validation. An active key also becomes the one
+// new tokens are signed with, retiring the previously active key.
+func (km *KeyManager) AddKey(key *SigningKey, active bool) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	km.keys[key.Kid] = key
+
+	if !active {
+		return
+	}
+
+	if previous, ok := km.keys[km.activeKid]; ok && previous.Kid != key.Kid {
+		now := time.Now()
+		previous.RetiredAt = &now
+	}
+
+	key.RetiredAt = nil
+	km.activeKid = key.Kid
+}
+
+func (km *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	km.mu.RLock()
+	key, ok := km.keys[km.activeKid]
+	km.mu.RUnlock()
+
+	if !ok {
+		return "", ErrNoActiveKey
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.Kid
+
+	return token.SignedString(key.signKey)
+}
+
+// Keyfunc resolves the verification key from the token's kid header.
+func (km *KeyManager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	km.mu.RLock()
+	key, ok := km.keys[kid]
+	km.mu.RUnlock()
+
+	if !ok || km.isExpired(key, time.Now()) {
+		return nil, ErrUnknownSigningKey
+	}
+
+	// Validate the algorithm used to sign the token
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, jwt.ErrInvalidKey
+	}
+
+	return key.verifyKey, nil
+}
+
+func (km *KeyManager) Methods() []string {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	methods := []string{}
+	for _, key := range km.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+
+	return methods
+}
+
+// JWKS returns the public halves of all asymmetric keys that can still
+// validate tokens. HMAC secrets are never published.
+func (km *KeyManager) JWKS() JSONWebKeySet {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	now := time.Now()
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+
+	for _, key := range km.keys {
+		if km.isExpired(key, now) {
+			continue
+		}
+
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JSONWebKey{
+				Kty: "RSA",
+				Kid: key.Kid,
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JSONWebKey{
+				Kty: "OKP",
+				Kid: key.Kid,
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+
+	return set
+}
+
+func (km *KeyManager) isExpired(key *SigningKey, now time.Time) bool {
+	return key.RetiredAt != nil && now.After(key.RetiredAt.Add(km.gracePeriod))
+}
+
+func NewHMACSigningKey(kid string, secret []byte) *SigningKey {
+	return &SigningKey{
+		Kid:       kid,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// hmacKid derives the kid from the secret, so a token names the secret it
+// was signed with across rotations without publishing the secret.
+func hmacKid(secret []byte) string {
+	sum := sha256.Sum256(secret)
+	return hex.EncodeToString(sum[:8])
+}
+
+func NewSigningKey(kid string, privateKey crypto.Signer) (*SigningKey, error) {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{
+			Kid:       kid,
+			Method:    jwt.SigningMethodRS256,
+			signKey:   key,
+			verifyKey: &key.PublicKey,
+		}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{
+			Kid:       kid,
+			Method:    jwt.SigningMethodEdDSA,
+			signKey:   key,
+			verifyKey: key.Public(),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+}
+
+func GenerateSigningKey(kid string, alg string) (*SigningKey, error) {
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewSigningKey(kid, privateKey)
+	case jwt.SigningMethodEdDSA.Alg():
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewSigningKey(kid, privateKey)
+	}
+
+	return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+}
+
+func ParseSigningKeyPEM(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewSigningKey(kid, privateKey)
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+
+	return NewSigningKey(kid, signer)
+}
+
+// NewKeyManagerFromConfig loads the signing keys cfg names.
+//
+// With KeysDir set, every <kid>.pem file in it is loaded and ActiveKid
+// selects the signing key; the others keep validating tokens for
+// KeyGracePeriod from KeysRotatedAt. Otherwise SigningAlg picks the
+// algorithm: HS256 signs with Secret (PreviousSecret stays valid for the
+// grace period after PreviousSecretRetiredAt) under a kid derived from each
+// secret, RS256 and EdDSA use a key generated at startup, which is only
+// meant for development.
+//
+// Retirement is anchored to those times rather than startup, so restarts
+// don't extend the grace period.
+func NewKeyManagerFromConfig(cfg config.JWTConfig) (*KeyManager, error) {
+	km := NewKeyManager(cfg.KeyGracePeriod)
+
+	if cfg.KeysDir != "" {
+		if err := km.loadKeysDir(cfg.KeysDir, cfg.ActiveKid, cfg.KeysRotatedAt); err != nil {
+			return nil, err
+		}
+
+		return km, nil
+	}
+
+	if cfg.SigningAlg == jwt.SigningMethodHS256.Alg() {
+		if cfg.Secret == "" {
+			return nil, errors.New("JWT_SECRET is not set")
+		}
+
+		if cfg.PreviousSecret != "" {
+			previous := NewHMACSigningKey(hmacKid([]byte(cfg.PreviousSecret)), []byte(cfg.PreviousSecret))
+			retiredAt := cfg.PreviousSecretRetiredAt
+			previous.RetiredAt = &retiredAt
+			km.AddKey(previous, false)
+		}
+		km.AddKey(NewHMACSigningKey(hmacKid([]byte(cfg.Secret)), []byte(cfg.Secret)), true)
+
+		return km, nil
+	}
+
+	// Ephemeral keys don't survive a restart, which logs everyone out
+	key, err := GenerateSigningKey(fmt.Sprintf("%d", time.Now().Unix()), cfg.SigningAlg)
+	if err != nil {
+		return nil, err
+	}
+	km.AddKey(key, true)
+
+	return km, nil
+}
+
+func (km *KeyManager) loadKeysDir(dir string, activeKid string, rotatedAt time.Time) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	if len(paths) == 0 {
+		return fmt.Errorf("no signing keys found in %s", dir)
+	}
+
+	if activeKid == "" {
+		if len(paths) > 1 {
+			return errors.New("JWT_ACTIVE_KID is required when JWT_KEYS_DIR holds several keys")
+		}
+
+		activeKid = strings.TrimSuffix(filepath.Base(paths[0]), ".pem")
+	}
+
+	if len(paths) > 1 && rotatedAt.IsZero() {
+		return errors.New("JWT_KEYS_ROTATED_AT is required when JWT_KEYS_DIR holds several keys")
+	}
+
+	var active *SigningKey
+	retired := []*SigningKey{}
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		key, err := ParseSigningKeyPEM(kid, data)
+		if err != nil {
+			return fmt.Errorf("failed to parse signing key %s: %w", kid, err)
+		}
+
+		if kid == activeKid {
+			active = key
+			continue
+		}
+
+		retired = append(retired, key)
+	}
+
+	if active == nil {
+		return fmt.Errorf("active signing key %s not found in %s", activeKid, dir)
+	}
+
+	// The other keys stopped signing when the active key took over, they're
+	// only accepted for the grace period after that
+	for _, key := range retired {
+		key.RetiredAt = &rotatedAt
+		km.AddKey(key, false)
+	}
+	km.AddKey(active, true)
+
+	return nil
+}
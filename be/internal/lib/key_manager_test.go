@@ -0,0 +1,212 @@
+package lib
+
+import (
+	"calometer/internal/config"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHMACSecretRotation(t *testing.T) {
+	before, err := NewKeyManagerFromConfig(config.JWTConfig{
+		SigningAlg: "HS256",
+		Secret:     "old secret",
+	})
+	if err != nil {
+		t.Fatalf("failed to create key manager: %v", err)
+	}
+
+	token, err := before.GenerateJWT(uuid.New(), "alice", RoleUser)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	rotated := config.JWTConfig{
+		SigningAlg:              "HS256",
+		Secret:                  "new secret",
+		PreviousSecret:          "old secret",
+		PreviousSecretRetiredAt: time.Now(),
+		KeyGracePeriod:          time.Hour,
+	}
+
+	after, err := NewKeyManagerFromConfig(rotated)
+	if err != nil {
+		t.Fatalf("failed to create key manager: %v", err)
+	}
+
+	if err := after.ValidateToken(token); err != nil {
+		t.Fatalf("token issued before the rotation is invalid: %v", err)
+	}
+
+	newToken, err := after.GenerateJWT(uuid.New(), "alice", RoleUser)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	if err := after.ValidateToken(newToken); err != nil {
+		t.Fatalf("token issued after the rotation is invalid: %v", err)
+	}
+
+	if err := before.ValidateToken(newToken); err == nil {
+		t.Fatal("token signed with the new secret validated against the old one")
+	}
+
+	rotated.PreviousSecretRetiredAt = time.Now().Add(-2 * time.Hour)
+	expired, err := NewKeyManagerFromConfig(rotated)
+	if err != nil {
+		t.Fatalf("failed to create key manager: %v", err)
+	}
+
+	if err := expired.ValidateToken(token); !errors.Is(err, ErrUnknownSigningKey) {
+		t.Fatalf("validating a token after the grace period returned %v, want %v", err, ErrUnknownSigningKey)
+	}
+}
+
+// writeKey stores a PKCS #8 encoded private key as <kid>.pem in dir.
+func writeKey(t *testing.T, dir string, kid string, key crypto.Signer) {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+}
+
+func TestKeysDirRotation(t *testing.T) {
+	dir := t.TempDir()
+
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	writeKey(t, dir, "old", oldKey)
+
+	before, err := NewKeyManagerFromConfig(config.JWTConfig{KeysDir: dir})
+	if err != nil {
+		t.Fatalf("failed to create key manager: %v", err)
+	}
+
+	token, err := before.GenerateJWT(uuid.New(), "alice", RoleUser)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	writeKey(t, dir, "new", newKey)
+
+	cfg := config.JWTConfig{
+		KeysDir:        dir,
+		ActiveKid:      "new",
+		KeyGracePeriod: time.Hour,
+	}
+
+	if _, err := NewKeyManagerFromConfig(cfg); err == nil {
+		t.Fatal("loaded several keys without a rotation time")
+	}
+
+	cfg.KeysRotatedAt = time.Now().Add(-time.Minute)
+	after, err := NewKeyManagerFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to create key manager: %v", err)
+	}
+
+	if err := after.ValidateToken(token); err != nil {
+		t.Fatalf("token signed with the retired key is invalid: %v", err)
+	}
+
+	// The grace period counts from the configured rotation, however
+	// recently the key files were touched
+	cfg.KeysRotatedAt = time.Now().Add(-2 * time.Hour)
+	expired, err := NewKeyManagerFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to create key manager: %v", err)
+	}
+
+	if err := expired.ValidateToken(token); !errors.Is(err, ErrUnknownSigningKey) {
+		t.Fatalf("validating a token after the grace period returned %v, want %v", err, ErrUnknownSigningKey)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	writeKey(t, dir, "a-rsa", rsaKey)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	writeKey(t, dir, "b-ed25519", edKey)
+
+	cfg := config.JWTConfig{
+		KeysDir:        dir,
+		ActiveKid:      "b-ed25519",
+		KeysRotatedAt:  time.Now(),
+		KeyGracePeriod: time.Hour,
+	}
+
+	km, err := NewKeyManagerFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to create key manager: %v", err)
+	}
+
+	set := km.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("published %d keys, want 2", len(set.Keys))
+	}
+
+	rsaJWK, edJWK := set.Keys[0], set.Keys[1]
+	if rsaJWK.Kid != "a-rsa" || rsaJWK.Kty != "RSA" || rsaJWK.Alg != "RS256" || rsaJWK.N == "" || rsaJWK.E != "AQAB" {
+		t.Fatalf("RSA key published as %+v", rsaJWK)
+	}
+	if edJWK.Kid != "b-ed25519" || edJWK.Kty != "OKP" || edJWK.Crv != "Ed25519" || edJWK.X == "" {
+		t.Fatalf("Ed25519 key published as %+v", edJWK)
+	}
+
+	// Keys past their grace period are no longer published
+	cfg.KeysRotatedAt = time.Now().Add(-2 * time.Hour)
+	km, err = NewKeyManagerFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to create key manager: %v", err)
+	}
+
+	if set := km.JWKS(); len(set.Keys) != 1 || set.Keys[0].Kid != "b-ed25519" {
+		t.Fatalf("published %+v, want only the active key", set.Keys)
+	}
+}
+
+func TestJWKSLeavesOutHMACSecrets(t *testing.T) {
+	km, err := NewKeyManagerFromConfig(config.JWTConfig{
+		SigningAlg: "HS256",
+		Secret:     "secret",
+	})
+	if err != nil {
+		t.Fatalf("failed to create key manager: %v", err)
+	}
+
+	if set := km.JWKS(); len(set.Keys) != 0 {
+		t.Fatalf("published %+v, want no keys", set.Keys)
+	}
+}
@@ -0,0 +1,379 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const MaxLogCommentLength = 2000
+
+var (
+	ErrLogNotFound     = errors.New("no calorie log for this day")
+	ErrNoCoachShare    = errors.New("no active share between client and coach")
+	ErrCommentNotFound = errors.New("comment not found")
+)
+
+// LogComment is a note on a logged day or on a week. Replies point at the
+// comment that started the thread, so threads are one level deep.
+type LogComment struct {
+	Id             uuid.UUID  `json:"id"`
+	ClientId       uuid.UUID  `json:"client_id"`
+	ProfileId      uuid.UUID  `json:"profile_id"`
+	CoachId        uuid.UUID  `json:"coach_id"`
+	LogDate        *string    `json:"log_date,omitempty"`
+	WeekStart      *string    `json:"week_start,omitempty"`
+	ParentId       *uuid.UUID `json:"parent_id,omitempty"`
+	AuthorId       uuid.UUID  `json:"author_id"`
+	AuthorUsername string     `json:"author_username"`
+	Body           string     `json:"body"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Read           bool       `json:"read"`
+}
+
+// LogCommentTarget is either a logged day or the week containing WeekStart.
+type LogCommentTarget struct {
+	LogDate   string
+	WeekStart string
+}
+
+type LogCommentFilter struct {
+	// CoachId limits the comments to threads with one coach.
+	CoachId    *uuid.UUID
+	LogDate    string
+	WeekStart  string
+	UnreadOnly bool
+}
+
+type UnreadCommentCount struct {
+	ClientId  uuid.UUID `json:"client_id"`
+	ProfileId uuid.UUID `json:"profile_id"`
+	CoachId   uuid.UUID `json:"coach_id"`
+	Unread    int       `json:"unread"`
+}
+
+// WeekStartOf returns the Monday of the week containing date.
+func WeekStartOf(date string) (string, error) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return "", err
+	}
+
+	offset := (int(day.Weekday()) + 6) % 7
+
+	return day.AddDate(0, 0, -offset).Format("2006-01-02"), nil
+}
+
+// resolveCommentTarget returns the id of the logged day, or the Monday of
+// the week, a new thread is about.
+func resolveCommentTarget(
+	ctx context.Context,
+	calorieLogs CalorieLogStore,
+	profileId uuid.UUID,
+	target LogCommentTarget,
+) (*uuid.UUID, *string, error) {
+	if target.LogDate != "" {
+		logId, err := calorieLogs.GetCalorieLogId(ctx, profileId, target.LogDate)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return nil, nil, ErrLogNotFound
+			}
+
+			return nil, nil, err
+		}
+
+		return logId, nil, nil
+	}
+
+	start, err := WeekStartOf(target.WeekStart)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nil, &start, nil
+}
+
+// CreateLogComment starts a thread between the client and coach about one of
+// the client's profiles.
+func (s *PgStore) CreateLogComment(
+	ctx context.Context,
+	clientId uuid.UUID,
+	profileId uuid.UUID,
+	coachId uuid.UUID,
+	authorId uuid.UUID,
+	target LogCommentTarget,
+	body string,
+) (*uuid.UUID, error) {
+	calorieLogId, weekStart, err := resolveCommentTarget(ctx, s, profileId, target)
+	if err != nil {
+		return nil, err
+	}
+
+	var commentId uuid.UUID
+
+	qStr := `
+		INSERT INTO log_comments (
+			client_id,
+			p_id,
+			coach_id,
+			calorie_log_id,
+			week_start,
+			author_id,
+			body
+		)
+		SELECT $1, $2, $3, $4, $5::date, $6, $7
+		WHERE EXISTS (
+			SELECT 1
+			FROM coach_shares
+			WHERE client_id = $1
+				AND p_id = $2
+				AND coach_id = $3
+				AND accepted_at IS NOT NULL
+				AND revoked_at IS NULL
+		)
+		RETURNING id
+	`
+
+	if err := s.pool.QueryRow(
+		ctx,
+		qStr,
+		clientId,
+		profileId,
+		coachId,
+		calorieLogId,
+		weekStart,
+		authorId,
+		body,
+	).Scan(&commentId); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNoCoachShare
+		}
+
+		return nil, err
+	}
+
+	return &commentId, nil
+}
+
+// ReplyToLogComment adds a reply to the thread of parentId. The author has to
+// be the client or coach of that thread, and their share still active.
+func (s *PgStore) ReplyToLogComment(
+	ctx context.Context,
+	profileId uuid.UUID,
+	authorId uuid.UUID,
+	parentId uuid.UUID,
+	body string,
+) (*uuid.UUID, error) {
+	var commentId uuid.UUID
+
+	qStr := `
+		INSERT INTO log_comments (
+			client_id,
+			p_id,
+			coach_id,
+			calorie_log_id,
+			week_start,
+			parent_id,
+			author_id,
+			body
+		)
+		SELECT
+			p.client_id,
+			p.p_id,
+			p.coach_id,
+			p.calorie_log_id,
+			p.week_start,
+			COALESCE(p.parent_id, p.id),
+			$3,
+			$4
+		FROM log_comments p
+		JOIN coach_shares s
+			ON s.p_id = p.p_id
+			AND s.coach_id = p.coach_id
+			AND s.accepted_at IS NOT NULL
+			AND s.revoked_at IS NULL
+		WHERE p.id = $1
+			AND p.p_id = $2
+			AND $3 IN (p.client_id, p.coach_id)
+		RETURNING id
+	`
+
+	if err := s.pool.QueryRow(
+		ctx,
+		qStr,
+		parentId,
+		profileId,
+		authorId,
+		body,
+	).Scan(&commentId); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrCommentNotFound
+		}
+
+		return nil, err
+	}
+
+	return &commentId, nil
+}
+
+// GetLogComments lists a profile's comments as seen by viewerId, oldest
+// first. Comments are read for their author.
+func (s *PgStore) GetLogComments(ctx context.Context, profileId uuid.UUID, viewerId uuid.UUID, filter LogCommentFilter) ([]LogComment, error) {
+	comments := []LogComment{}
+
+	weekStart := filter.WeekStart
+	if weekStart != "" {
+		start, err := WeekStartOf(weekStart)
+		if err != nil {
+			return nil, err
+		}
+		weekStart = start
+	}
+
+	qStr := `
+		SELECT
+			c.id,
+			c.client_id,
+			c.p_id,
+			c.coach_id,
+			l.log_date,
+			c.week_start,
+			c.parent_id,
+			c.author_id,
+			u.username,
+			c.body,
+			c.created_at,
+			(c.author_id = $2 OR r.comment_id IS NOT NULL) AS read
+		FROM log_comments c
+		JOIN users u ON u.id = c.author_id
+		LEFT JOIN user_calorie_logs l ON l.id = c.calorie_log_id
+		LEFT JOIN log_comment_reads r ON r.comment_id = c.id AND r.u_id = $2
+		WHERE c.p_id = $1
+			AND ($3::uuid IS NULL OR c.coach_id = $3)
+			AND ($4 = '' OR l.log_date = NULLIF($4, '')::date)
+			AND ($5 = '' OR c.week_start = NULLIF($5, '')::date)
+			AND (NOT $6 OR (c.author_id <> $2 AND r.comment_id IS NULL))
+		ORDER BY c.created_at
+	`
+
+	rows, err := s.pool.Query(
+		ctx,
+		qStr,
+		profileId,
+		viewerId,
+		filter.CoachId,
+		filter.LogDate,
+		weekStart,
+		filter.UnreadOnly,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var comment LogComment
+		var logDate, weekStart *time.Time
+
+		if err := rows.Scan(
+			&comment.Id,
+			&comment.ClientId,
+			&comment.ProfileId,
+			&comment.CoachId,
+			&logDate,
+			&weekStart,
+			&comment.ParentId,
+			&comment.AuthorId,
+			&comment.AuthorUsername,
+			&comment.Body,
+			&comment.CreatedAt,
+			&comment.Read,
+		); err != nil {
+			return nil, err
+		}
+
+		if logDate != nil {
+			formatted := logDate.Format("2006-01-02")
+			comment.LogDate = &formatted
+		}
+
+		if weekStart != nil {
+			formatted := weekStart.Format("2006-01-02")
+			comment.WeekStart = &formatted
+		}
+
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// GetUnreadCommentCounts counts unread comments per shared profile and
+// coach, covering both the user's own profiles and those shared with them.
+func (s *PgStore) GetUnreadCommentCounts(ctx context.Context, userId uuid.UUID) ([]UnreadCommentCount, error) {
+	counts := []UnreadCommentCount{}
+
+	qStr := `
+		SELECT c.client_id, c.p_id, c.coach_id, COUNT(*)
+		FROM log_comments c
+		JOIN coach_shares s
+			ON s.p_id = c.p_id
+			AND s.coach_id = c.coach_id
+			AND s.accepted_at IS NOT NULL
+			AND s.revoked_at IS NULL
+		LEFT JOIN log_comment_reads r ON r.comment_id = c.id AND r.u_id = $1
+		WHERE $1 IN (c.client_id, c.coach_id)
+			AND c.author_id <> $1
+			AND r.comment_id IS NULL
+		GROUP BY c.client_id, c.p_id, c.coach_id
+	`
+
+	rows, err := s.pool.Query(ctx, qStr, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var count UnreadCommentCount
+		if err := rows.Scan(&count.ClientId, &count.ProfileId, &count.CoachId, &count.Unread); err != nil {
+			return nil, err
+		}
+
+		counts = append(counts, count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// MarkLogCommentsRead ignores comments from threads the user isn't part of.
+func (s *PgStore) MarkLogCommentsRead(ctx context.Context, userId uuid.UUID, commentIds []uuid.UUID) error {
+	qStr := `
+		INSERT INTO log_comment_reads (
+			comment_id,
+			u_id
+		)
+		SELECT id, $1
+		FROM log_comments
+		WHERE id = ANY($2)
+			AND $1 IN (client_id, coach_id)
+		ON CONFLICT DO NOTHING
+	`
+
+	if _, err := s.pool.Exec(ctx, qStr, userId, commentIds); err != nil {
+		return err
+	}
+
+	return nil
+}
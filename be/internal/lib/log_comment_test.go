@@ -0,0 +1,291 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type commentFixture struct {
+	store     *SqliteStore
+	clientId  uuid.UUID
+	profileId uuid.UUID
+	coachId   uuid.UUID
+	shareId   uuid.UUID
+}
+
+// newCommentFixture shares the client's default profile, which has a log
+// on 2024-03-06, with an accepted coach.
+func newCommentFixture(t *testing.T) commentFixture {
+	t.Helper()
+
+	ctx := context.Background()
+	store := newTestSqliteStore(t)
+
+	f := commentFixture{
+		store:    store,
+		clientId: createTestUser(t, store, "client"),
+		coachId:  createTestCoach(t, store, "coach"),
+	}
+
+	profiles, err := store.GetProfiles(ctx, f.clientId)
+	if err != nil {
+		t.Fatalf("failed to get profiles: %v", err)
+	}
+	f.profileId = profiles[0].Id
+
+	if err := store.CreateUserLog(ctx, f.profileId, 1800, "2024-03-06"); err != nil {
+		t.Fatalf("failed to create log: %v", err)
+	}
+
+	shareId, err := store.InviteCoach(ctx, f.clientId, f.profileId, "coach")
+	if err != nil {
+		t.Fatalf("failed to invite coach: %v", err)
+	}
+	f.shareId = *shareId
+
+	if _, err := store.AcceptCoachInvite(ctx, f.coachId, f.shareId); err != nil {
+		t.Fatalf("failed to accept invite: %v", err)
+	}
+
+	return f
+}
+
+func createTestCoach(t *testing.T, store *SqliteStore, username string) uuid.UUID {
+	t.Helper()
+
+	coachId := createTestUser(t, store, username)
+	if _, err := store.SetUserRole(context.Background(), coachId, RoleCoach); err != nil {
+		t.Fatalf("failed to set role: %v", err)
+	}
+
+	return coachId
+}
+
+func (f commentFixture) comment(t *testing.T, target LogCommentTarget, body string) uuid.UUID {
+	t.Helper()
+
+	commentId, err := f.store.CreateLogComment(context.Background(), f.clientId, f.profileId, f.coachId, f.coachId, target, body)
+	if err != nil {
+		t.Fatalf("failed to create comment: %v", err)
+	}
+
+	return *commentId
+}
+
+func (f commentFixture) unread(t *testing.T, userId uuid.UUID) int {
+	t.Helper()
+
+	counts, err := f.store.GetUnreadCommentCounts(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("failed to get unread counts: %v", err)
+	}
+
+	unread := 0
+	for _, count := range counts {
+		if count.ProfileId != f.profileId {
+			t.Fatalf("unread count for profile %s, want only %s", count.ProfileId, f.profileId)
+		}
+		unread += count.Unread
+	}
+
+	return unread
+}
+
+func TestLogCommentThreads(t *testing.T) {
+	f := newCommentFixture(t)
+	ctx := context.Background()
+
+	dayComment := f.comment(t, LogCommentTarget{LogDate: "2024-03-06"}, "Nice day")
+	// Any day of the week is stored as its Monday
+	weekComment := f.comment(t, LogCommentTarget{WeekStart: "2024-03-07"}, "Good week")
+
+	reply, err := f.store.ReplyToLogComment(ctx, f.profileId, f.clientId, dayComment, "Thanks")
+	if err != nil {
+		t.Fatalf("failed to reply: %v", err)
+	}
+
+	// Replying to a reply stays in the thread it belongs to
+	nested, err := f.store.ReplyToLogComment(ctx, f.profileId, f.coachId, *reply, "You're welcome")
+	if err != nil {
+		t.Fatalf("failed to reply: %v", err)
+	}
+
+	comments, err := f.store.GetLogComments(ctx, f.profileId, f.clientId, LogCommentFilter{})
+	if err != nil {
+		t.Fatalf("failed to get comments: %v", err)
+	}
+
+	byId := map[uuid.UUID]LogComment{}
+	for _, comment := range comments {
+		byId[comment.Id] = comment
+	}
+
+	if len(byId) != 4 {
+		t.Fatalf("got %d comments, want 4", len(byId))
+	}
+
+	if byId[dayComment].ParentId != nil || byId[weekComment].ParentId != nil {
+		t.Fatal("a comment starting a thread has a parent")
+	}
+
+	for _, id := range []uuid.UUID{*reply, *nested} {
+		comment := byId[id]
+		if comment.ParentId == nil || *comment.ParentId != dayComment {
+			t.Fatalf("reply %q has parent %v, want %s", comment.Body, comment.ParentId, dayComment)
+		}
+
+		if comment.LogDate == nil || *comment.LogDate != "2024-03-06" {
+			t.Fatalf("reply %q is on day %v, want the thread's", comment.Body, comment.LogDate)
+		}
+	}
+
+	if week := byId[weekComment].WeekStart; week == nil || *week != "2024-03-04" {
+		t.Fatalf("week comment starts %v, want 2024-03-04", week)
+	}
+
+	if byId[*reply].AuthorUsername != "client" || byId[*nested].AuthorUsername != "coach" {
+		t.Fatalf("reply authors = %q, %q", byId[*reply].AuthorUsername, byId[*nested].AuthorUsername)
+	}
+
+	dayComments, err := f.store.GetLogComments(ctx, f.profileId, f.clientId, LogCommentFilter{LogDate: "2024-03-06"})
+	if err != nil {
+		t.Fatalf("failed to get comments: %v", err)
+	}
+
+	if len(dayComments) != 3 {
+		t.Fatalf("got %d comments on the day, want the thread's 3", len(dayComments))
+	}
+
+	weekComments, err := f.store.GetLogComments(ctx, f.profileId, f.clientId, LogCommentFilter{WeekStart: "2024-03-10"})
+	if err != nil {
+		t.Fatalf("failed to get comments: %v", err)
+	}
+
+	if len(weekComments) != 1 || weekComments[0].Id != weekComment {
+		t.Fatalf("week comments = %+v, want only the week's", weekComments)
+	}
+}
+
+func TestLogCommentUnreadCounts(t *testing.T) {
+	f := newCommentFixture(t)
+	ctx := context.Background()
+
+	first := f.comment(t, LogCommentTarget{LogDate: "2024-03-06"}, "Nice day")
+	second := f.comment(t, LogCommentTarget{WeekStart: "2024-03-04"}, "Good week")
+
+	// Authors have read their own comments
+	if unread := f.unread(t, f.coachId); unread != 0 {
+		t.Fatalf("coach has %d unread, want 0", unread)
+	}
+
+	if unread := f.unread(t, f.clientId); unread != 2 {
+		t.Fatalf("client has %d unread, want 2", unread)
+	}
+
+	unreadOnly, err := f.store.GetLogComments(ctx, f.profileId, f.clientId, LogCommentFilter{UnreadOnly: true})
+	if err != nil {
+		t.Fatalf("failed to get comments: %v", err)
+	}
+
+	if len(unreadOnly) != 2 {
+		t.Fatalf("got %d unread comments, want 2", len(unreadOnly))
+	}
+
+	// Someone outside the thread can't mark it read for anyone
+	stranger := createTestUser(t, f.store, "stranger")
+	if err := f.store.MarkLogCommentsRead(ctx, stranger, []uuid.UUID{first, second}); err != nil {
+		t.Fatalf("failed to mark comments read: %v", err)
+	}
+
+	if unread := f.unread(t, f.clientId); unread != 2 {
+		t.Fatalf("client has %d unread after a stranger read them, want 2", unread)
+	}
+
+	// Marking twice is harmless
+	for i := 0; i < 2; i++ {
+		if err := f.store.MarkLogCommentsRead(ctx, f.clientId, []uuid.UUID{first}); err != nil {
+			t.Fatalf("failed to mark comments read: %v", err)
+		}
+	}
+
+	if unread := f.unread(t, f.clientId); unread != 1 {
+		t.Fatalf("client has %d unread, want 1", unread)
+	}
+
+	if _, err := f.store.ReplyToLogComment(ctx, f.profileId, f.clientId, first, "Thanks"); err != nil {
+		t.Fatalf("failed to reply: %v", err)
+	}
+
+	if unread := f.unread(t, f.coachId); unread != 1 {
+		t.Fatalf("coach has %d unread, want the reply", unread)
+	}
+
+	// A revoked share takes its threads out of the counts
+	if _, err := f.store.RevokeCoachShare(ctx, f.clientId, f.shareId); err != nil {
+		t.Fatalf("failed to revoke share: %v", err)
+	}
+
+	if unread := f.unread(t, f.clientId); unread != 0 {
+		t.Fatalf("client has %d unread after revoking the share, want 0", unread)
+	}
+}
+
+func TestLogCommentPermissions(t *testing.T) {
+	f := newCommentFixture(t)
+	ctx := context.Background()
+
+	commentId := f.comment(t, LogCommentTarget{LogDate: "2024-03-06"}, "Nice day")
+
+	otherCoach := createTestCoach(t, f.store, "other coach")
+	stranger := createTestUser(t, f.store, "stranger")
+
+	if _, err := f.store.CreateLogComment(ctx, f.clientId, f.profileId, otherCoach, otherCoach, LogCommentTarget{LogDate: "2024-03-06"}, "Hello"); !errors.Is(err, ErrNoCoachShare) {
+		t.Fatalf("coach without a share commented, err = %v", err)
+	}
+
+	if _, err := f.store.CreateLogComment(ctx, f.clientId, f.profileId, f.coachId, f.coachId, LogCommentTarget{LogDate: "2024-03-07"}, "Hello"); !errors.Is(err, ErrLogNotFound) {
+		t.Fatalf("commented on a day without a log, err = %v", err)
+	}
+
+	for name, authorId := range map[string]uuid.UUID{"other coach": otherCoach, "stranger": stranger} {
+		if _, err := f.store.ReplyToLogComment(ctx, f.profileId, authorId, commentId, "Hello"); !errors.Is(err, ErrCommentNotFound) {
+			t.Fatalf("%s replied to a thread they aren't part of, err = %v", name, err)
+		}
+	}
+
+	otherProfileId, err := f.store.CreateProfile(ctx, f.clientId, "other")
+	if err != nil {
+		t.Fatalf("failed to create profile: %v", err)
+	}
+
+	if _, err := f.store.ReplyToLogComment(ctx, *otherProfileId, f.clientId, commentId, "Hello"); !errors.Is(err, ErrCommentNotFound) {
+		t.Fatalf("replied through another profile, err = %v", err)
+	}
+
+	// Coaches only see their own threads
+	comments, err := f.store.GetLogComments(ctx, f.profileId, otherCoach, LogCommentFilter{CoachId: &otherCoach})
+	if err != nil {
+		t.Fatalf("failed to get comments: %v", err)
+	}
+
+	if len(comments) != 0 {
+		t.Fatalf("other coach sees %d comments, want 0", len(comments))
+	}
+
+	if _, err := f.store.RevokeCoachShare(ctx, f.coachId, f.shareId); err != nil {
+		t.Fatalf("failed to revoke share: %v", err)
+	}
+
+	if _, err := f.store.CreateLogComment(ctx, f.clientId, f.profileId, f.coachId, f.coachId, LogCommentTarget{LogDate: "2024-03-06"}, "Hello"); !errors.Is(err, ErrNoCoachShare) {
+		t.Fatalf("coach commented after the share was revoked, err = %v", err)
+	}
+
+	for name, authorId := range map[string]uuid.UUID{"client": f.clientId, "coach": f.coachId} {
+		if _, err := f.store.ReplyToLogComment(ctx, f.profileId, authorId, commentId, "Hello"); !errors.Is(err, ErrCommentNotFound) {
+			t.Fatalf("%s replied after the share was revoked, err = %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,514 @@
+package lib
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]*LoginAttempt
+	lockouts []LoginLockout
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{
+		attempts: make(map[string]*LoginAttempt),
+	}
+}
+
+func (s *MemoryLoginAttemptStore) GetLoginAttempt(ctx context.Context, key string) (*LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *attempt
+	return &copied, nil
+}
+
+func (s *MemoryLoginAttemptStore) ReserveLoginAttempt(
+	ctx context.Context,
+	key string,
+	now time.Time,
+	window time.Duration,
+	wait func(attempt LoginAttempt) time.Duration,
+) (*LoginAttempt, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop attempts nobody has failed on in a while so the map doesn't grow
+	// with every username and address ever tried.
+	for k, attempt := range s.attempts {
+		if now.Sub(attempt.LastFailureAt) > window {
+			delete(s.attempts, k)
+		}
+	}
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = &LoginAttempt{}
+	}
+
+	if delay := wait(*attempt); delay > 0 {
+		copied := *attempt
+		return &copied, delay, nil
+	}
+
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	s.attempts[key] = attempt
+
+	copied := *attempt
+	return &copied, 0, nil
+}
+
+func (s *MemoryLoginAttemptStore) ReleaseLoginAttempt(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempt, ok := s.attempts[key]; ok && attempt.Failures > 0 {
+		attempt.Failures--
+	}
+
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) LockLogin(ctx context.Context, lockout LoginLockout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempt, ok := s.attempts[lockout.Key]; ok {
+		lockedUntil := lockout.LockedUntil
+		attempt.LockedUntil = &lockedUntil
+	}
+
+	s.lockouts = append(s.lockouts, lockout)
+
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) ResetLoginAttempts(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+
+	return nil
+}
+
+// Lockouts returns the audit trail of lockouts since the process started.
+func (s *MemoryLoginAttemptStore) Lockouts() []LoginLockout {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]LoginLockout{}, s.lockouts...)
+}
+
+type PostgresLoginAttemptStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresLoginAttemptStore(pool *pgxpool.Pool) *PostgresLoginAttemptStore {
+	return &PostgresLoginAttemptStore{pool: pool}
+}
+
+func (s *PostgresLoginAttemptStore) GetLoginAttempt(ctx context.Context, key string) (*LoginAttempt, error) {
+	var attempt LoginAttempt
+
+	qStr := `
+		SELECT failures, last_failure_at, locked_until
+		FROM login_attempts
+		WHERE key = $1
+	`
+
+	if err := s.pool.QueryRow(ctx, qStr, key).Scan(
+		&attempt.Failures,
+		&attempt.LastFailureAt,
+		&attempt.LockedUntil,
+	); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+// ReserveLoginAttempt locks the key's row, so concurrent attempts for it
+// queue up behind each other.
+func (s *PostgresLoginAttemptStore) ReserveLoginAttempt(
+	ctx context.Context,
+	key string,
+	now time.Time,
+	window time.Duration,
+	wait func(attempt LoginAttempt) time.Duration,
+) (*LoginAttempt, time.Duration, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	qStr := `
+		INSERT INTO login_attempts (
+			key,
+			failures,
+			last_failure_at
+		) VALUES (
+			$1,
+			0,
+			$2
+		) ON CONFLICT (key) DO NOTHING
+	`
+
+	if _, err := tx.Exec(ctx, qStr, key, now); err != nil {
+		return nil, 0, err
+	}
+
+	var attempt LoginAttempt
+
+	qStr = `
+		SELECT failures, last_failure_at, locked_until
+		FROM login_attempts
+		WHERE key = $1
+		FOR UPDATE
+	`
+
+	if err := tx.QueryRow(ctx, qStr, key).Scan(
+		&attempt.Failures,
+		&attempt.LastFailureAt,
+		&attempt.LockedUntil,
+	); err != nil {
+		return nil, 0, err
+	}
+
+	if now.Sub(attempt.LastFailureAt) > window {
+		attempt = LoginAttempt{}
+	}
+
+	if delay := wait(attempt); delay > 0 {
+		return &attempt, delay, nil
+	}
+
+	attempt.Failures++
+	attempt.LastFailureAt = now
+
+	qStr = `
+		UPDATE login_attempts
+		SET
+			failures = $2,
+			last_failure_at = $3,
+			locked_until = $4
+		WHERE key = $1
+	`
+
+	if _, err := tx.Exec(
+		ctx,
+		qStr,
+		key,
+		attempt.Failures,
+		attempt.LastFailureAt,
+		attempt.LockedUntil,
+	); err != nil {
+		return nil, 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	return &attempt, 0, nil
+}
+
+func (s *PostgresLoginAttemptStore) ReleaseLoginAttempt(ctx context.Context, key string) error {
+	qStr := `
+		UPDATE login_attempts
+		SET failures = GREATEST(failures - 1, 0)
+		WHERE key = $1
+	`
+
+	if _, err := s.pool.Exec(ctx, qStr, key); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *PostgresLoginAttemptStore) LockLogin(ctx context.Context, lockout LoginLockout) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qStr := `
+		UPDATE login_attempts
+		SET locked_until = $2
+		WHERE key = $1
+	`
+
+	if _, err := tx.Exec(ctx, qStr, lockout.Key, lockout.LockedUntil); err != nil {
+		return err
+	}
+
+	qStr = `
+		INSERT INTO login_lockouts (
+			key,
+			failures,
+			locked_until,
+			created_at
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			$4
+		)
+	`
+
+	if _, err := tx.Exec(
+		ctx,
+		qStr,
+		lockout.Key,
+		lockout.Failures,
+		lockout.LockedUntil,
+		lockout.CreatedAt,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *PostgresLoginAttemptStore) ResetLoginAttempts(ctx context.Context, key string) error {
+	qStr := `
+		DELETE FROM login_attempts
+		WHERE key = $1
+	`
+
+	if _, err := s.pool.Exec(ctx, qStr, key); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// loginAttemptTimeLayout sorts like the times it stores, so SQLite can
+// compare them as text.
+const loginAttemptTimeLayout = "2006-01-02 15:04:05.000000000"
+
+type SqliteLoginAttemptStore struct {
+	db *sql.DB
+}
+
+func NewSqliteLoginAttemptStore(db *sql.DB) *SqliteLoginAttemptStore {
+	return &SqliteLoginAttemptStore{db: db}
+}
+
+func scanSqliteLoginAttempt(row *sql.Row) (*LoginAttempt, error) {
+	var attempt LoginAttempt
+	var lastFailureAt string
+	var lockedUntil sql.NullString
+
+	if err := row.Scan(&attempt.Failures, &lastFailureAt, &lockedUntil); err != nil {
+		return nil, err
+	}
+
+	var err error
+	attempt.LastFailureAt, err = time.Parse(loginAttemptTimeLayout, lastFailureAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if lockedUntil.Valid {
+		until, err := time.Parse(loginAttemptTimeLayout, lockedUntil.String)
+		if err != nil {
+			return nil, err
+		}
+		attempt.LockedUntil = &until
+	}
+
+	return &attempt, nil
+}
+
+func (s *SqliteLoginAttemptStore) GetLoginAttempt(ctx context.Context, key string) (*LoginAttempt, error) {
+	qStr := `
+		SELECT failures, last_failure_at, locked_until
+		FROM login_attempts
+		WHERE key = ?1
+	`
+
+	attempt, err := scanSqliteLoginAttempt(s.db.QueryRowContext(ctx, qStr, key))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return attempt, nil
+}
+
+// ReserveLoginAttempt needs no row lock like the Postgres store's, the
+// transaction holds the database's write lock from the start.
+func (s *SqliteLoginAttemptStore) ReserveLoginAttempt(
+	ctx context.Context,
+	key string,
+	now time.Time,
+	window time.Duration,
+	wait func(attempt LoginAttempt) time.Duration,
+) (*LoginAttempt, time.Duration, error) {
+	var attempt *LoginAttempt
+	var delay time.Duration
+
+	err := runSqliteTx(ctx, s.db, func(tx *sql.Tx) error {
+		qStr := `
+			SELECT failures, last_failure_at, locked_until
+			FROM login_attempts
+			WHERE key = ?1
+		`
+
+		var err error
+		attempt, err = scanSqliteLoginAttempt(tx.QueryRowContext(ctx, qStr, key))
+		if err == sql.ErrNoRows || (err == nil && now.Sub(attempt.LastFailureAt) > window) {
+			attempt, err = &LoginAttempt{}, nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if delay = wait(*attempt); delay > 0 {
+			return nil
+		}
+
+		attempt.Failures++
+		attempt.LastFailureAt = now
+
+		var lockedUntil *string
+		if attempt.LockedUntil != nil {
+			formatted := attempt.LockedUntil.UTC().Format(loginAttemptTimeLayout)
+			lockedUntil = &formatted
+		}
+
+		qStr = `
+			INSERT INTO login_attempts (
+				key,
+				failures,
+				last_failure_at,
+				locked_until
+			) VALUES (
+				?1,
+				?2,
+				?3,
+				?4
+			) ON CONFLICT (key) DO UPDATE
+			SET
+				failures = ?2,
+				last_failure_at = ?3,
+				locked_until = ?4
+		`
+
+		if _, err := tx.ExecContext(
+			ctx,
+			qStr,
+			key,
+			attempt.Failures,
+			now.UTC().Format(loginAttemptTimeLayout),
+			lockedUntil,
+		); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return attempt, delay, nil
+}
+
+func (s *SqliteLoginAttemptStore) ReleaseLoginAttempt(ctx context.Context, key string) error {
+	qStr := `
+		UPDATE login_attempts
+		SET failures = MAX(failures - 1, 0)
+		WHERE key = ?1
+	`
+
+	if _, err := s.db.ExecContext(ctx, qStr, key); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *SqliteLoginAttemptStore) LockLogin(ctx context.Context, lockout LoginLockout) error {
+	return runSqliteTx(ctx, s.db, func(tx *sql.Tx) error {
+		lockedUntil := lockout.LockedUntil.UTC().Format(loginAttemptTimeLayout)
+
+		qStr := `
+			UPDATE login_attempts
+			SET locked_until = ?2
+			WHERE key = ?1
+		`
+
+		if _, err := tx.ExecContext(ctx, qStr, lockout.Key, lockedUntil); err != nil {
+			return err
+		}
+
+		qStr = `
+			INSERT INTO login_lockouts (
+				id,
+				key,
+				failures,
+				locked_until,
+				created_at
+			) VALUES (
+				?1,
+				?2,
+				?3,
+				?4,
+				?5
+			)
+		`
+
+		if _, err := tx.ExecContext(
+			ctx,
+			qStr,
+			uuid.New(),
+			lockout.Key,
+			lockout.Failures,
+			lockedUntil,
+			lockout.CreatedAt.UTC().Format(loginAttemptTimeLayout),
+		); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (s *SqliteLoginAttemptStore) ResetLoginAttempts(ctx context.Context, key string) error {
+	qStr := `
+		DELETE FROM login_attempts
+		WHERE key = ?1
+	`
+
+	if _, err := s.db.ExecContext(ctx, qStr, key); err != nil {
+		return err
+	}
+
+	return nil
+}
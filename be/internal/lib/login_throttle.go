@@ -0,0 +1,242 @@
+package lib
+
+import (
+	"calometer/internal/config"
+	"context"
+	"fmt"
+	"time"
+)
+
+type LoginAttempt struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+type LoginLockout struct {
+	Key         string
+	Failures    int
+	LockedUntil time.Time
+	CreatedAt   time.Time
+}
+
+// LoginAttemptStore keeps track of failed logins. The in-memory store is
+// enough for a single node, several nodes have to share the Postgres one.
+type LoginAttemptStore interface {
+	GetLoginAttempt(ctx context.Context, key string) (*LoginAttempt, error)
+	// ReserveLoginAttempt counts an attempt for key as a failure unless wait
+	// returns a delay for the failures so far, starting over when the
+	// previous failure is older than window. Deciding and counting happen
+	// atomically, so concurrent attempts can't all pass before any of them
+	// is counted. It returns the attempt as stored and the delay.
+	ReserveLoginAttempt(
+		ctx context.Context,
+		key string,
+		now time.Time,
+		window time.Duration,
+		wait func(attempt LoginAttempt) time.Duration,
+	) (*LoginAttempt, time.Duration, error)
+	// ReleaseLoginAttempt takes back a reserved attempt that didn't fail.
+	ReleaseLoginAttempt(ctx context.Context, key string) error
+	LockLogin(ctx context.Context, lockout LoginLockout) error
+	ResetLoginAttempts(ctx context.Context, key string) error
+}
+
+type LoginThrottle struct {
+	store LoginAttemptStore
+
+	MaxUserFailures int
+	MaxIPFailures   int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutDuration time.Duration
+	FailureWindow   time.Duration
+}
+
+func NewLoginThrottle(store LoginAttemptStore) *LoginThrottle {
+	return &LoginThrottle{
+		store:           store,
+		MaxUserFailures: 5,
+		MaxIPFailures:   20,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutDuration: 15 * time.Minute,
+		FailureWindow:   15 * time.Minute,
+	}
+}
+
+func userThrottleKey(username string) string {
+	return "user:" + username
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// LoginReservation is a login attempt counted as a failure before the
+// credentials are checked. It is settled with Fail, Succeed or Release.
+type LoginReservation struct {
+	Username string
+	IP       string
+
+	throttle *LoginThrottle
+	// failures holds the failures of each reserved key, this attempt
+	// included
+	failures map[string]int
+	settled  bool
+}
+
+// Reserve counts an attempt for the username and IP up front. When either
+// has to wait before trying again nothing is reserved and the delay is
+// returned instead.
+func (t *LoginThrottle) Reserve(ctx context.Context, username string, ip string) (*LoginReservation, time.Duration, error) {
+	now := time.Now()
+	reservation := &LoginReservation{
+		Username: username,
+		IP:       ip,
+		throttle: t,
+		failures: make(map[string]int),
+	}
+
+	for _, key := range []string{userThrottleKey(username), ipThrottleKey(ip)} {
+		attempt, wait, err := t.store.ReserveLoginAttempt(ctx, key, now, t.FailureWindow, func(attempt LoginAttempt) time.Duration {
+			return t.wait(attempt, now)
+		})
+		if err != nil || wait > 0 {
+			// Give back the username's attempt when the IP is throttled
+			if releaseErr := reservation.Release(ctx); err == nil {
+				err = releaseErr
+			}
+
+			return nil, wait, err
+		}
+
+		reservation.failures[key] = attempt.Failures
+	}
+
+	return reservation, 0, nil
+}
+
+// wait is how long an attempt has to wait for the lockout and the
+// exponential backoff between consecutive failures.
+func (t *LoginThrottle) wait(attempt LoginAttempt, now time.Time) time.Duration {
+	var wait time.Duration
+
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+		wait = attempt.LockedUntil.Sub(now)
+	}
+
+	if next := attempt.LastFailureAt.Add(t.backoff(attempt.Failures)); next.After(now) {
+		wait = max(wait, next.Sub(now))
+	}
+
+	return wait
+}
+
+// Fail keeps the attempt counted and returns the lockouts it caused, which
+// are also written to the store as an audit record.
+func (r *LoginReservation) Fail(ctx context.Context) ([]LoginLockout, error) {
+	if r.settled {
+		return nil, nil
+	}
+	r.settled = true
+
+	now := time.Now()
+	lockouts := []LoginLockout{}
+
+	limits := map[string]int{
+		userThrottleKey(r.Username): r.throttle.MaxUserFailures,
+		ipThrottleKey(r.IP):         r.throttle.MaxIPFailures,
+	}
+
+	for key, limit := range limits {
+		if r.failures[key] < limit {
+			continue
+		}
+
+		lockout := LoginLockout{
+			Key:         key,
+			Failures:    r.failures[key],
+			LockedUntil: now.Add(r.throttle.LockoutDuration),
+			CreatedAt:   now,
+		}
+
+		if err := r.throttle.store.LockLogin(ctx, lockout); err != nil {
+			return nil, err
+		}
+
+		lockouts = append(lockouts, lockout)
+	}
+
+	return lockouts, nil
+}
+
+// Succeed clears the username's failures and takes back the IP's attempt.
+// The IP's earlier failures are left to expire, otherwise logging into an
+// account of one's own between guesses would reset the per-IP limit.
+func (r *LoginReservation) Succeed(ctx context.Context) error {
+	if r.settled {
+		return nil
+	}
+	r.settled = true
+
+	if err := r.throttle.store.ResetLoginAttempts(ctx, userThrottleKey(r.Username)); err != nil {
+		return err
+	}
+
+	return r.throttle.store.ReleaseLoginAttempt(ctx, ipThrottleKey(r.IP))
+}
+
+// Release takes back an attempt that neither failed nor succeeded, e.g.
+// because a query failed or a second factor is still required. It does
+// nothing once the reservation is settled.
+func (r *LoginReservation) Release(ctx context.Context) error {
+	if r.settled {
+		return nil
+	}
+	r.settled = true
+
+	for key := range r.failures {
+		if err := r.throttle.store.ReleaseLoginAttempt(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *LoginThrottle) backoff(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+
+	delay := t.BaseDelay
+	for i := 1; i < failures && delay < t.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, t.MaxDelay)
+}
+
+// NewLoginThrottleFromConfig keeps attempts in memory when cfg says so, and
+// in the database's store otherwise.
+func NewLoginThrottleFromConfig(cfg config.LoginThrottleConfig, database LoginAttemptStore) (*LoginThrottle, error) {
+	var store LoginAttemptStore
+
+	switch cfg.Store {
+	case "memory":
+		store = NewMemoryLoginAttemptStore()
+	case "postgres", "sqlite":
+		store = database
+	default:
+		return nil, fmt.Errorf("unknown login throttle store %q", cfg.Store)
+	}
+
+	throttle := NewLoginThrottle(store)
+	throttle.MaxUserFailures = cfg.MaxFailures
+	throttle.MaxIPFailures = cfg.MaxIPFailures
+	throttle.LockoutDuration = cfg.LockoutDuration
+	throttle.FailureWindow = max(throttle.FailureWindow, cfg.LockoutDuration)
+
+	return throttle, nil
+}
@@ -0,0 +1,274 @@
+package lib
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// loginAttemptStores runs the throttle tests against the in-memory and a
+// SQLite store.
+func loginAttemptStores(t *testing.T) map[string]LoginAttemptStore {
+	return map[string]LoginAttemptStore{
+		"memory": NewMemoryLoginAttemptStore(),
+		"sqlite": NewSqliteLoginAttemptStore(newTestSqliteStore(t).db),
+	}
+}
+
+func newTestLoginThrottle(store LoginAttemptStore) *LoginThrottle {
+	throttle := NewLoginThrottle(store)
+	throttle.BaseDelay = 0
+	throttle.MaxUserFailures = 3
+	throttle.MaxIPFailures = 5
+
+	return throttle
+}
+
+// failLogin reserves an attempt and fails it, returning the lockouts.
+func failLogin(t *testing.T, throttle *LoginThrottle, username string, ip string) []LoginLockout {
+	t.Helper()
+	ctx := context.Background()
+
+	reservation, wait, err := throttle.Reserve(ctx, username, ip)
+	if err != nil {
+		t.Fatalf("failed to reserve attempt: %v", err)
+	}
+	if wait > 0 {
+		t.Fatalf("attempt has to wait %v", wait)
+	}
+
+	lockouts, err := reservation.Fail(ctx)
+	if err != nil {
+		t.Fatalf("failed to record failure: %v", err)
+	}
+
+	return lockouts
+}
+
+func expectWait(t *testing.T, throttle *LoginThrottle, username string, ip string) time.Duration {
+	t.Helper()
+
+	reservation, wait, err := throttle.Reserve(context.Background(), username, ip)
+	if err != nil {
+		t.Fatalf("failed to reserve attempt: %v", err)
+	}
+	if reservation != nil || wait <= 0 {
+		t.Fatal("attempt was let through")
+	}
+
+	return wait
+}
+
+func TestLoginThrottleBackoff(t *testing.T) {
+	throttle := NewLoginThrottle(NewMemoryLoginAttemptStore())
+	throttle.BaseDelay = time.Second
+	throttle.MaxDelay = 8 * time.Second
+
+	tests := []struct {
+		failures int
+		delay    time.Duration
+	}{
+		{failures: 0, delay: 0},
+		{failures: 1, delay: time.Second},
+		{failures: 2, delay: 2 * time.Second},
+		{failures: 3, delay: 4 * time.Second},
+		{failures: 4, delay: 8 * time.Second},
+		{failures: 10, delay: 8 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if delay := throttle.backoff(tt.failures); delay != tt.delay {
+			t.Fatalf("backoff(%d) = %v, want %v", tt.failures, delay, tt.delay)
+		}
+	}
+
+	// The next attempt waits out the backoff of the last failure
+	failLogin(t, throttle, "alice", "192.0.2.1")
+	if wait := expectWait(t, throttle, "alice", "192.0.2.1"); wait > time.Second {
+		t.Fatalf("attempt has to wait %v after one failure, want at most 1s", wait)
+	}
+}
+
+func TestLoginThrottleLocksOutUsername(t *testing.T) {
+	for name, store := range loginAttemptStores(t) {
+		t.Run(name, func(t *testing.T) {
+			throttle := newTestLoginThrottle(store)
+
+			// Each guess comes from another address
+			for i, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+				if lockouts := failLogin(t, throttle, "alice", ip); len(lockouts) > 0 {
+					t.Fatalf("failure %d locked out %v", i+1, lockouts)
+				}
+			}
+
+			lockouts := failLogin(t, throttle, "alice", "192.0.2.3")
+			if len(lockouts) != 1 || lockouts[0].Key != "user:alice" || lockouts[0].Failures != 3 {
+				t.Fatalf("third failure locked out %+v, want user:alice", lockouts)
+			}
+
+			if wait := expectWait(t, throttle, "alice", "192.0.2.4"); wait > throttle.LockoutDuration {
+				t.Fatalf("locked out username has to wait %v, want at most %v", wait, throttle.LockoutDuration)
+			}
+
+			// The IPs weren't locked out
+			reservation, wait, err := throttle.Reserve(context.Background(), "bob", "192.0.2.1")
+			if err != nil || wait > 0 {
+				t.Fatalf("Reserve() = %v, %v for another username", wait, err)
+			}
+			reservation.Release(context.Background())
+		})
+	}
+}
+
+func TestLoginThrottleLocksOutIP(t *testing.T) {
+	for name, store := range loginAttemptStores(t) {
+		t.Run(name, func(t *testing.T) {
+			throttle := newTestLoginThrottle(store)
+
+			var lockouts []LoginLockout
+			for _, username := range []string{"a", "b", "c", "d", "e"} {
+				lockouts = failLogin(t, throttle, username, "192.0.2.1")
+			}
+
+			if len(lockouts) != 1 || lockouts[0].Key != "ip:192.0.2.1" || lockouts[0].Failures != 5 {
+				t.Fatalf("fifth failure locked out %+v, want ip:192.0.2.1", lockouts)
+			}
+
+			expectWait(t, throttle, "f", "192.0.2.1")
+		})
+	}
+}
+
+func TestLoginThrottleWindowExpires(t *testing.T) {
+	for name, store := range loginAttemptStores(t) {
+		t.Run(name, func(t *testing.T) {
+			throttle := newTestLoginThrottle(store)
+			throttle.FailureWindow = 50 * time.Millisecond
+
+			failLogin(t, throttle, "alice", "192.0.2.1")
+			failLogin(t, throttle, "alice", "192.0.2.1")
+
+			time.Sleep(2 * throttle.FailureWindow)
+
+			// Counting starts over, so this isn't the third failure
+			if lockouts := failLogin(t, throttle, "alice", "192.0.2.1"); len(lockouts) > 0 {
+				t.Fatalf("failure after the window locked out %v", lockouts)
+			}
+
+			attempt, err := store.GetLoginAttempt(context.Background(), "user:alice")
+			if err != nil {
+				t.Fatalf("failed to get attempt: %v", err)
+			}
+			if attempt.Failures != 1 {
+				t.Fatalf("counted %d failures, want 1", attempt.Failures)
+			}
+		})
+	}
+}
+
+func TestLoginThrottleSuccessKeepsIPFailures(t *testing.T) {
+	for name, store := range loginAttemptStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			throttle := newTestLoginThrottle(store)
+
+			failLogin(t, throttle, "alice", "192.0.2.1")
+			failLogin(t, throttle, "alice", "192.0.2.1")
+
+			reservation, _, err := throttle.Reserve(ctx, "alice", "192.0.2.1")
+			if err != nil {
+				t.Fatalf("failed to reserve attempt: %v", err)
+			}
+			if err := reservation.Succeed(ctx); err != nil {
+				t.Fatalf("failed to record success: %v", err)
+			}
+
+			attempt, err := store.GetLoginAttempt(ctx, "user:alice")
+			if err != nil {
+				t.Fatalf("failed to get attempt: %v", err)
+			}
+			if attempt != nil {
+				t.Fatalf("username kept %d failures after a success", attempt.Failures)
+			}
+
+			attempt, err = store.GetLoginAttempt(ctx, "ip:192.0.2.1")
+			if err != nil {
+				t.Fatalf("failed to get attempt: %v", err)
+			}
+			if attempt == nil || attempt.Failures != 2 {
+				t.Fatalf("IP has %+v after a success, want its 2 failures", attempt)
+			}
+		})
+	}
+}
+
+func TestLoginThrottleReleasedAttemptIsNotCounted(t *testing.T) {
+	for name, store := range loginAttemptStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			throttle := newTestLoginThrottle(store)
+
+			failLogin(t, throttle, "alice", "192.0.2.1")
+
+			reservation, _, err := throttle.Reserve(ctx, "alice", "192.0.2.1")
+			if err != nil {
+				t.Fatalf("failed to reserve attempt: %v", err)
+			}
+			if err := reservation.Release(ctx); err != nil {
+				t.Fatalf("failed to release attempt: %v", err)
+			}
+
+			for _, key := range []string{"user:alice", "ip:192.0.2.1"} {
+				attempt, err := store.GetLoginAttempt(ctx, key)
+				if err != nil {
+					t.Fatalf("failed to get attempt: %v", err)
+				}
+				if attempt.Failures != 1 {
+					t.Fatalf("%s has %d failures, want 1", key, attempt.Failures)
+				}
+			}
+		})
+	}
+}
+
+func TestLoginThrottleConcurrentAttempts(t *testing.T) {
+	for name, store := range loginAttemptStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			throttle := newTestLoginThrottle(store)
+			throttle.BaseDelay = time.Minute
+
+			// Only one of the attempts sent at once may go through, the
+			// others have to wait out its backoff
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			reserved := 0
+
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					reservation, wait, err := throttle.Reserve(ctx, "alice", "192.0.2.1")
+					if err != nil {
+						t.Errorf("failed to reserve attempt: %v", err)
+						return
+					}
+
+					if wait == 0 {
+						mu.Lock()
+						reserved++
+						mu.Unlock()
+						reservation.Fail(ctx)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if reserved != 1 {
+				t.Fatalf("%d concurrent attempts went through, want 1", reserved)
+			}
+		})
+	}
+}
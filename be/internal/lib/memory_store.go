@@ -0,0 +1,780 @@
+package lib
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// MemoryStore keeps everything in memory and behaves like PgStore, including
+// its errors, for running the domain logic without a database.
+type MemoryStore struct {
+	mu    sync.Mutex
+	users map[uuid.UUID]*memoryUser
+	// identities map "<provider>:<subject>" to the linked user
+	identities    map[string]uuid.UUID
+	totp          map[uuid.UUID]*memoryTOTP
+	recoveryCodes map[uuid.UUID][]*memoryRecoveryCode
+	bodyDetails   map[uuid.UUID]*memoryBodyDetails
+	goals         map[uuid.UUID]string
+	// logs are keyed by profile id and log date
+	logs     map[uuid.UUID]map[string]*memoryCalorieLog
+	balances map[uuid.UUID]float64
+}
+
+type memoryUser struct {
+	name         string
+	username     string
+	passwordHash string
+	role         string
+	disabledAt   *time.Time
+	createdAt    time.Time
+}
+
+type memoryTOTP struct {
+	secret       string
+	enabled      bool
+	lastUsedStep int64
+}
+
+type memoryRecoveryCode struct {
+	codeHash string
+	used     bool
+}
+
+type memoryBodyDetails struct {
+	age      int
+	heightCm int
+	weightKg float64
+	gender   string
+	bmr      float64
+}
+
+type memoryCalorieLog struct {
+	id               uuid.UUID
+	tdee             float64
+	caloriesConsumed float64
+	caloriesBurnt    float64
+	logStatus        string
+	updatedAt        time.Time
+}
+
+var (
+	_ UserStore       = (*MemoryStore)(nil)
+	_ TwoFactorStore  = (*MemoryStore)(nil)
+	_ CalorieLogStore = (*MemoryStore)(nil)
+	_ BalanceStore    = (*MemoryStore)(nil)
+)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:         make(map[uuid.UUID]*memoryUser),
+		identities:    make(map[string]uuid.UUID),
+		totp:          make(map[uuid.UUID]*memoryTOTP),
+		recoveryCodes: make(map[uuid.UUID][]*memoryRecoveryCode),
+		bodyDetails:   make(map[uuid.UUID]*memoryBodyDetails),
+		goals:         make(map[uuid.UUID]string),
+		logs:          make(map[uuid.UUID]map[string]*memoryCalorieLog),
+		balances:      make(map[uuid.UUID]float64),
+	}
+}
+
+// uniqueViolation is what Postgres reports for a duplicate key.
+func uniqueViolation(constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23505",
+		Message:        "duplicate key value violates unique constraint",
+		ConstraintName: constraint,
+	}
+}
+
+func (s *MemoryStore) DoesUserExists(ctx context.Context, username string) (*bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doesExist := s.findUserId(username) != nil
+
+	return &doesExist, nil
+}
+
+func (s *MemoryStore) GetUserIdByUsername(ctx context.Context, username string) (*uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.findUserId(username), nil
+}
+
+func (s *MemoryStore) findUserId(username string) *uuid.UUID {
+	for id, user := range s.users {
+		if user.username == username {
+			return &id
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) CreateUser(ctx context.Context, name string, username string, passwordHash string) (*uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findUserId(username) != nil {
+		return nil, uniqueViolation("users_username_key")
+	}
+
+	userId := uuid.New()
+	s.users[userId] = &memoryUser{
+		name:         name,
+		username:     username,
+		passwordHash: passwordHash,
+		role:         RoleUser,
+		createdAt:    time.Now(),
+	}
+
+	return &userId, nil
+}
+
+func (s *MemoryStore) GetUsernameById(ctx context.Context, userId uuid.UUID) (*string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userId]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	username := user.username
+
+	return &username, nil
+}
+
+func (s *MemoryStore) GetPasswordHash(ctx context.Context, username string) (*string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userId := s.findUserId(username)
+	if userId == nil {
+		return nil, pgx.ErrNoRows
+	}
+
+	passwordHash := s.users[*userId].passwordHash
+
+	return &passwordHash, nil
+}
+
+func (s *MemoryStore) GetUserStatusById(ctx context.Context, userId uuid.UUID) (*UserStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userId]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return &UserStatus{Role: user.role, Disabled: user.disabledAt != nil}, nil
+}
+
+func (s *MemoryStore) SetUserDisabled(ctx context.Context, userId uuid.UUID, disabled bool) (*bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userId]
+	if ok {
+		switch {
+		case !disabled:
+			user.disabledAt = nil
+		case user.disabledAt == nil:
+			now := time.Now()
+			user.disabledAt = &now
+		}
+	}
+
+	return &ok, nil
+}
+
+func (s *MemoryStore) SetUserRole(ctx context.Context, userId uuid.UUID, role string) (*bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userId]
+	if ok {
+		user.role = role
+	}
+
+	return &ok, nil
+}
+
+// SearchUsers only counts the logs of the default profile, the only one
+// MemoryStore keeps per user.
+func (s *MemoryStore) SearchUsers(ctx context.Context, query string, limit int, offset int) ([]UserSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query = strings.ToLower(query)
+
+	users := []UserSummary{}
+	for id, user := range s.users {
+		if query != "" &&
+			!strings.Contains(strings.ToLower(user.username), query) &&
+			!strings.Contains(strings.ToLower(user.name), query) {
+			continue
+		}
+
+		summary := UserSummary{
+			Id:         id,
+			Name:       user.name,
+			Username:   user.username,
+			Role:       user.role,
+			DisabledAt: user.disabledAt,
+			CreatedAt:  user.createdAt,
+			LogCount:   len(s.logs[id]),
+		}
+
+		for _, log := range s.logs[id] {
+			if log.logStatus == "D" {
+				summary.CompletedLogCount++
+			}
+		}
+
+		users = append(users, summary)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].CreatedAt.After(users[j].CreatedAt)
+	})
+
+	if offset >= len(users) {
+		return []UserSummary{}, nil
+	}
+
+	return users[offset:min(offset+limit, len(users))], nil
+}
+
+func (s *MemoryStore) GetUserIdByIdentity(ctx context.Context, provider string, subject string) (*uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userId, ok := s.identities[provider+":"+subject]
+	if !ok {
+		return nil, nil
+	}
+
+	return &userId, nil
+}
+
+func (s *MemoryStore) LinkIdentity(ctx context.Context, userId uuid.UUID, provider string, subject string, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.linkIdentity(userId, provider, subject)
+}
+
+func (s *MemoryStore) linkIdentity(userId uuid.UUID, provider string, subject string) error {
+	key := provider + ":" + subject
+	if _, ok := s.identities[key]; ok {
+		return uniqueViolation("unique_provider_subject")
+	}
+
+	s.identities[key] = userId
+
+	return nil
+}
+
+func (s *MemoryStore) CreateUserWithIdentity(
+	ctx context.Context,
+	name string,
+	username string,
+	passwordHash string,
+	provider string,
+	subject string,
+	email string,
+) (*uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findUserId(username) != nil {
+		return nil, uniqueViolation("users_username_key")
+	}
+
+	if _, ok := s.identities[provider+":"+subject]; ok {
+		return nil, uniqueViolation("unique_provider_subject")
+	}
+
+	userId := uuid.New()
+	s.users[userId] = &memoryUser{
+		name:         name,
+		username:     username,
+		passwordHash: passwordHash,
+		role:         RoleUser,
+		createdAt:    time.Now(),
+	}
+
+	if err := s.linkIdentity(userId, provider, subject); err != nil {
+		return nil, err
+	}
+
+	return &userId, nil
+}
+
+func (s *MemoryStore) StartTOTPEnrollment(ctx context.Context, userId uuid.UUID) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if totp, ok := s.totp[userId]; ok && totp.enabled {
+		return "", ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+
+	s.totp[userId] = &memoryTOTP{secret: secret}
+
+	return secret, nil
+}
+
+func (s *MemoryStore) VerifyTOTPEnrollment(ctx context.Context, userId uuid.UUID, code string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	totp, ok := s.totp[userId]
+	if !ok {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	if totp.enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	step, ok := ValidateTOTPCode(totp.secret, code, time.Now())
+	if !ok {
+		return nil, ErrTOTPInvalidCode
+	}
+
+	codes, codeHashes, err := generateRecoveryCodes(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	totp.enabled = true
+	totp.lastUsedStep = step
+
+	s.recoveryCodes[userId] = nil
+	for _, codeHash := range codeHashes {
+		s.recoveryCodes[userId] = append(s.recoveryCodes[userId], &memoryRecoveryCode{codeHash: codeHash})
+	}
+
+	return codes, nil
+}
+
+func (s *MemoryStore) IsTOTPEnabled(ctx context.Context, userId uuid.UUID) (*bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	totp, ok := s.totp[userId]
+	enabled := ok && totp.enabled
+
+	return &enabled, nil
+}
+
+func (s *MemoryStore) VerifyTOTPCode(ctx context.Context, userId uuid.UUID, code string) (*bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	valid := false
+
+	totp, ok := s.totp[userId]
+	if !ok || !totp.enabled {
+		return &valid, nil
+	}
+
+	step, ok := ValidateTOTPCode(totp.secret, code, time.Now())
+	if ok && step > totp.lastUsedStep {
+		totp.lastUsedStep = step
+		valid = true
+	}
+
+	return &valid, nil
+}
+
+func (s *MemoryStore) UseRecoveryCode(ctx context.Context, userId uuid.UUID, code string) (*bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	used := false
+	codeHash := hashRecoveryCode(userId, code)
+
+	for _, recoveryCode := range s.recoveryCodes[userId] {
+		if !recoveryCode.used && recoveryCode.codeHash == codeHash {
+			recoveryCode.used = true
+			used = true
+			break
+		}
+	}
+
+	return &used, nil
+}
+
+func (s *MemoryStore) DisableTOTP(ctx context.Context, userId uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.totp, userId)
+	delete(s.recoveryCodes, userId)
+
+	return nil
+}
+
+func (s *MemoryStore) UpdatePassword(ctx context.Context, userId uuid.UUID, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[userId]; ok {
+		user.passwordHash = passwordHash
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) AddUserBodyDetails(ctx context.Context, profileId uuid.UUID, age int, height_cm int, weight_kg float64, gender string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bmr := CalculateBMR(gender, age, weight_kg, height_cm)
+
+	details, ok := s.bodyDetails[profileId]
+	if !ok {
+		s.bodyDetails[profileId] = &memoryBodyDetails{
+			age:      age,
+			heightCm: height_cm,
+			weightKg: weight_kg,
+			gender:   gender,
+			bmr:      bmr,
+		}
+		return nil
+	}
+
+	// Zero values keep what was stored, like the upsert in PgStore
+	updated := *details
+	if age != 0 {
+		updated.age = age
+	}
+	if height_cm != 0 {
+		updated.heightCm = height_cm
+	}
+	if weight_kg != 0 {
+		updated.weightKg = weight_kg
+	}
+	if gender != "" {
+		updated.gender = gender
+	}
+
+	if updated != *details {
+		updated.bmr = bmr
+	}
+
+	*details = updated
+
+	return nil
+}
+
+func (s *MemoryStore) SetUserGoal(ctx context.Context, profileId uuid.UUID, goal string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.goals[profileId]; ok && goal == "" {
+		return nil
+	}
+
+	s.goals[profileId] = goal
+
+	return nil
+}
+
+func (s *MemoryStore) GetUserBmr(ctx context.Context, profileId uuid.UUID) (*float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	details, ok := s.bodyDetails[profileId]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	bmr := details.bmr
+
+	return &bmr, nil
+}
+
+func (s *MemoryStore) GetUserWeightGoalById(ctx context.Context, profileId uuid.UUID) (*string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	goal, ok := s.goals[profileId]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return &goal, nil
+}
+
+func (s *MemoryStore) DoesBodyDetailsExist(ctx context.Context, profileId uuid.UUID) (*bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, exists := s.bodyDetails[profileId]
+
+	return &exists, nil
+}
+
+func (s *MemoryStore) findLog(profileId uuid.UUID, logDate string) *memoryCalorieLog {
+	return s.logs[profileId][logDate]
+}
+
+func (s *MemoryStore) DoesLogExistForTheDay(ctx context.Context, profileId uuid.UUID, logDate string) (*bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	logExists := s.findLog(profileId, logDate) != nil
+
+	return &logExists, nil
+}
+
+func (s *MemoryStore) CreateUserLog(ctx context.Context, profileId uuid.UUID, bmr float64, logDate string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findLog(profileId, logDate) != nil {
+		return uniqueViolation("unique_user_log")
+	}
+
+	if s.logs[profileId] == nil {
+		s.logs[profileId] = make(map[string]*memoryCalorieLog)
+	}
+
+	s.logs[profileId][logDate] = &memoryCalorieLog{
+		id:        uuid.New(),
+		tdee:      bmr,
+		logStatus: "P",
+		updatedAt: time.Now(),
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) LogCaloriesConsumed(ctx context.Context, profileId uuid.UUID, caloriesConsumed float64, logDate string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if log := s.findLog(profileId, logDate); log != nil {
+		log.caloriesConsumed += caloriesConsumed
+		log.updatedAt = time.Now()
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) LogCaloriesBurnt(ctx context.Context, profileId uuid.UUID, caloriesBurnt float64, logDate string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if log := s.findLog(profileId, logDate); log != nil {
+		log.caloriesBurnt += caloriesBurnt
+		log.updatedAt = time.Now()
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) FetchCaloriesConsumedForTheDay(ctx context.Context, profileId uuid.UUID, logDate string) (*float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log := s.findLog(profileId, logDate)
+	if log == nil {
+		return nil, pgx.ErrNoRows
+	}
+
+	caloriesConsumed := log.caloriesConsumed
+
+	return &caloriesConsumed, nil
+}
+
+func (s *MemoryStore) FetchCaloriesBurntForTheDay(ctx context.Context, profileId uuid.UUID, logDate string) (*float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log := s.findLog(profileId, logDate)
+	if log == nil {
+		return nil, pgx.ErrNoRows
+	}
+
+	caloriesBurnt := log.caloriesBurnt
+
+	return &caloriesBurnt, nil
+}
+
+func (s *MemoryStore) AddCaloriesBurntInTDEE(ctx context.Context, profileId uuid.UUID, logDate string, caloriesBurnt float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if log := s.findLog(profileId, logDate); log != nil {
+		log.tdee += caloriesBurnt
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) FinalizeCalorieLog(ctx context.Context, profileId uuid.UUID, logDate string) (*float64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log := s.findLog(profileId, logDate)
+	if log == nil {
+		return nil, false, pgx.ErrNoRows
+	}
+
+	finalized := log.logStatus != "D"
+
+	log.logStatus = "D"
+	log.updatedAt = time.Now()
+
+	caloricBalance := log.tdee - log.caloriesConsumed
+	s.balances[log.id] = caloricBalance
+
+	return &caloricBalance, finalized, nil
+}
+
+func (s *MemoryStore) ReopenCalorieLog(ctx context.Context, profileId uuid.UUID, logDate string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log := s.findLog(profileId, logDate)
+	if log == nil {
+		return pgx.ErrNoRows
+	}
+
+	log.logStatus = "P"
+	log.updatedAt = time.Now()
+
+	if _, ok := s.balances[log.id]; ok {
+		s.balances[log.id] = 0
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) GetCalorieLogId(ctx context.Context, profileId uuid.UUID, logDate string) (*uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log := s.findLog(profileId, logDate)
+	if log == nil {
+		return nil, pgx.ErrNoRows
+	}
+
+	calorieLogId := log.id
+
+	return &calorieLogId, nil
+}
+
+func (s *MemoryStore) GetCalorieLogs(ctx context.Context, profileId uuid.UUID) (map[string][]UserCalorieLogs, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	logDates := make([]string, 0, len(s.logs[profileId]))
+	for logDate := range s.logs[profileId] {
+		logDates = append(logDates, logDate)
+	}
+	sort.Strings(logDates)
+
+	monthlyLogs := make(map[string][]UserCalorieLogs)
+	for _, logDate := range logDates {
+		log := s.logs[profileId][logDate]
+
+		date, err := time.Parse("2006-01-02", logDate)
+		if err != nil {
+			return nil, err
+		}
+
+		monthYearKey := date.Format("January, 2006")
+		monthlyLogs[monthYearKey] = append(monthlyLogs[monthYearKey], UserCalorieLogs{
+			LogDate:          logDate,
+			CaloriesBurnt:    log.caloriesBurnt,
+			CaloriesConsumed: log.caloriesConsumed,
+			Tdee:             log.tdee,
+			Updated_at:       log.updatedAt,
+			LogStatus:        log.logStatus,
+		})
+	}
+
+	return monthlyLogs, nil
+}
+
+func (s *MemoryStore) UpdateCalorieLog(ctx context.Context,
+	profileId uuid.UUID,
+	logDate string,
+	caloriesConsumed float64,
+	caloriesBurnt float64,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if log := s.findLog(profileId, logDate); log != nil {
+		log.caloriesConsumed += caloriesConsumed
+		log.caloriesBurnt += caloriesBurnt
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) CheckLogStatusByIdAndDate(ctx context.Context, profileId uuid.UUID, logDate string) (*string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log := s.findLog(profileId, logDate)
+	if log == nil {
+		return nil, pgx.ErrNoRows
+	}
+
+	logStatus := log.logStatus
+
+	return &logStatus, nil
+}
+
+func (s *MemoryStore) DeleteCalorieLog(ctx context.Context, profileId uuid.UUID, logDate string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.logs[profileId], logDate)
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteCaloricBalanceByLogId(ctx context.Context, logId uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.balances, logId)
+
+	return nil
+}
+
+func (s *MemoryStore) GetNetCaloricBalance(ctx context.Context, profileId uuid.UUID) (*float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	netCaloricBalance := 0.0
+	for _, log := range s.logs[profileId] {
+		netCaloricBalance += s.balances[log.id]
+	}
+
+	return &netCaloricBalance, nil
+}
@@ -0,0 +1,226 @@
+package lib
+
+import (
+	"bufio"
+	"calometer/internal/config"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+type ValidationError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type PasswordPolicy struct {
+	MinLength      int
+	MaxLength      int
+	RequireUpper   bool
+	RequireLower   bool
+	RequireDigit   bool
+	RequireSymbol  bool
+	RejectUsername bool
+	// MaxRepeated is the longest run of a single character, 0 for no limit
+	MaxRepeated int
+	// ContextWords are rejected like the username, case insensitively
+	ContextWords []string
+
+	// Breached is optional, without it passwords aren't checked against
+	// known breaches.
+	Breached *BreachedPasswords
+}
+
+// BreachedPasswords holds SHA-1 hashes of breached passwords, bucketed by
+// their first five hex characters like the k-anonymity range API they are
+// usually exported from.
+type BreachedPasswords struct {
+	buckets map[string]map[string]struct{}
+	count   int
+}
+
+// Validate returns every rule the password breaks, or nil when it is
+// acceptable.
+func (p *PasswordPolicy) Validate(password string, username string) []ValidationError {
+	var errs []ValidationError
+
+	addError := func(code string, message string) {
+		errs = append(errs, ValidationError{
+			Field:   "password",
+			Code:    code,
+			Message: message,
+		})
+	}
+
+	if len([]rune(password)) < p.MinLength {
+		addError("too_short", fmt.Sprintf("Password must be at least %d characters.", p.MinLength))
+	}
+
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		addError("too_long", fmt.Sprintf("Password must be at most %d bytes.", p.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireUpper && !hasUpper {
+		addError("missing_uppercase", "Password must contain an uppercase letter.")
+	}
+
+	if p.RequireLower && !hasLower {
+		addError("missing_lowercase", "Password must contain a lowercase letter.")
+	}
+
+	if p.RequireDigit && !hasDigit {
+		addError("missing_digit", "Password must contain a digit.")
+	}
+
+	if p.RequireSymbol && !hasSymbol {
+		addError("missing_symbol", "Password must contain a symbol.")
+	}
+
+	if p.RejectUsername && username != "" &&
+		strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		addError("contains_username", "Password must not contain the username.")
+	}
+
+	if p.MaxRepeated > 0 && longestRun(password) > p.MaxRepeated {
+		addError("repeated_characters", fmt.Sprintf("Password must not repeat a character more than %d times in a row.", p.MaxRepeated))
+	}
+
+	lowered := strings.ToLower(password)
+	for _, word := range p.ContextWords {
+		if word != "" && strings.Contains(lowered, strings.ToLower(word)) {
+			addError("contains_context_word", fmt.Sprintf("Password must not contain %q.", word))
+			break
+		}
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		addError("breached", "Password has appeared in a data breach, please choose another one.")
+	}
+
+	return errs
+}
+
+func longestRun(password string) int {
+	var longest, run int
+	var last rune
+
+	for i, r := range password {
+		if i > 0 && r == last {
+			run++
+		} else {
+			run = 1
+		}
+		last = r
+		longest = max(longest, run)
+	}
+
+	return longest
+}
+
+// LoadBreachedPasswords reads one SHA-1 hash per line, optionally followed
+// by ":<count>" as in the Pwned Passwords downloads.
+func LoadBreachedPasswords(path string) (*BreachedPasswords, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	breached := &BreachedPasswords{
+		buckets: make(map[string]map[string]struct{}),
+	}
+
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("%s:%d: not a SHA-1 hash", path, lineNo)
+		}
+
+		breached.add(hash)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return breached, nil
+}
+
+func (b *BreachedPasswords) add(hash string) {
+	prefix, suffix := hash[:5], hash[5:]
+
+	bucket, ok := b.buckets[prefix]
+	if !ok {
+		bucket = make(map[string]struct{})
+		b.buckets[prefix] = bucket
+	}
+
+	if _, ok := bucket[suffix]; !ok {
+		bucket[suffix] = struct{}{}
+		b.count++
+	}
+}
+
+func (b *BreachedPasswords) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	_, ok := b.buckets[hash[:5]][hash[5:]]
+	return ok
+}
+
+func (b *BreachedPasswords) Len() int {
+	return b.count
+}
+
+// NewPasswordPolicyFromConfig also loads the breached passwords list when
+// cfg names one.
+func NewPasswordPolicyFromConfig(cfg config.PasswordConfig) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{
+		MinLength:      cfg.MinLength,
+		MaxLength:      cfg.MaxLength,
+		RequireUpper:   cfg.RequireUpper,
+		RequireLower:   cfg.RequireLower,
+		RequireDigit:   cfg.RequireDigit,
+		RequireSymbol:  cfg.RequireSymbol,
+		RejectUsername: cfg.RejectUsername,
+		MaxRepeated:    cfg.MaxRepeated,
+		ContextWords:   cfg.ContextWords,
+	}
+
+	if cfg.BreachedPasswordsFile != "" {
+		breached, err := LoadBreachedPasswords(cfg.BreachedPasswordsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load breached passwords: %w", err)
+		}
+		policy.Breached = breached
+	}
+
+	return policy, nil
+}
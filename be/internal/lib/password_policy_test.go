@@ -0,0 +1,143 @@
+package lib
+
+import (
+	"calometer/internal/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func validationCodes(errs []ValidationError) []string {
+	var codes []string
+	for _, err := range errs {
+		codes = append(codes, err.Code)
+	}
+
+	return codes
+}
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := &PasswordPolicy{
+		MinLength:      8,
+		MaxLength:      16,
+		RejectUsername: true,
+		MaxRepeated:    3,
+		ContextWords:   []string{"calometer"},
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		username string
+		want     []string
+	}{
+		{"acceptable", "plum tart 42", "alice", nil},
+		{"too short", "short", "alice", []string{"too_short"}},
+		{"exactly min length", "abcdefgh", "alice", nil},
+		{"too long", "a very long passphrase", "alice", []string{"too_long"}},
+		// Length counts characters, the maximum counts bytes for bcrypt
+		{"multibyte min length", "ümlautäö", "alice", nil},
+		{"multibyte max length", "ümlautäöümlautäö", "alice", []string{"too_long"}},
+		{"three repeated", "plum aaa tart", "alice", nil},
+		{"four repeated", "plum aaaa tart", "alice", []string{"repeated_characters"}},
+		{"repeated multibyte", "plumäääätart", "alice", []string{"repeated_characters"}},
+		{"contains username", "xxAliCe2024", "alice", []string{"contains_username"}},
+		{"no username", "plum tart 42", "", nil},
+		{"contains context word", "myCaloMeter1", "alice", []string{"contains_context_word"}},
+		{"several rules", "alice", "alice", []string{"too_short", "contains_username"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := validationCodes(policy.Validate(tt.password, tt.username))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Validate(%q, %q) = %v, want %v", tt.password, tt.username, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyCharacterClasses(t *testing.T) {
+	policy := &PasswordPolicy{
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}
+
+	tests := []struct {
+		password string
+		want     []string
+	}{
+		{"Abc1!", nil},
+		{"abc1!", []string{"missing_uppercase"}},
+		{"ABC1!", []string{"missing_lowercase"}},
+		{"Abcd!", []string{"missing_digit"}},
+		{"Abc12", []string{"missing_symbol"}},
+		{"Abc 1", nil},
+		{"", []string{"missing_uppercase", "missing_lowercase", "missing_digit", "missing_symbol"}},
+	}
+
+	for _, tt := range tests {
+		got := validationCodes(policy.Validate(tt.password, ""))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Validate(%q) = %v, want %v", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestBreachedPasswords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	// SHA-1 of "password" and of "123456", in either case
+	list := "# leaked\n" +
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\n" +
+		"\n" +
+		"7c4a8d09ca3762af61e59520943dc26494f8941b\n"
+	if err := os.WriteFile(path, []byte(list), 0o600); err != nil {
+		t.Fatalf("failed to write list: %v", err)
+	}
+
+	cfg := config.Default().Password
+	cfg.BreachedPasswordsFile = path
+
+	policy, err := NewPasswordPolicyFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to create policy: %v", err)
+	}
+
+	if policy.Breached.Len() != 2 {
+		t.Fatalf("loaded %d hashes, want 2", policy.Breached.Len())
+	}
+
+	for _, password := range []string{"password", "123456"} {
+		if !policy.Breached.Contains(password) {
+			t.Errorf("%q is not reported as breached", password)
+		}
+	}
+
+	// The hashes are of the exact passwords
+	if policy.Breached.Contains("Password") {
+		t.Errorf("%q is reported as breached", "Password")
+	}
+
+	got := validationCodes(policy.Validate("password", "alice"))
+	if !reflect.DeepEqual(got, []string{"breached"}) {
+		t.Fatalf("Validate(%q) = %v, want [breached]", "password", got)
+	}
+
+	if errs := policy.Validate("plum tart 42", "alice"); errs != nil {
+		t.Fatalf("Validate(%q) = %v, want none", "plum tart 42", validationCodes(errs))
+	}
+}
+
+func TestLoadBreachedPasswordsRejectsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\npassword\n"), 0o600); err != nil {
+		t.Fatalf("failed to write list: %v", err)
+	}
+
+	if _, err := LoadBreachedPasswords(path); err == nil {
+		t.Fatal("loaded a list with a line that isn't a hash")
+	}
+}
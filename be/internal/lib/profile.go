@@ -0,0 +1,159 @@
+package lib
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A user tracks one or more people through profiles. Body details, goals,
+// logs and weigh-ins all belong to a profile. The default profile has the
+// same id as its user, so requests without a selected profile use it.
+type Profile struct {
+	Id        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	IsDefault bool      `json:"is_default"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (s *PgStore) GetProfiles(ctx context.Context, userId uuid.UUID) ([]Profile, error) {
+	profiles := []Profile{}
+
+	qStr := `
+		SELECT id, name, is_default, created_at
+		FROM user_profiles
+		WHERE u_id = $1
+		ORDER BY is_default DESC, created_at
+	`
+
+	rows, err := s.pool.Query(ctx, qStr, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var profile Profile
+		if err := rows.Scan(
+			&profile.Id,
+			&profile.Name,
+			&profile.IsDefault,
+			&profile.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		profiles = append(profiles, profile)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+func (s *PgStore) DoesProfileBelongToUser(ctx context.Context, userId uuid.UUID, profileId uuid.UUID) (*bool, error) {
+	var belongs bool
+
+	qStr := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM user_profiles
+			WHERE id = $1 AND u_id = $2
+		)`
+
+	if err := s.pool.QueryRow(ctx, qStr, profileId, userId).Scan(&belongs); err != nil {
+		return nil, err
+	}
+
+	return &belongs, nil
+}
+
+func (s *PgStore) CreateProfile(ctx context.Context, userId uuid.UUID, name string) (*uuid.UUID, error) {
+	var profileId uuid.UUID
+
+	qStr := `
+		INSERT INTO user_profiles (u_id, name)
+		VALUES ($1, $2)
+		RETURNING id`
+
+	if err := s.pool.QueryRow(ctx, qStr, userId, name).Scan(&profileId); err != nil {
+		return nil, err
+	}
+
+	return &profileId, nil
+}
+
+// RenameProfile returns false when the user has no such profile.
+func (s *PgStore) RenameProfile(ctx context.Context, userId uuid.UUID, profileId uuid.UUID, name string) (*bool, error) {
+	qStr := `
+		UPDATE user_profiles
+		SET
+			name = $3,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND u_id = $2`
+
+	tag, err := s.pool.Exec(ctx, qStr, profileId, userId, name)
+	if err != nil {
+		return nil, err
+	}
+
+	renamed := tag.RowsAffected() == 1
+
+	return &renamed, nil
+}
+
+// DeleteProfile removes a profile with everything logged for it and ends its
+// coach shares. The default profile can't be deleted, for it and unknown
+// profiles false is returned.
+func (s *PgStore) DeleteProfile(ctx context.Context, userId uuid.UUID, profileId uuid.UUID) (*bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	qStr := `
+		DELETE FROM user_profiles
+		WHERE id = $1 AND u_id = $2 AND NOT is_default`
+
+	tag, err := tx.Exec(ctx, qStr, profileId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := tag.RowsAffected() == 1
+	if !deleted {
+		return &deleted, nil
+	}
+
+	queries := []string{
+		`DELETE FROM user_caloric_balance
+		WHERE calorie_log_id IN (SELECT id FROM user_calorie_logs WHERE p_id = $1)`,
+		`DELETE FROM user_calorie_logs WHERE p_id = $1`,
+		`DELETE FROM user_body_details WHERE p_id = $1`,
+		`DELETE FROM user_weight_goal WHERE p_id = $1`,
+		`DELETE FROM user_weigh_ins WHERE p_id = $1`,
+		`DELETE FROM group_members WHERE p_id = $1`,
+		`DELETE FROM user_achievements WHERE p_id = $1`,
+		`DELETE FROM user_streaks WHERE p_id = $1`,
+		`DELETE FROM log_comment_reads
+		WHERE comment_id IN (SELECT id FROM log_comments WHERE p_id = $1)`,
+		`DELETE FROM log_comments WHERE p_id = $1`,
+		`UPDATE coach_shares SET revoked_at = CURRENT_TIMESTAMP WHERE p_id = $1 AND revoked_at IS NULL`,
+	}
+
+	for _, qStr := range queries {
+		if _, err := tx.Exec(ctx, qStr, profileId); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &deleted, nil
+}
@@ -0,0 +1,238 @@
+package lib
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+)
+
+// GenerateOpaqueToken returns a random, URL safe token. Only its hash is
+// ever stored in the database.
+func GenerateOpaqueToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueRefreshToken starts a new token family for the user, e.g. on login.
+func (s *PgStore) IssueRefreshToken(ctx context.Context, userId uuid.UUID) (string, error) {
+	token, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	qStr := `
+		INSERT INTO user_refresh_tokens (
+			u_id,
+			family_id,
+			token_hash,
+			expires_at
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			$4
+		)
+	`
+
+	if _, err := s.pool.Exec(
+		ctx,
+		qStr,
+		userId,
+		uuid.New(),
+		HashOpaqueToken(token),
+		time.Now().Add(RefreshTokenTTL),
+	); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken exchanges a refresh token for a new one in the same
+// family. Presenting a token that was already rotated is treated as theft
+// and revokes the whole family, a token revoked by logout or a password
+// change is merely invalid.
+func (s *PgStore) RotateRefreshToken(ctx context.Context, token string) (*uuid.UUID, string, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		tokenId    uuid.UUID
+		userId     uuid.UUID
+		familyId   uuid.UUID
+		expiresAt  time.Time
+		revokedAt  *time.Time
+		replacedBy *uuid.UUID
+	)
+
+	qStr := `
+		SELECT id, u_id, family_id, expires_at, revoked_at, replaced_by
+		FROM user_refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`
+
+	if err := tx.QueryRow(ctx, qStr, HashOpaqueToken(token)).Scan(
+		&tokenId,
+		&userId,
+		&familyId,
+		&expiresAt,
+		&revokedAt,
+		&replacedBy,
+	); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, "", ErrRefreshTokenInvalid
+		}
+
+		return nil, "", err
+	}
+
+	if revokedAt != nil && replacedBy == nil {
+		return nil, "", ErrRefreshTokenInvalid
+	}
+
+	if revokedAt != nil {
+		if err := revokeRefreshTokenFamily(ctx, tx, familyId); err != nil {
+			return nil, "", err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return nil, "", err
+		}
+
+		return &userId, "", ErrRefreshTokenReused
+	}
+
+	if time.Now().After(expiresAt) {
+		return nil, "", ErrRefreshTokenInvalid
+	}
+
+	newToken, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var newTokenId uuid.UUID
+
+	qStr = `
+		INSERT INTO user_refresh_tokens (
+			u_id,
+			family_id,
+			token_hash,
+			expires_at
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			$4
+		) RETURNING id
+	`
+
+	if err := tx.QueryRow(
+		ctx,
+		qStr,
+		userId,
+		familyId,
+		HashOpaqueToken(newToken),
+		time.Now().Add(RefreshTokenTTL),
+	).Scan(&newTokenId); err != nil {
+		return nil, "", err
+	}
+
+	qStr = `
+		UPDATE user_refresh_tokens
+		SET
+			revoked_at = CURRENT_TIMESTAMP,
+			replaced_by = $2
+		WHERE id = $1
+	`
+
+	if _, err := tx.Exec(ctx, qStr, tokenId, newTokenId); err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, "", err
+	}
+
+	return &userId, newToken, nil
+}
+
+// RevokeRefreshTokenFamily revokes every token issued alongside the given
+// one, e.g. on logout.
+func (s *PgStore) RevokeRefreshTokenFamily(ctx context.Context, token string) error {
+	qStr := `
+		UPDATE user_refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE revoked_at IS NULL AND family_id = (
+			SELECT family_id
+			FROM user_refresh_tokens
+			WHERE token_hash = $1
+		)
+	`
+
+	if _, err := s.pool.Exec(ctx, qStr, HashOpaqueToken(token)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RevokeUserRefreshTokens ends every session of the user, e.g. after their
+// password changed.
+func (s *PgStore) RevokeUserRefreshTokens(ctx context.Context, userId uuid.UUID) error {
+	qStr := `
+		UPDATE user_refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE u_id = $1 AND revoked_at IS NULL
+	`
+
+	if _, err := s.pool.Exec(ctx, qStr, userId); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func revokeRefreshTokenFamily(ctx context.Context, tx pgx.Tx, familyId uuid.UUID) error {
+	qStr := `
+		UPDATE user_refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`
+
+	if _, err := tx.Exec(ctx, qStr, familyId); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRotateRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSqliteStore(t)
+	userId := createTestUser(t, store, "alice")
+
+	token, err := store.IssueRefreshToken(ctx, userId)
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	gotUserId, rotated, err := store.RotateRefreshToken(ctx, token)
+	if err != nil {
+		t.Fatalf("failed to rotate token: %v", err)
+	}
+	if *gotUserId != userId {
+		t.Fatalf("rotated token for user %s, want %s", gotUserId, userId)
+	}
+	if rotated == "" || rotated == token {
+		t.Fatalf("rotation returned %q, want a new token", rotated)
+	}
+
+	if _, _, err := store.RotateRefreshToken(ctx, "not a token"); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("rotating an unknown token returned %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+}
+
+func TestRotateRefreshTokenReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSqliteStore(t)
+	userId := createTestUser(t, store, "alice")
+
+	token, err := store.IssueRefreshToken(ctx, userId)
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	_, rotated, err := store.RotateRefreshToken(ctx, token)
+	if err != nil {
+		t.Fatalf("failed to rotate token: %v", err)
+	}
+
+	// Another session of the same user must survive the revocation
+	other, err := store.IssueRefreshToken(ctx, userId)
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	if _, _, err := store.RotateRefreshToken(ctx, token); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replaying a rotated token returned %v, want %v", err, ErrRefreshTokenReused)
+	}
+
+	if _, _, err := store.RotateRefreshToken(ctx, rotated); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("rotating the stolen token's successor returned %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+
+	if _, _, err := store.RotateRefreshToken(ctx, other); err != nil {
+		t.Fatalf("failed to rotate token of another family: %v", err)
+	}
+}
+
+func TestRotateRefreshTokenAfterLogout(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSqliteStore(t)
+	userId := createTestUser(t, store, "alice")
+
+	token, err := store.IssueRefreshToken(ctx, userId)
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	if err := store.RevokeRefreshTokenFamily(ctx, token); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+
+	// A token revoked by logout is not a sign of theft
+	if _, _, err := store.RotateRefreshToken(ctx, token); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("rotating a logged out token returned %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+}
@@ -0,0 +1,197 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RoleUser  = "user"
+	RoleCoach = "coach"
+	RoleAdmin = "admin"
+)
+
+var Roles = []string{
+	RoleUser,
+	RoleCoach,
+	RoleAdmin,
+}
+
+var ErrUserDisabled = errors.New("user account is disabled")
+
+type UserStatus struct {
+	Role     string
+	Disabled bool
+}
+
+// UserSummary is what admins see of an account.
+type UserSummary struct {
+	Id                uuid.UUID  `json:"id"`
+	Name              string     `json:"name"`
+	Username          string     `json:"username"`
+	Role              string     `json:"role"`
+	DisabledAt        *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LogCount          int        `json:"log_count"`
+	CompletedLogCount int        `json:"completed_log_count"`
+}
+
+func IsValidRole(role string) bool {
+	return slices.Contains(Roles, role)
+}
+
+func (s *PgStore) GetUserStatusById(ctx context.Context, userId uuid.UUID) (*UserStatus, error) {
+	var status UserStatus
+	var disabledAt *time.Time
+
+	qStr := `
+		SELECT role, disabled_at
+		FROM users
+		WHERE id = $1`
+
+	if err := s.pool.QueryRow(
+		ctx,
+		qStr,
+		userId,
+	).Scan(&status.Role, &disabledAt); err != nil {
+		return nil, err
+	}
+
+	status.Disabled = disabledAt != nil
+
+	return &status, nil
+}
+
+// SearchUsers matches query literally against usernames and names, an empty
+// query lists everyone.
+func (s *PgStore) SearchUsers(ctx context.Context, query string, limit int, offset int) ([]UserSummary, error) {
+	qStr := `
+		SELECT
+			u.id,
+			u.name,
+			u.username,
+			u.role,
+			u.disabled_at,
+			u.created_at,
+			COUNT(l.id) AS log_count,
+			COUNT(l.id) FILTER (WHERE l.log_status = 'D') AS completed_log_count
+		FROM users u
+		LEFT JOIN user_profiles p ON p.u_id = u.id
+		LEFT JOIN user_calorie_logs l ON l.p_id = p.id
+		WHERE $1 = ''
+			OR u.username ILIKE '%' || $4 || '%' ESCAPE '\'
+			OR u.name ILIKE '%' || $4 || '%' ESCAPE '\'
+		GROUP BY u.id
+		ORDER BY u.created_at DESC
+		LIMIT $2
+		OFFSET $3
+	`
+
+	rows, err := s.pool.Query(ctx, qStr, query, limit, offset, escapeLike(query))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []UserSummary{}
+	for rows.Next() {
+		var user UserSummary
+		if err := rows.Scan(
+			&user.Id,
+			&user.Name,
+			&user.Username,
+			&user.Role,
+			&user.DisabledAt,
+			&user.CreatedAt,
+			&user.LogCount,
+			&user.CompletedLogCount,
+		); err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes LIKE match s literally, % and _ included.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+func (s *PgStore) SetUserDisabled(ctx context.Context, userId uuid.UUID, disabled bool) (*bool, error) {
+	qStr := `
+		UPDATE users
+		SET
+			disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, CURRENT_TIMESTAMP) END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+
+	tag, err := s.pool.Exec(ctx, qStr, userId, disabled)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := tag.RowsAffected() == 1
+
+	return &updated, nil
+}
+
+func (s *PgStore) SetUserRole(ctx context.Context, userId uuid.UUID, role string) (*bool, error) {
+	qStr := `
+		UPDATE users
+		SET
+			role = $2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+
+	tag, err := s.pool.Exec(ctx, qStr, userId, role)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := tag.RowsAffected() == 1
+
+	return &updated, nil
+}
+
+func SetUserRoleByUsername(ctx context.Context, users UserStore, username string, role string) (*bool, error) {
+	userId, err := users.GetUserIdByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if userId == nil {
+		updated := false
+		return &updated, nil
+	}
+
+	return users.SetUserRole(ctx, *userId, role)
+}
+
+// GetActiveUserStatus is GetUserStatusById, failing with ErrUserDisabled for
+// disabled accounts.
+func GetActiveUserStatus(ctx context.Context, users UserStore, userId uuid.UUID) (*UserStatus, error) {
+	status, err := users.GetUserStatusById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if status.Disabled {
+		return nil, ErrUserDisabled
+	}
+
+	return status, nil
+}
@@ -0,0 +1,78 @@
+package lib
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestEscapeLike(t *testing.T) {
+	tests := map[string]string{
+		"alice":   "alice",
+		"100%":    `100\%`,
+		"a_b":     `a\_b`,
+		`back\sl`: `back\\sl`,
+		`\%_`:     `\\\%\_`,
+		"":        "",
+	}
+
+	for s, want := range tests {
+		if got := escapeLike(s); got != want {
+			t.Errorf("escapeLike(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestSearchUsersMatchesLiterally(t *testing.T) {
+	stores := map[string]UserStore{
+		"memory": NewMemoryStore(),
+		"sqlite": newTestSqliteStore(t),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			for _, username := range []string{"100%_sure", "100xxsure", "a_b", "axb", `back\slash`, "Alice"} {
+				createTestUser(t, store, username)
+			}
+
+			tests := []struct {
+				query string
+				want  []string
+			}{
+				{"%", []string{"100%_sure"}},
+				{"_", []string{"100%_sure", "a_b"}},
+				{"0%_", []string{"100%_sure"}},
+				{"a_b", []string{"a_b"}},
+				{`\`, []string{`back\slash`}},
+				{"ALICE", []string{"Alice"}},
+				{"nobody", nil},
+			}
+
+			for _, tt := range tests {
+				users, err := store.SearchUsers(context.Background(), tt.query, 50, 0)
+				if err != nil {
+					t.Fatalf("failed to search users: %v", err)
+				}
+
+				var got []string
+				for _, user := range users {
+					got = append(got, user.Username)
+				}
+				slices.Sort(got)
+
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("SearchUsers(%q) = %v, want %v", tt.query, got, tt.want)
+				}
+			}
+
+			users, err := store.SearchUsers(context.Background(), "", 50, 0)
+			if err != nil {
+				t.Fatalf("failed to search users: %v", err)
+			}
+
+			if len(users) != 6 {
+				t.Fatalf("SearchUsers(\"\") returned %d users, want 6", len(users))
+			}
+		})
+	}
+}
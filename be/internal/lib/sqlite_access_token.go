@@ -0,0 +1,206 @@
+package lib
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scopes are stored as a JSON array, SQLite has no array type.
+func (s *SqliteStore) CreateAccessToken(
+	ctx context.Context,
+	userId uuid.UUID,
+	name string,
+	scopes []string,
+	expiresAt *time.Time,
+) (*uuid.UUID, string, error) {
+	secret, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	token := AccessTokenPrefix + secret
+
+	encodedScopes, err := json.Marshal(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var encodedExpiresAt *string
+	if expiresAt != nil {
+		formatted := sqliteTime(*expiresAt)
+		encodedExpiresAt = &formatted
+	}
+
+	tokenId := uuid.New()
+
+	qStr := `
+		INSERT INTO user_access_tokens (
+			id,
+			u_id,
+			name,
+			token_hash,
+			scopes,
+			expires_at
+		) VALUES (
+			?1,
+			?2,
+			?3,
+			?4,
+			?5,
+			?6
+		)
+	`
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		qStr,
+		tokenId,
+		userId,
+		name,
+		HashOpaqueToken(token),
+		string(encodedScopes),
+		encodedExpiresAt,
+	); err != nil {
+		return nil, "", sqliteError(err)
+	}
+
+	return &tokenId, token, nil
+}
+
+func (s *SqliteStore) GetAccessTokens(ctx context.Context, userId uuid.UUID) ([]UserAccessToken, error) {
+	tokens := []UserAccessToken{}
+
+	qStr := `
+		SELECT
+			id,
+			name,
+			scopes,
+			expires_at,
+			last_used_at,
+			created_at
+		FROM user_access_tokens
+		WHERE u_id = ?1 AND revoked_at IS NULL
+		ORDER BY created_at
+	`
+
+	rows, err := s.db.QueryContext(ctx, qStr, userId)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var token UserAccessToken
+		var scopes string
+		var expiresAt, lastUsedAt, createdAt sql.NullString
+
+		if err := rows.Scan(
+			&token.Id,
+			&token.Name,
+			&scopes,
+			&expiresAt,
+			&lastUsedAt,
+			&createdAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(scopes), &token.Scopes); err != nil {
+			return nil, err
+		}
+
+		if token.ExpiresAt, err = parseSqliteTime(expiresAt); err != nil {
+			return nil, err
+		}
+
+		if token.LastUsedAt, err = parseSqliteTime(lastUsedAt); err != nil {
+			return nil, err
+		}
+
+		created, err := parseSqliteTime(createdAt)
+		if err != nil {
+			return nil, err
+		}
+		token.CreatedAt = *created
+
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *SqliteStore) RevokeAccessToken(ctx context.Context, userId uuid.UUID, tokenId uuid.UUID) (*bool, error) {
+	qStr := `
+		UPDATE user_access_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = ?1 AND u_id = ?2 AND revoked_at IS NULL
+	`
+
+	result, err := s.db.ExecContext(ctx, qStr, tokenId, userId)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return sqliteRowUpdated(result)
+}
+
+// AuthenticateAccessToken looks the token up before recording its use, the
+// RETURNING clause of SQLite's UPDATE can't read the users table.
+func (s *SqliteStore) AuthenticateAccessToken(ctx context.Context, token string) (*AccessTokenAuth, error) {
+	var auth AccessTokenAuth
+
+	err := runSqliteTx(ctx, s.db, func(tx *sql.Tx) error {
+		var tokenId uuid.UUID
+		var scopes string
+
+		qStr := `
+			SELECT t.id, t.u_id, u.role, t.scopes
+			FROM user_access_tokens t
+			JOIN users u ON u.id = t.u_id
+			WHERE t.token_hash = ?1
+				AND t.revoked_at IS NULL
+				AND (t.expires_at IS NULL OR t.expires_at > CURRENT_TIMESTAMP)
+				AND u.disabled_at IS NULL
+		`
+
+		if err := tx.QueryRowContext(
+			ctx,
+			qStr,
+			HashOpaqueToken(token),
+		).Scan(&tokenId, &auth.UserId, &auth.Role, &scopes); err != nil {
+			if err == sql.ErrNoRows {
+				return ErrAccessTokenInvalid
+			}
+
+			return err
+		}
+
+		if err := json.Unmarshal([]byte(scopes), &auth.Scopes); err != nil {
+			return err
+		}
+
+		qStr = `
+			UPDATE user_access_tokens
+			SET last_used_at = CURRENT_TIMESTAMP
+			WHERE id = ?1
+		`
+
+		if _, err := tx.ExecContext(ctx, qStr, tokenId); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &auth, nil
+}
@@ -0,0 +1,339 @@
+package lib
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func loadSqliteProgressHistory(ctx context.Context, tx *sql.Tx, profileId uuid.UUID) (*ProgressHistory, error) {
+	history := &ProgressHistory{}
+
+	qStr := `
+		SELECT l.log_date, CAST(COALESCE(b.caloric_balance, 0) AS REAL)
+		FROM user_calorie_logs l
+		LEFT JOIN user_caloric_balance b ON b.calorie_log_id = l.id
+		WHERE l.p_id = ?1 AND l.log_status = 'D'
+		ORDER BY l.log_date
+	`
+
+	rows, err := tx.QueryContext(ctx, qStr, profileId)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var day CompletedDay
+		var logDate string
+
+		if err := rows.Scan(&logDate, &day.CaloricBalance); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		if day.LogDate, err = time.Parse("2006-01-02", logDate); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		history.CompletedDays = append(history.CompletedDays, day)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	qStr = `
+		SELECT weighed_on
+		FROM user_weigh_ins
+		WHERE p_id = ?1
+		ORDER BY weighed_on
+	`
+
+	rows, err = tx.QueryContext(ctx, qStr, profileId)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var weighedOn string
+		if err := rows.Scan(&weighedOn); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		day, err := time.Parse("2006-01-02", weighedOn)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		history.WeighInDays = append(history.WeighInDays, day)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	qStr = `
+		SELECT goal
+		FROM user_weight_goal
+		WHERE p_id = ?1
+	`
+
+	if err := tx.QueryRowContext(ctx, qStr, profileId).Scan(&history.Goal); err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+func saveSqliteStreaks(ctx context.Context, tx *sql.Tx, profileId uuid.UUID, streaks Streaks) error {
+	qStr := `
+		INSERT INTO user_streaks (
+			p_id,
+			current_streak,
+			longest_streak,
+			last_completed_on
+		) VALUES (
+			?1,
+			?2,
+			?3,
+			?4
+		) ON CONFLICT (p_id) DO UPDATE
+		SET
+			current_streak = ?2,
+			longest_streak = ?3,
+			last_completed_on = ?4,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	if _, err := tx.ExecContext(
+		ctx,
+		qStr,
+		profileId,
+		streaks.Current,
+		streaks.Longest,
+		streaks.LastCompletedOn,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// EvaluateAchievements needs no advisory lock like PgStore's, evaluations
+// are serialized by the database lock.
+func (s *SqliteStore) EvaluateAchievements(ctx context.Context, profileId uuid.UUID, event string) ([]Achievement, error) {
+	awarded := []Achievement{}
+
+	err := runSqliteTx(ctx, s.db, func(tx *sql.Tx) error {
+		history, err := loadSqliteProgressHistory(ctx, tx, profileId)
+		if err != nil {
+			return err
+		}
+
+		if err := saveSqliteStreaks(ctx, tx, profileId, ComputeStreaks(completedDates(history), time.Now())); err != nil {
+			return err
+		}
+
+		qStr := `
+			INSERT INTO user_achievements (
+				p_id,
+				badge,
+				earned_on
+			) VALUES (
+				?1,
+				?2,
+				?3
+			) ON CONFLICT DO NOTHING
+		`
+
+		for _, badge := range earnedBadges(history, event) {
+			result, err := tx.ExecContext(ctx, qStr, profileId, badge.rule.Badge, badge.earnedOn.Format("2006-01-02"))
+			if err != nil {
+				return err
+			}
+
+			inserted, err := sqliteRowUpdated(result)
+			if err != nil {
+				return err
+			}
+
+			if *inserted {
+				awarded = append(awarded, badge.rule.achievement(badge.earnedOn, nil))
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return awarded, nil
+}
+
+func (s *SqliteStore) RecomputeAchievements(ctx context.Context, profileId uuid.UUID) error {
+	err := runSqliteTx(ctx, s.db, func(tx *sql.Tx) error {
+		history, err := loadSqliteProgressHistory(ctx, tx, profileId)
+		if err != nil {
+			return err
+		}
+
+		if err := saveSqliteStreaks(ctx, tx, profileId, ComputeStreaks(completedDates(history), time.Now())); err != nil {
+			return err
+		}
+
+		earned := []string{}
+
+		qStr := `
+			INSERT INTO user_achievements (
+				p_id,
+				badge,
+				earned_on
+			) VALUES (
+				?1,
+				?2,
+				?3
+			) ON CONFLICT (p_id, badge) DO UPDATE
+			SET earned_on = ?3
+		`
+
+		for _, badge := range earnedBadges(history, "") {
+			if _, err := tx.ExecContext(ctx, qStr, profileId, badge.rule.Badge, badge.earnedOn.Format("2006-01-02")); err != nil {
+				return err
+			}
+
+			earned = append(earned, badge.rule.Badge)
+		}
+
+		encodedEarned, err := json.Marshal(earned)
+		if err != nil {
+			return err
+		}
+
+		qStr = `
+			DELETE FROM user_achievements
+			WHERE p_id = ?1 AND badge NOT IN (SELECT value FROM json_each(?2))
+		`
+
+		if _, err := tx.ExecContext(ctx, qStr, profileId, string(encodedEarned)); err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	return sqliteError(err)
+}
+
+func (s *SqliteStore) GetAllProfileIds(ctx context.Context) ([]uuid.UUID, error) {
+	qStr := `
+		SELECT id
+		FROM user_profiles
+		ORDER BY created_at
+	`
+
+	rows, err := s.db.QueryContext(ctx, qStr)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	defer rows.Close()
+
+	profileIds := []uuid.UUID{}
+	for rows.Next() {
+		var profileId uuid.UUID
+		if err := rows.Scan(&profileId); err != nil {
+			return nil, err
+		}
+
+		profileIds = append(profileIds, profileId)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return profileIds, nil
+}
+
+func (s *SqliteStore) GetAchievements(ctx context.Context, profileId uuid.UUID) ([]Achievement, error) {
+	awards := make(map[string]achievementAward)
+
+	qStr := `
+		SELECT badge, earned_on, awarded_at
+		FROM user_achievements
+		WHERE p_id = ?1
+	`
+
+	rows, err := s.db.QueryContext(ctx, qStr, profileId)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var badge, earnedOn, awardedAt string
+		var a achievementAward
+
+		if err := rows.Scan(&badge, &earnedOn, &awardedAt); err != nil {
+			return nil, err
+		}
+
+		if a.earnedOn, err = time.Parse("2006-01-02", earnedOn); err != nil {
+			return nil, err
+		}
+
+		if a.awardedAt, err = time.Parse(sqliteTimeLayout, awardedAt); err != nil {
+			return nil, err
+		}
+
+		awards[badge] = a
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return achievementsFromAwards(awards), nil
+}
+
+func (s *SqliteStore) GetStreaks(ctx context.Context, profileId uuid.UUID) (*Streaks, error) {
+	var streaks Streaks
+	var lastCompletedOn sql.NullString
+
+	qStr := `
+		SELECT current_streak, longest_streak, last_completed_on
+		FROM user_streaks
+		WHERE p_id = ?1
+	`
+
+	if err := s.db.QueryRowContext(ctx, qStr, profileId).Scan(
+		&streaks.Current,
+		&streaks.Longest,
+		&lastCompletedOn,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return &Streaks{}, nil
+		}
+
+		return nil, sqliteError(err)
+	}
+
+	if !lastCompletedOn.Valid {
+		return savedStreaks(streaks, nil), nil
+	}
+
+	day, err := time.Parse("2006-01-02", lastCompletedOn.String)
+	if err != nil {
+		return nil, err
+	}
+
+	return savedStreaks(streaks, &day), nil
+}
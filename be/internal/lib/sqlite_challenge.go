@@ -0,0 +1,231 @@
+package lib
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *SqliteStore) CreateChallenge(
+	ctx context.Context,
+	groupId uuid.UUID,
+	createdBy uuid.UUID,
+	name string,
+	metric string,
+	startsOn string,
+	endsOn string,
+) (*uuid.UUID, error) {
+	challengeId := uuid.New()
+
+	qStr := `
+		INSERT INTO challenges (
+			id,
+			group_id,
+			name,
+			metric,
+			starts_on,
+			ends_on,
+			created_by
+		) VALUES (
+			?1,
+			?2,
+			?3,
+			?4,
+			?5,
+			?6,
+			?7
+		)
+	`
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		qStr,
+		challengeId,
+		groupId,
+		name,
+		metric,
+		startsOn,
+		endsOn,
+		createdBy,
+	); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &challengeId, nil
+}
+
+func (s *SqliteStore) GetChallenges(ctx context.Context, groupId uuid.UUID) ([]Challenge, error) {
+	challenges := []Challenge{}
+
+	qStr := `
+		SELECT id, group_id, name, metric, starts_on, ends_on, created_at
+		FROM challenges
+		WHERE group_id = ?1
+		ORDER BY starts_on DESC, created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, qStr, groupId)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		challenge, err := scanSqliteChallenge(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		challenges = append(challenges, *challenge)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return challenges, nil
+}
+
+func (s *SqliteStore) GetChallenge(ctx context.Context, challengeId uuid.UUID) (*Challenge, error) {
+	qStr := `
+		SELECT id, group_id, name, metric, starts_on, ends_on, created_at
+		FROM challenges
+		WHERE id = ?1
+	`
+
+	challenge, err := scanSqliteChallenge(s.db.QueryRowContext(ctx, qStr, challengeId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, sqliteError(err)
+	}
+
+	return challenge, nil
+}
+
+// scanSqliteChallenge takes a *sql.Row or *sql.Rows.
+func scanSqliteChallenge(row interface{ Scan(dest ...any) error }) (*Challenge, error) {
+	var challenge Challenge
+	var createdAt string
+
+	if err := row.Scan(
+		&challenge.Id,
+		&challenge.GroupId,
+		&challenge.Name,
+		&challenge.Metric,
+		&challenge.StartsOn,
+		&challenge.EndsOn,
+		&createdAt,
+	); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if challenge.CreatedAt, err = time.Parse(sqliteTimeLayout, createdAt); err != nil {
+		return nil, err
+	}
+
+	return &challenge, nil
+}
+
+// sqliteLeaderboardQueries match leaderboardQueries, with correlated
+// subqueries where Postgres uses lateral joins.
+var sqliteLeaderboardQueries = map[string]string{
+	ChallengeMetricAdherence: `
+		SELECT p.id, p.name, CAST(COUNT(l.id) AS REAL)
+		FROM group_members m
+		JOIN user_profiles p ON p.id = m.p_id
+		LEFT JOIN user_calorie_logs l
+			ON l.p_id = m.p_id
+			AND l.log_status = 'D'
+			AND l.log_date BETWEEN ?2 AND ?3
+		WHERE m.group_id = ?1
+		GROUP BY p.id, p.name
+	`,
+	ChallengeMetricBalance: `
+		SELECT p.id, p.name, CAST(COALESCE(SUM(b.caloric_balance), 0) AS REAL)
+		FROM group_members m
+		JOIN user_profiles p ON p.id = m.p_id
+		LEFT JOIN user_calorie_logs l
+			ON l.p_id = m.p_id
+			AND l.log_status = 'D'
+			AND l.log_date BETWEEN ?2 AND ?3
+		LEFT JOIN user_caloric_balance b ON b.calorie_log_id = l.id
+		WHERE m.group_id = ?1
+		GROUP BY p.id, p.name
+	`,
+	ChallengeMetricWeightChange: `
+		SELECT
+			id,
+			name,
+			CASE WHEN last_on > first_on
+				THEN (first_kg - last_kg) / first_kg * 100
+			END
+		FROM (
+			SELECT
+				p.id,
+				p.name,
+				first_w.weighed_on AS first_on,
+				first_w.weight_kg AS first_kg,
+				last_w.weighed_on AS last_on,
+				last_w.weight_kg AS last_kg
+			FROM group_members m
+			JOIN user_profiles p ON p.id = m.p_id
+			LEFT JOIN user_weigh_ins first_w ON first_w.id = (
+				SELECT id
+				FROM user_weigh_ins
+				WHERE p_id = m.p_id AND weighed_on <= ?3
+				ORDER BY
+					CASE WHEN weighed_on <= ?2 THEN 0 ELSE 1 END,
+					ABS(julianday(weighed_on) - julianday(?2))
+				LIMIT 1
+			)
+			LEFT JOIN user_weigh_ins last_w ON last_w.id = (
+				SELECT id
+				FROM user_weigh_ins
+				WHERE p_id = m.p_id AND weighed_on <= ?3
+				ORDER BY weighed_on DESC
+				LIMIT 1
+			)
+			WHERE m.group_id = ?1
+		)
+	`,
+}
+
+func (s *SqliteStore) GetChallengeScores(ctx context.Context, challenge Challenge, viewerProfileId uuid.UUID) ([]LeaderboardEntry, error) {
+	entries := []LeaderboardEntry{}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		sqliteLeaderboardQueries[challenge.Metric],
+		challenge.GroupId,
+		challenge.StartsOn,
+		challenge.EndsOn,
+	)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry LeaderboardEntry
+		var profileId uuid.UUID
+
+		if err := rows.Scan(&profileId, &entry.Name, &entry.Score); err != nil {
+			return nil, err
+		}
+
+		entry.IsSelf = profileId == viewerProfileId
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,257 @@
+package lib
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *SqliteStore) InviteCoach(ctx context.Context, clientId uuid.UUID, profileId uuid.UUID, coachUsername string) (*uuid.UUID, error) {
+	shareId := uuid.New()
+
+	qStr := `
+		INSERT INTO coach_shares (
+			id,
+			client_id,
+			p_id,
+			coach_id
+		)
+		SELECT ?4, ?1, ?2, id
+		FROM users
+		WHERE username = ?3
+			AND role = 'coach'
+			AND disabled_at IS NULL
+			AND id <> ?1
+	`
+
+	result, err := s.db.ExecContext(ctx, qStr, clientId, profileId, coachUsername, shareId)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	invited, err := sqliteRowUpdated(result)
+	if err != nil {
+		return nil, err
+	}
+
+	if !*invited {
+		return nil, ErrCoachNotFound
+	}
+
+	return &shareId, nil
+}
+
+func (s *SqliteStore) GetClientShares(ctx context.Context, clientId uuid.UUID) ([]CoachShare, error) {
+	return s.getCoachShares(ctx, `s.client_id = ?1`, clientId)
+}
+
+func (s *SqliteStore) GetCoachShares(ctx context.Context, coachId uuid.UUID) ([]CoachShare, error) {
+	return s.getCoachShares(ctx, `s.coach_id = ?1`, coachId)
+}
+
+func (s *SqliteStore) getCoachShares(ctx context.Context, condition string, userId uuid.UUID) ([]CoachShare, error) {
+	shares := []CoachShare{}
+
+	qStr := `
+		SELECT
+			s.id,
+			s.client_id,
+			client.name,
+			client.username,
+			s.p_id,
+			profile.name,
+			s.coach_id,
+			coach.name,
+			coach.username,
+			s.created_at,
+			s.accepted_at
+		FROM coach_shares s
+		JOIN users client ON client.id = s.client_id
+		JOIN users coach ON coach.id = s.coach_id
+		JOIN user_profiles profile ON profile.id = s.p_id
+		WHERE ` + condition + ` AND s.revoked_at IS NULL
+		ORDER BY s.created_at
+	`
+
+	rows, err := s.db.QueryContext(ctx, qStr, userId)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var share CoachShare
+		var createdAt string
+		var acceptedAt sql.NullString
+
+		if err := rows.Scan(
+			&share.Id,
+			&share.ClientId,
+			&share.ClientName,
+			&share.ClientUsername,
+			&share.ProfileId,
+			&share.ProfileName,
+			&share.CoachId,
+			&share.CoachName,
+			&share.CoachUsername,
+			&createdAt,
+			&acceptedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if share.CreatedAt, err = time.Parse(sqliteTimeLayout, createdAt); err != nil {
+			return nil, err
+		}
+
+		if share.AcceptedAt, err = parseSqliteTime(acceptedAt); err != nil {
+			return nil, err
+		}
+
+		shares = append(shares, share)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+func (s *SqliteStore) AcceptCoachInvite(ctx context.Context, coachId uuid.UUID, shareId uuid.UUID) (*bool, error) {
+	qStr := `
+		UPDATE coach_shares
+		SET accepted_at = CURRENT_TIMESTAMP
+		WHERE id = ?1
+			AND coach_id = ?2
+			AND accepted_at IS NULL
+			AND revoked_at IS NULL
+	`
+
+	result, err := s.db.ExecContext(ctx, qStr, shareId, coachId)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return sqliteRowUpdated(result)
+}
+
+func (s *SqliteStore) RevokeCoachShare(ctx context.Context, userId uuid.UUID, shareId uuid.UUID) (*bool, error) {
+	qStr := `
+		UPDATE coach_shares
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = ?1
+			AND (client_id = ?2 OR coach_id = ?2)
+			AND revoked_at IS NULL
+	`
+
+	result, err := s.db.ExecContext(ctx, qStr, shareId, userId)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return sqliteRowUpdated(result)
+}
+
+func (s *SqliteStore) GetActiveShare(ctx context.Context, coachId uuid.UUID, profileId uuid.UUID) (*ActiveCoachShare, error) {
+	var share ActiveCoachShare
+
+	qStr := `
+		SELECT id, client_id
+		FROM coach_shares
+		WHERE coach_id = ?1
+			AND p_id = ?2
+			AND accepted_at IS NOT NULL
+			AND revoked_at IS NULL
+	`
+
+	if err := s.db.QueryRowContext(
+		ctx,
+		qStr,
+		coachId,
+		profileId,
+	).Scan(&share.Id, &share.ClientId); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, sqliteError(err)
+	}
+
+	return &share, nil
+}
+
+func (s *SqliteStore) RecordCoachAccess(ctx context.Context, shareId uuid.UUID, resource string) error {
+	qStr := `
+		INSERT INTO coach_access_log (
+			id,
+			share_id,
+			coach_id,
+			client_id,
+			p_id,
+			resource
+		)
+		SELECT ?3, id, coach_id, client_id, p_id, ?2
+		FROM coach_shares
+		WHERE id = ?1
+	`
+
+	if _, err := s.db.ExecContext(ctx, qStr, shareId, resource, uuid.New()); err != nil {
+		return sqliteError(err)
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) GetCoachAccessLog(ctx context.Context, clientId uuid.UUID, limit int) ([]CoachAccess, error) {
+	accesses := []CoachAccess{}
+
+	qStr := `
+		SELECT
+			a.coach_id,
+			a.p_id,
+			u.username,
+			a.resource,
+			a.accessed_at
+		FROM coach_access_log a
+		JOIN users u ON u.id = a.coach_id
+		WHERE a.client_id = ?1
+		ORDER BY a.accessed_at DESC
+		LIMIT ?2
+	`
+
+	rows, err := s.db.QueryContext(ctx, qStr, clientId, limit)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var access CoachAccess
+		var accessedAt string
+
+		if err := rows.Scan(
+			&access.CoachId,
+			&access.ProfileId,
+			&access.CoachUsername,
+			&access.Resource,
+			&accessedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if access.AccessedAt, err = time.Parse(sqliteTimeLayout, accessedAt); err != nil {
+			return nil, err
+		}
+
+		accesses = append(accesses, access)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return accesses, nil
+}
@@ -0,0 +1,189 @@
+package lib
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *SqliteStore) CreateGroup(ctx context.Context, ownerId uuid.UUID, profileId uuid.UUID, name string) (*uuid.UUID, error) {
+	groupId := uuid.New()
+
+	err := runSqliteTx(ctx, s.db, func(tx *sql.Tx) error {
+		qStr := `
+			INSERT INTO groups (id, name, invite_code, owner_id)
+			VALUES (?1, ?2, ?3, ?4)
+			ON CONFLICT (invite_code) DO NOTHING`
+
+		// Retry the unlikely invite code collisions
+		for attempt := 0; ; attempt++ {
+			inviteCode, err := generateInviteCode()
+			if err != nil {
+				return err
+			}
+
+			result, err := tx.ExecContext(ctx, qStr, groupId, name, inviteCode, ownerId)
+			if err != nil {
+				return err
+			}
+
+			created, err := sqliteRowUpdated(result)
+			if err != nil {
+				return err
+			}
+
+			if *created {
+				break
+			}
+
+			if attempt == 2 {
+				return errors.New("failed to generate a unique invite code")
+			}
+		}
+
+		qStr = `
+			INSERT INTO group_members (group_id, p_id)
+			VALUES (?1, ?2)`
+
+		if _, err := tx.ExecContext(ctx, qStr, groupId, profileId); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &groupId, nil
+}
+
+func (s *SqliteStore) JoinGroup(ctx context.Context, profileId uuid.UUID, inviteCode string) (*uuid.UUID, error) {
+	var groupId uuid.UUID
+
+	qStr := `
+		SELECT id
+		FROM groups
+		WHERE invite_code = UPPER(?1)`
+
+	if err := s.db.QueryRowContext(ctx, qStr, inviteCode).Scan(&groupId); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrGroupNotFound
+		}
+
+		return nil, sqliteError(err)
+	}
+
+	qStr = `
+		INSERT INTO group_members (group_id, p_id)
+		VALUES (?1, ?2)
+		ON CONFLICT DO NOTHING`
+
+	if _, err := s.db.ExecContext(ctx, qStr, groupId, profileId); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &groupId, nil
+}
+
+func (s *SqliteStore) GetGroups(ctx context.Context, userId uuid.UUID, profileId uuid.UUID) ([]Group, error) {
+	groups := []Group{}
+
+	qStr := `
+		SELECT
+			g.id,
+			g.name,
+			g.invite_code,
+			g.owner_id = ?1,
+			(SELECT COUNT(*) FROM group_members WHERE group_id = g.id),
+			g.created_at
+		FROM groups g
+		JOIN group_members m ON m.group_id = g.id
+		WHERE m.p_id = ?2
+		ORDER BY g.created_at
+	`
+
+	rows, err := s.db.QueryContext(ctx, qStr, userId, profileId)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var group Group
+		var createdAt string
+
+		if err := rows.Scan(
+			&group.Id,
+			&group.Name,
+			&group.InviteCode,
+			&group.IsOwner,
+			&group.MemberCount,
+			&createdAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if group.CreatedAt, err = time.Parse(sqliteTimeLayout, createdAt); err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func (s *SqliteStore) LeaveGroup(ctx context.Context, groupId uuid.UUID, profileId uuid.UUID) (*bool, error) {
+	qStr := `
+		DELETE FROM group_members
+		WHERE group_id = ?1 AND p_id = ?2`
+
+	result, err := s.db.ExecContext(ctx, qStr, groupId, profileId)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return sqliteRowUpdated(result)
+}
+
+func (s *SqliteStore) IsGroupMember(ctx context.Context, groupId uuid.UUID, profileId uuid.UUID) (*bool, error) {
+	var isMember bool
+
+	qStr := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM group_members
+			WHERE group_id = ?1 AND p_id = ?2
+		)`
+
+	if err := s.db.QueryRowContext(ctx, qStr, groupId, profileId).Scan(&isMember); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &isMember, nil
+}
+
+func (s *SqliteStore) IsGroupOwner(ctx context.Context, groupId uuid.UUID, userId uuid.UUID) (*bool, error) {
+	var isOwner bool
+
+	qStr := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM groups
+			WHERE id = ?1 AND owner_id = ?2
+		)`
+
+	if err := s.db.QueryRowContext(ctx, qStr, groupId, userId).Scan(&isOwner); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &isOwner, nil
+}
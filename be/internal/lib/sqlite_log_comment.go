@@ -0,0 +1,300 @@
+package lib
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *SqliteStore) CreateLogComment(
+	ctx context.Context,
+	clientId uuid.UUID,
+	profileId uuid.UUID,
+	coachId uuid.UUID,
+	authorId uuid.UUID,
+	target LogCommentTarget,
+	body string,
+) (*uuid.UUID, error) {
+	calorieLogId, weekStart, err := resolveCommentTarget(ctx, s, profileId, target)
+	if err != nil {
+		return nil, err
+	}
+
+	commentId := uuid.New()
+
+	qStr := `
+		INSERT INTO log_comments (
+			id,
+			client_id,
+			p_id,
+			coach_id,
+			calorie_log_id,
+			week_start,
+			author_id,
+			body
+		)
+		SELECT ?8, ?1, ?2, ?3, ?4, ?5, ?6, ?7
+		WHERE EXISTS (
+			SELECT 1
+			FROM coach_shares
+			WHERE client_id = ?1
+				AND p_id = ?2
+				AND coach_id = ?3
+				AND accepted_at IS NOT NULL
+				AND revoked_at IS NULL
+		)
+	`
+
+	result, err := s.db.ExecContext(
+		ctx,
+		qStr,
+		clientId,
+		profileId,
+		coachId,
+		calorieLogId,
+		weekStart,
+		authorId,
+		body,
+		commentId,
+	)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	created, err := sqliteRowUpdated(result)
+	if err != nil {
+		return nil, err
+	}
+
+	if !*created {
+		return nil, ErrNoCoachShare
+	}
+
+	return &commentId, nil
+}
+
+func (s *SqliteStore) ReplyToLogComment(
+	ctx context.Context,
+	profileId uuid.UUID,
+	authorId uuid.UUID,
+	parentId uuid.UUID,
+	body string,
+) (*uuid.UUID, error) {
+	commentId := uuid.New()
+
+	qStr := `
+		INSERT INTO log_comments (
+			id,
+			client_id,
+			p_id,
+			coach_id,
+			calorie_log_id,
+			week_start,
+			parent_id,
+			author_id,
+			body
+		)
+		SELECT
+			?5,
+			p.client_id,
+			p.p_id,
+			p.coach_id,
+			p.calorie_log_id,
+			p.week_start,
+			COALESCE(p.parent_id, p.id),
+			?3,
+			?4
+		FROM log_comments p
+		JOIN coach_shares s
+			ON s.p_id = p.p_id
+			AND s.coach_id = p.coach_id
+			AND s.accepted_at IS NOT NULL
+			AND s.revoked_at IS NULL
+		WHERE p.id = ?1
+			AND p.p_id = ?2
+			AND ?3 IN (p.client_id, p.coach_id)
+	`
+
+	result, err := s.db.ExecContext(ctx, qStr, parentId, profileId, authorId, body, commentId)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	replied, err := sqliteRowUpdated(result)
+	if err != nil {
+		return nil, err
+	}
+
+	if !*replied {
+		return nil, ErrCommentNotFound
+	}
+
+	return &commentId, nil
+}
+
+func (s *SqliteStore) GetLogComments(ctx context.Context, profileId uuid.UUID, viewerId uuid.UUID, filter LogCommentFilter) ([]LogComment, error) {
+	comments := []LogComment{}
+
+	weekStart := filter.WeekStart
+	if weekStart != "" {
+		start, err := WeekStartOf(weekStart)
+		if err != nil {
+			return nil, err
+		}
+		weekStart = start
+	}
+
+	qStr := `
+		SELECT
+			c.id,
+			c.client_id,
+			c.p_id,
+			c.coach_id,
+			l.log_date,
+			c.week_start,
+			c.parent_id,
+			c.author_id,
+			u.username,
+			c.body,
+			c.created_at,
+			(c.author_id = ?2 OR r.comment_id IS NOT NULL) AS read
+		FROM log_comments c
+		JOIN users u ON u.id = c.author_id
+		LEFT JOIN user_calorie_logs l ON l.id = c.calorie_log_id
+		LEFT JOIN log_comment_reads r ON r.comment_id = c.id AND r.u_id = ?2
+		WHERE c.p_id = ?1
+			AND (?3 IS NULL OR c.coach_id = ?3)
+			AND (?4 = '' OR l.log_date = ?4)
+			AND (?5 = '' OR c.week_start = ?5)
+			AND (NOT ?6 OR (c.author_id <> ?2 AND r.comment_id IS NULL))
+		ORDER BY c.created_at
+	`
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		qStr,
+		profileId,
+		viewerId,
+		filter.CoachId,
+		filter.LogDate,
+		weekStart,
+		filter.UnreadOnly,
+	)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var comment LogComment
+		var logDate, weekStart sql.NullString
+		var createdAt string
+
+		if err := rows.Scan(
+			&comment.Id,
+			&comment.ClientId,
+			&comment.ProfileId,
+			&comment.CoachId,
+			&logDate,
+			&weekStart,
+			&comment.ParentId,
+			&comment.AuthorId,
+			&comment.AuthorUsername,
+			&comment.Body,
+			&createdAt,
+			&comment.Read,
+		); err != nil {
+			return nil, err
+		}
+
+		if logDate.Valid {
+			comment.LogDate = &logDate.String
+		}
+
+		if weekStart.Valid {
+			comment.WeekStart = &weekStart.String
+		}
+
+		if comment.CreatedAt, err = time.Parse(sqliteTimeLayout, createdAt); err != nil {
+			return nil, err
+		}
+
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+func (s *SqliteStore) GetUnreadCommentCounts(ctx context.Context, userId uuid.UUID) ([]UnreadCommentCount, error) {
+	counts := []UnreadCommentCount{}
+
+	qStr := `
+		SELECT c.client_id, c.p_id, c.coach_id, COUNT(*)
+		FROM log_comments c
+		JOIN coach_shares s
+			ON s.p_id = c.p_id
+			AND s.coach_id = c.coach_id
+			AND s.accepted_at IS NOT NULL
+			AND s.revoked_at IS NULL
+		LEFT JOIN log_comment_reads r ON r.comment_id = c.id AND r.u_id = ?1
+		WHERE ?1 IN (c.client_id, c.coach_id)
+			AND c.author_id <> ?1
+			AND r.comment_id IS NULL
+		GROUP BY c.client_id, c.p_id, c.coach_id
+	`
+
+	rows, err := s.db.QueryContext(ctx, qStr, userId)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var count UnreadCommentCount
+		if err := rows.Scan(&count.ClientId, &count.ProfileId, &count.CoachId, &count.Unread); err != nil {
+			return nil, err
+		}
+
+		counts = append(counts, count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// MarkLogCommentsRead passes the ids as a JSON array, SQLite has no array
+// parameters.
+func (s *SqliteStore) MarkLogCommentsRead(ctx context.Context, userId uuid.UUID, commentIds []uuid.UUID) error {
+	encodedIds, err := json.Marshal(commentIds)
+	if err != nil {
+		return err
+	}
+
+	qStr := `
+		INSERT INTO log_comment_reads (
+			comment_id,
+			u_id
+		)
+		SELECT id, ?1
+		FROM log_comments
+		WHERE id IN (SELECT value FROM json_each(?2))
+			AND ?1 IN (client_id, coach_id)
+		ON CONFLICT DO NOTHING
+	`
+
+	if _, err := s.db.ExecContext(ctx, qStr, userId, string(encodedIds)); err != nil {
+		return sqliteError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package lib
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *SqliteStore) GetProfiles(ctx context.Context, userId uuid.UUID) ([]Profile, error) {
+	profiles := []Profile{}
+
+	qStr := `
+		SELECT id, name, is_default, created_at
+		FROM user_profiles
+		WHERE u_id = ?1
+		ORDER BY is_default DESC, created_at
+	`
+
+	rows, err := s.db.QueryContext(ctx, qStr, userId)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var profile Profile
+		var createdAt string
+
+		if err := rows.Scan(
+			&profile.Id,
+			&profile.Name,
+			&profile.IsDefault,
+			&createdAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if profile.CreatedAt, err = time.Parse(sqliteTimeLayout, createdAt); err != nil {
+			return nil, err
+		}
+
+		profiles = append(profiles, profile)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+func (s *SqliteStore) DoesProfileBelongToUser(ctx context.Context, userId uuid.UUID, profileId uuid.UUID) (*bool, error) {
+	var belongs bool
+
+	qStr := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM user_profiles
+			WHERE id = ?1 AND u_id = ?2
+		)`
+
+	if err := s.db.QueryRowContext(ctx, qStr, profileId, userId).Scan(&belongs); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &belongs, nil
+}
+
+func (s *SqliteStore) CreateProfile(ctx context.Context, userId uuid.UUID, name string) (*uuid.UUID, error) {
+	profileId := uuid.New()
+
+	qStr := `
+		INSERT INTO user_profiles (id, u_id, name)
+		VALUES (?1, ?2, ?3)`
+
+	if _, err := s.db.ExecContext(ctx, qStr, profileId, userId, name); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &profileId, nil
+}
+
+func (s *SqliteStore) RenameProfile(ctx context.Context, userId uuid.UUID, profileId uuid.UUID, name string) (*bool, error) {
+	qStr := `
+		UPDATE user_profiles
+		SET
+			name = ?3,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?1 AND u_id = ?2`
+
+	result, err := s.db.ExecContext(ctx, qStr, profileId, userId, name)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return sqliteRowUpdated(result)
+}
+
+func (s *SqliteStore) DeleteProfile(ctx context.Context, userId uuid.UUID, profileId uuid.UUID) (*bool, error) {
+	var deleted *bool
+
+	err := runSqliteTx(ctx, s.db, func(tx *sql.Tx) error {
+		qStr := `
+			DELETE FROM user_profiles
+			WHERE id = ?1 AND u_id = ?2 AND NOT is_default`
+
+		result, err := tx.ExecContext(ctx, qStr, profileId, userId)
+		if err != nil {
+			return err
+		}
+
+		deleted, err = sqliteRowUpdated(result)
+		if err != nil || !*deleted {
+			return err
+		}
+
+		queries := []string{
+			`DELETE FROM user_caloric_balance
+			WHERE calorie_log_id IN (SELECT id FROM user_calorie_logs WHERE p_id = ?1)`,
+			`DELETE FROM user_calorie_logs WHERE p_id = ?1`,
+			`DELETE FROM user_body_details WHERE p_id = ?1`,
+			`DELETE FROM user_weight_goal WHERE p_id = ?1`,
+			`DELETE FROM user_weigh_ins WHERE p_id = ?1`,
+			`DELETE FROM group_members WHERE p_id = ?1`,
+			`DELETE FROM user_achievements WHERE p_id = ?1`,
+			`DELETE FROM user_streaks WHERE p_id = ?1`,
+			`DELETE FROM log_comment_reads
+			WHERE comment_id IN (SELECT id FROM log_comments WHERE p_id = ?1)`,
+			`DELETE FROM log_comments WHERE p_id = ?1`,
+			`UPDATE coach_shares SET revoked_at = CURRENT_TIMESTAMP WHERE p_id = ?1 AND revoked_at IS NULL`,
+		}
+
+		for _, qStr := range queries {
+			if _, err := tx.ExecContext(ctx, qStr, profileId); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return deleted, nil
+}
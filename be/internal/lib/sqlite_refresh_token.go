@@ -0,0 +1,198 @@
+package lib
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *SqliteStore) IssueRefreshToken(ctx context.Context, userId uuid.UUID) (string, error) {
+	token, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	qStr := `
+		INSERT INTO user_refresh_tokens (
+			id,
+			u_id,
+			family_id,
+			token_hash,
+			expires_at
+		) VALUES (
+			?1,
+			?2,
+			?3,
+			?4,
+			?5
+		)
+	`
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		qStr,
+		uuid.New(),
+		userId,
+		uuid.New(),
+		HashOpaqueToken(token),
+		sqliteTime(time.Now().Add(RefreshTokenTTL)),
+	); err != nil {
+		return "", sqliteError(err)
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken needs no row lock like PgStore's, the transaction holds
+// the database's write lock from the start.
+func (s *SqliteStore) RotateRefreshToken(ctx context.Context, token string) (*uuid.UUID, string, error) {
+	var userId uuid.UUID
+	var newToken string
+
+	err := runSqliteTx(ctx, s.db, func(tx *sql.Tx) error {
+		var (
+			tokenId    uuid.UUID
+			familyId   uuid.UUID
+			expiresAt  string
+			revokedAt  sql.NullString
+			replacedBy sql.NullString
+		)
+
+		qStr := `
+			SELECT id, u_id, family_id, expires_at, revoked_at, replaced_by
+			FROM user_refresh_tokens
+			WHERE token_hash = ?1
+		`
+
+		if err := tx.QueryRowContext(ctx, qStr, HashOpaqueToken(token)).Scan(
+			&tokenId,
+			&userId,
+			&familyId,
+			&expiresAt,
+			&revokedAt,
+			&replacedBy,
+		); err != nil {
+			if err == sql.ErrNoRows {
+				return ErrRefreshTokenInvalid
+			}
+
+			return err
+		}
+
+		if revokedAt.Valid && !replacedBy.Valid {
+			return ErrRefreshTokenInvalid
+		}
+
+		if revokedAt.Valid {
+			qStr = `
+				UPDATE user_refresh_tokens
+				SET revoked_at = CURRENT_TIMESTAMP
+				WHERE family_id = ?1 AND revoked_at IS NULL
+			`
+
+			if _, err := tx.ExecContext(ctx, qStr, familyId); err != nil {
+				return err
+			}
+
+			// Commits the revocation, the reuse is reported below
+			return nil
+		}
+
+		if expiresAt <= sqliteTime(time.Now()) {
+			return ErrRefreshTokenInvalid
+		}
+
+		var err error
+		newToken, err = GenerateOpaqueToken()
+		if err != nil {
+			return err
+		}
+
+		newTokenId := uuid.New()
+
+		qStr = `
+			INSERT INTO user_refresh_tokens (
+				id,
+				u_id,
+				family_id,
+				token_hash,
+				expires_at
+			) VALUES (
+				?1,
+				?2,
+				?3,
+				?4,
+				?5
+			)
+		`
+
+		if _, err := tx.ExecContext(
+			ctx,
+			qStr,
+			newTokenId,
+			userId,
+			familyId,
+			HashOpaqueToken(newToken),
+			sqliteTime(time.Now().Add(RefreshTokenTTL)),
+		); err != nil {
+			return err
+		}
+
+		qStr = `
+			UPDATE user_refresh_tokens
+			SET
+				revoked_at = CURRENT_TIMESTAMP,
+				replaced_by = ?2
+			WHERE id = ?1
+		`
+
+		if _, err := tx.ExecContext(ctx, qStr, tokenId, newTokenId); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, "", sqliteError(err)
+	}
+
+	if newToken == "" {
+		return &userId, "", ErrRefreshTokenReused
+	}
+
+	return &userId, newToken, nil
+}
+
+func (s *SqliteStore) RevokeRefreshTokenFamily(ctx context.Context, token string) error {
+	qStr := `
+		UPDATE user_refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE revoked_at IS NULL AND family_id = (
+			SELECT family_id
+			FROM user_refresh_tokens
+			WHERE token_hash = ?1
+		)
+	`
+
+	if _, err := s.db.ExecContext(ctx, qStr, HashOpaqueToken(token)); err != nil {
+		return sqliteError(err)
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) RevokeUserRefreshTokens(ctx context.Context, userId uuid.UUID) error {
+	qStr := `
+		UPDATE user_refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE u_id = ?1 AND revoked_at IS NULL
+	`
+
+	if _, err := s.db.ExecContext(ctx, qStr, userId); err != nil {
+		return sqliteError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,936 @@
+package lib
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteTimeLayout is how CURRENT_TIMESTAMP stores time, always UTC.
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
+// SqliteStore keeps everything PgStore does in a single SQLite file for
+// self-hosting. Its errors match PgStore's, missing rows are pgx.ErrNoRows
+// and duplicates a 23505 *pgconn.PgError, so handlers don't tell them apart.
+type SqliteStore struct {
+	db *sql.DB
+}
+
+var _ Store = (*SqliteStore)(nil)
+
+func NewSqliteStore(db *sql.DB) *SqliteStore {
+	return &SqliteStore{db: db}
+}
+
+// sqliteError translates SQLite errors into the ones Postgres returns.
+func sqliteError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return pgx.ErrNoRows
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			// "UNIQUE constraint failed: users.username (2067)"
+			_, constraint, _ := strings.Cut(sqliteErr.Error(), "failed: ")
+			constraint, _, _ = strings.Cut(constraint, " (")
+			return uniqueViolation(constraint)
+		}
+	}
+
+	return err
+}
+
+// sqliteTime formats t like CURRENT_TIMESTAMP, so the two compare as text.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+// parseSqliteTime reads a nullable timestamp column, nil for NULL.
+func parseSqliteTime(value sql.NullString) (*time.Time, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+
+	t, err := time.Parse(sqliteTimeLayout, value.String)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// runSqliteTx runs fn in a transaction, committing when it returns nil.
+func runSqliteTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SqliteStore) DoesUserExists(ctx context.Context, username string) (*bool, error) {
+	qStr := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM users
+			WHERE username = ?1
+		)`
+
+	var doesExist bool
+	if err := s.db.QueryRowContext(ctx, qStr, username).Scan(&doesExist); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &doesExist, nil
+}
+
+func (s *SqliteStore) GetUserIdByUsername(ctx context.Context, username string) (*uuid.UUID, error) {
+	var userId uuid.UUID
+
+	qStr := `
+		SELECT id
+		FROM users
+		WHERE username = ?1`
+
+	if err := s.db.QueryRowContext(ctx, qStr, username).Scan(&userId); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, sqliteError(err)
+	}
+
+	return &userId, nil
+}
+
+// CreateUser also creates the default profile sharing the user's id, like
+// PgStore does in a single statement.
+func (s *SqliteStore) CreateUser(ctx context.Context, name string, username string, passwordHash string) (*uuid.UUID, error) {
+	userId := uuid.New()
+
+	err := runSqliteTx(ctx, s.db, func(tx *sql.Tx) error {
+		return insertSqliteUser(ctx, tx, userId, name, username, passwordHash)
+	})
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &userId, nil
+}
+
+func insertSqliteUser(ctx context.Context, tx *sql.Tx, userId uuid.UUID, name string, username string, passwordHash string) error {
+	qStr := `
+		INSERT INTO users (id, name, username, password_hash)
+		VALUES (?1, ?2, ?3, ?4)`
+
+	if _, err := tx.ExecContext(ctx, qStr, userId, name, username, passwordHash); err != nil {
+		return err
+	}
+
+	qStr = `
+		INSERT INTO user_profiles (id, u_id, name, is_default)
+		VALUES (?1, ?1, ?2, 1)`
+
+	if _, err := tx.ExecContext(ctx, qStr, userId, name); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) GetUsernameById(ctx context.Context, userId uuid.UUID) (*string, error) {
+	var username string
+
+	qStr := `
+		SELECT username
+		FROM users
+		WHERE id = ?1`
+
+	if err := s.db.QueryRowContext(ctx, qStr, userId).Scan(&username); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &username, nil
+}
+
+func (s *SqliteStore) GetPasswordHash(ctx context.Context, username string) (*string, error) {
+	var passwordHash string
+
+	qStr := `
+		SELECT password_hash
+		FROM users
+		WHERE username = ?1`
+
+	if err := s.db.QueryRowContext(ctx, qStr, username).Scan(&passwordHash); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &passwordHash, nil
+}
+
+func (s *SqliteStore) GetUserStatusById(ctx context.Context, userId uuid.UUID) (*UserStatus, error) {
+	var status UserStatus
+	var disabledAt sql.NullString
+
+	qStr := `
+		SELECT role, disabled_at
+		FROM users
+		WHERE id = ?1`
+
+	if err := s.db.QueryRowContext(ctx, qStr, userId).Scan(&status.Role, &disabledAt); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	status.Disabled = disabledAt.Valid
+
+	return &status, nil
+}
+
+func (s *SqliteStore) SetUserDisabled(ctx context.Context, userId uuid.UUID, disabled bool) (*bool, error) {
+	qStr := `
+		UPDATE users
+		SET
+			disabled_at = CASE WHEN ?2 THEN COALESCE(disabled_at, CURRENT_TIMESTAMP) END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?1`
+
+	result, err := s.db.ExecContext(ctx, qStr, userId, disabled)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return sqliteRowUpdated(result)
+}
+
+func (s *SqliteStore) SetUserRole(ctx context.Context, userId uuid.UUID, role string) (*bool, error) {
+	qStr := `
+		UPDATE users
+		SET
+			role = ?2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?1`
+
+	result, err := s.db.ExecContext(ctx, qStr, userId, role)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return sqliteRowUpdated(result)
+}
+
+// SearchUsers matches like PgStore's, SQLite's LIKE already ignores the
+// case of ASCII letters.
+func (s *SqliteStore) SearchUsers(ctx context.Context, query string, limit int, offset int) ([]UserSummary, error) {
+	qStr := `
+		SELECT
+			u.id,
+			u.name,
+			u.username,
+			u.role,
+			u.disabled_at,
+			u.created_at,
+			COUNT(l.id) AS log_count,
+			COUNT(l.id) FILTER (WHERE l.log_status = 'D') AS completed_log_count
+		FROM users u
+		LEFT JOIN user_profiles p ON p.u_id = u.id
+		LEFT JOIN user_calorie_logs l ON l.p_id = p.id
+		WHERE ?1 = ''
+			OR u.username LIKE '%' || ?4 || '%' ESCAPE '\'
+			OR u.name LIKE '%' || ?4 || '%' ESCAPE '\'
+		GROUP BY u.id
+		ORDER BY u.created_at DESC
+		LIMIT ?2
+		OFFSET ?3
+	`
+
+	rows, err := s.db.QueryContext(ctx, qStr, query, limit, offset, escapeLike(query))
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	defer rows.Close()
+
+	users := []UserSummary{}
+	for rows.Next() {
+		var user UserSummary
+		var disabledAt, createdAt sql.NullString
+
+		if err := rows.Scan(
+			&user.Id,
+			&user.Name,
+			&user.Username,
+			&user.Role,
+			&disabledAt,
+			&createdAt,
+			&user.LogCount,
+			&user.CompletedLogCount,
+		); err != nil {
+			return nil, err
+		}
+
+		if user.DisabledAt, err = parseSqliteTime(disabledAt); err != nil {
+			return nil, err
+		}
+
+		created, err := parseSqliteTime(createdAt)
+		if err != nil {
+			return nil, err
+		}
+		user.CreatedAt = *created
+
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// sqliteRowUpdated reports whether a statement changed a row, like
+// RowsAffected() == 1 on a pgx command tag.
+func sqliteRowUpdated(result sql.Result) (*bool, error) {
+	count, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	updated := count == 1
+
+	return &updated, nil
+}
+
+func (s *SqliteStore) GetUserIdByIdentity(ctx context.Context, provider string, subject string) (*uuid.UUID, error) {
+	var userId uuid.UUID
+
+	qStr := `
+		SELECT u_id
+		FROM user_identities
+		WHERE provider = ?1 AND subject = ?2`
+
+	if err := s.db.QueryRowContext(ctx, qStr, provider, subject).Scan(&userId); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, sqliteError(err)
+	}
+
+	return &userId, nil
+}
+
+const sqliteLinkIdentityQuery = `
+		INSERT INTO user_identities (
+			id,
+			u_id,
+			provider,
+			subject,
+			email
+		) VALUES (
+			?1,
+			?2,
+			?3,
+			?4,
+			NULLIF(?5, '')
+		)`
+
+func (s *SqliteStore) LinkIdentity(ctx context.Context, userId uuid.UUID, provider string, subject string, email string) error {
+	if _, err := s.db.ExecContext(ctx, sqliteLinkIdentityQuery, uuid.New(), userId, provider, subject, email); err != nil {
+		return sqliteError(err)
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) CreateUserWithIdentity(
+	ctx context.Context,
+	name string,
+	username string,
+	passwordHash string,
+	provider string,
+	subject string,
+	email string,
+) (*uuid.UUID, error) {
+	userId := uuid.New()
+
+	err := runSqliteTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := insertSqliteUser(ctx, tx, userId, name, username, passwordHash); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, sqliteLinkIdentityQuery, uuid.New(), userId, provider, subject, email); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &userId, nil
+}
+
+func (s *SqliteStore) UpdatePassword(ctx context.Context, userId uuid.UUID, passwordHash string) error {
+	qStr := `
+		UPDATE users
+		SET
+			password_hash = ?2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?1`
+
+	if _, err := s.db.ExecContext(ctx, qStr, userId, passwordHash); err != nil {
+		return sqliteError(err)
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) AddUserBodyDetails(ctx context.Context, profileId uuid.UUID, age int, height_cm int, weight_kg float64, gender string) error {
+	bmr := CalculateBMR(gender, age, weight_kg, height_cm)
+
+	// SQLite checks constraints on the inserted row before resolving the
+	// conflict, so an update leaving gender empty inserts the stored one.
+	qStr := `
+		INSERT INTO user_body_details (
+			id,
+			p_id,
+			age,
+			height_cm,
+			weight_kg,
+			gender,
+			bmr
+		) VALUES (
+			?1,
+			?2,
+			?3,
+			?4,
+			?5,
+			COALESCE(NULLIF(?6, ''), (SELECT gender FROM user_body_details WHERE p_id = ?2)),
+			?7
+		) ON CONFLICT (p_id) DO UPDATE
+		SET
+			age = COALESCE(NULLIF(?3, 0), user_body_details.age),
+			height_cm = COALESCE(NULLIF(?4, 0), user_body_details.height_cm),
+			weight_kg = COALESCE(NULLIF(?5, 0), user_body_details.weight_kg),
+			gender = COALESCE(NULLIF(?6, ''), user_body_details.gender),
+			bmr = CASE
+				WHEN user_body_details.age <> COALESCE(NULLIF(?3, 0), user_body_details.age)
+					OR user_body_details.height_cm <> COALESCE(NULLIF(?4, 0), user_body_details.height_cm)
+					OR user_body_details.weight_kg <> COALESCE(NULLIF(?5, 0), user_body_details.weight_kg)
+					OR user_body_details.gender <> COALESCE(NULLIF(?6, ''), user_body_details.gender)
+				THEN ?7
+				ELSE user_body_details.bmr
+			END
+	`
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		qStr,
+		uuid.New(),
+		profileId,
+		age,
+		height_cm,
+		weight_kg,
+		gender,
+		bmr,
+	); err != nil {
+		return sqliteError(err)
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) SetUserGoal(ctx context.Context, profileId uuid.UUID, goal string) error {
+	qStr := `
+		INSERT INTO user_weight_goal (
+			id,
+			p_id,
+			goal
+		) VALUES (
+			?1,
+			?2,
+			COALESCE(NULLIF(?3, ''), (SELECT goal FROM user_weight_goal WHERE p_id = ?2))
+		) ON CONFLICT (p_id) DO UPDATE
+		SET goal = COALESCE(NULLIF(?3, ''), user_weight_goal.goal)
+		`
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		qStr,
+		uuid.New(),
+		profileId,
+		goal,
+	); err != nil {
+		return sqliteError(err)
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) GetUserBmr(ctx context.Context, profileId uuid.UUID) (*float64, error) {
+	var bmr float64
+
+	qStr := `
+		SELECT bmr
+		FROM user_body_details
+		WHERE p_id = ?1
+	`
+
+	if err := s.db.QueryRowContext(ctx, qStr, profileId).Scan(&bmr); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &bmr, nil
+}
+
+func (s *SqliteStore) GetUserWeightGoalById(ctx context.Context, profileId uuid.UUID) (*string, error) {
+	var goal string
+
+	qStr := `
+		SELECT goal
+		FROM user_weight_goal
+		WHERE p_id = ?1
+	`
+
+	if err := s.db.QueryRowContext(ctx, qStr, profileId).Scan(&goal); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &goal, nil
+}
+
+func (s *SqliteStore) DoesBodyDetailsExist(ctx context.Context, profileId uuid.UUID) (*bool, error) {
+	var exists bool
+
+	qStr := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM user_body_details
+			WHERE p_id = ?1
+		)
+	`
+
+	if err := s.db.QueryRowContext(ctx, qStr, profileId).Scan(&exists); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &exists, nil
+}
+
+func (s *SqliteStore) DoesLogExistForTheDay(ctx context.Context, profileId uuid.UUID, logDate string) (*bool, error) {
+	var logExists bool
+
+	qStr := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM user_calorie_logs
+			WHERE p_id = ?1 AND log_date = ?2
+		)
+	`
+
+	if err := s.db.QueryRowContext(ctx, qStr, profileId, logDate).Scan(&logExists); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &logExists, nil
+}
+
+func (s *SqliteStore) CreateUserLog(ctx context.Context, profileId uuid.UUID, bmr float64, logDate string) error {
+	qStr := `
+		INSERT INTO user_calorie_logs (
+			id,
+			p_id,
+			tdee,
+			log_date
+		) VALUES (
+			?1,
+			?2,
+			?3,
+			?4
+		)
+	`
+	if _, err := s.db.ExecContext(ctx, qStr, uuid.New(), profileId, bmr, logDate); err != nil {
+		return sqliteError(err)
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) LogCaloriesConsumed(ctx context.Context, profileId uuid.UUID, caloriesConsumed float64, logDate string) error {
+	qStr := `
+		UPDATE user_calorie_logs
+		SET
+			calories_consumed = COALESCE(user_calorie_logs.calories_consumed, 0) + ?2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE p_id = ?1 AND log_date = ?3
+		`
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		qStr,
+		profileId,
+		caloriesConsumed,
+		logDate,
+	); err != nil {
+		return sqliteError(err)
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) LogCaloriesBurnt(ctx context.Context, profileId uuid.UUID, caloriesBurnt float64, logDate string) error {
+	qStr := `
+		UPDATE user_calorie_logs
+		SET
+			calories_burnt = COALESCE(user_calorie_logs.calories_burnt, 0) + ?2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE p_id = ?1 AND log_date = ?3
+		`
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		qStr,
+		profileId,
+		caloriesBurnt,
+		logDate,
+	); err != nil {
+		return sqliteError(err)
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) FetchCaloriesConsumedForTheDay(ctx context.Context, profileId uuid.UUID, logDate string) (*float64, error) {
+	var caloriesConsumed float64
+
+	qStr := `
+		SELECT calories_consumed
+		FROM user_calorie_logs
+		WHERE p_id = ?1 AND log_date = ?2
+	`
+
+	if err := s.db.QueryRowContext(ctx, qStr, profileId, logDate).Scan(&caloriesConsumed); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &caloriesConsumed, nil
+}
+
+func (s *SqliteStore) FetchCaloriesBurntForTheDay(ctx context.Context, profileId uuid.UUID, logDate string) (*float64, error) {
+	var caloriesBurnt float64
+
+	qStr := `
+		SELECT calories_burnt
+		FROM user_calorie_logs
+		WHERE p_id = ?1 AND log_date = ?2
+	`
+
+	if err := s.db.QueryRowContext(ctx, qStr, profileId, logDate).Scan(&caloriesBurnt); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &caloriesBurnt, nil
+}
+
+func (s *SqliteStore) AddCaloriesBurntInTDEE(ctx context.Context, profileId uuid.UUID, logDate string, caloriesBurnt float64) error {
+	qStr := `
+		UPDATE user_calorie_logs
+		SET tdee = user_calorie_logs.tdee + ?3
+		WHERE p_id = ?1 AND log_date = ?2
+	`
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		qStr,
+		profileId,
+		logDate,
+		caloriesBurnt,
+	); err != nil {
+		return sqliteError(err)
+	}
+
+	return nil
+}
+
+// FinalizeCalorieLog needs no retries like PgStore's, SQLite transactions
+// are serialized by the database lock.
+func (s *SqliteStore) FinalizeCalorieLog(ctx context.Context, profileId uuid.UUID, logDate string) (*float64, bool, error) {
+	var caloricBalance float64
+	var finalized bool
+
+	err := runSqliteTx(ctx, s.db, func(tx *sql.Tx) error {
+		var logId uuid.UUID
+		var previousStatus string
+
+		qStr := `
+			SELECT log_status
+			FROM user_calorie_logs
+			WHERE p_id = ?1 AND log_date = ?2
+		`
+
+		if err := tx.QueryRowContext(ctx, qStr, profileId, logDate).Scan(&previousStatus); err != nil {
+			return err
+		}
+
+		finalized = previousStatus != "D"
+
+		qStr = `
+			UPDATE user_calorie_logs
+			SET
+				log_status = 'D',
+				updated_at = CURRENT_TIMESTAMP
+			WHERE p_id = ?1 AND log_date = ?2
+			RETURNING id, (tdee - calories_consumed) AS caloric_balance
+		`
+
+		if err := tx.QueryRowContext(ctx, qStr, profileId, logDate).Scan(&logId, &caloricBalance); err != nil {
+			return err
+		}
+
+		qStr = `
+			INSERT INTO user_caloric_balance (
+				id,
+				calorie_log_id,
+				caloric_balance
+			) VALUES (
+				?1,
+				?2,
+				?3
+			) ON CONFLICT (calorie_log_id) DO UPDATE
+			SET caloric_balance = ?3
+		`
+
+		if _, err := tx.ExecContext(ctx, qStr, uuid.New(), logId, caloricBalance); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, false, sqliteError(err)
+	}
+
+	return &caloricBalance, finalized, nil
+}
+
+func (s *SqliteStore) ReopenCalorieLog(ctx context.Context, profileId uuid.UUID, logDate string) error {
+	err := runSqliteTx(ctx, s.db, func(tx *sql.Tx) error {
+		var logId uuid.UUID
+
+		qStr := `
+			UPDATE user_calorie_logs
+			SET
+				log_status = 'P',
+				updated_at = CURRENT_TIMESTAMP
+			WHERE p_id = ?1 AND log_date = ?2
+			RETURNING id
+		`
+
+		if err := tx.QueryRowContext(ctx, qStr, profileId, logDate).Scan(&logId); err != nil {
+			return err
+		}
+
+		qStr = `
+			UPDATE user_caloric_balance
+			SET caloric_balance = 0.00
+			WHERE calorie_log_id = ?1
+		`
+
+		if _, err := tx.ExecContext(ctx, qStr, logId); err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	return sqliteError(err)
+}
+
+func (s *SqliteStore) GetCalorieLogId(ctx context.Context, profileId uuid.UUID, logDate string) (*uuid.UUID, error) {
+	var calorieLogId uuid.UUID
+
+	qStr := `
+		SELECT id
+		FROM user_calorie_logs
+		WHERE p_id = ?1 AND log_date = ?2
+	`
+
+	if err := s.db.QueryRowContext(ctx, qStr, profileId, logDate).Scan(&calorieLogId); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &calorieLogId, nil
+}
+
+func (s *SqliteStore) GetCalorieLogs(ctx context.Context, profileId uuid.UUID) (map[string][]UserCalorieLogs, error) {
+	monthlyLogs := make(map[string][]UserCalorieLogs)
+
+	qStr := `
+		SELECT
+			log_date,
+			calories_burnt,
+			calories_consumed,
+			tdee,
+			updated_at,
+			log_status
+		FROM user_calorie_logs
+		WHERE p_id = ?1
+		ORDER BY log_date
+	`
+
+	rows, err := s.db.QueryContext(ctx, qStr, profileId)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var log UserCalorieLogs
+		var logDate string
+		var updatedAt string
+
+		err := rows.Scan(
+			&logDate,
+			&log.CaloriesBurnt,
+			&log.CaloriesConsumed,
+			&log.Tdee,
+			&updatedAt,
+			&log.LogStatus,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		day, err := time.Parse("2006-01-02", logDate)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Updated_at, err = time.Parse(sqliteTimeLayout, updatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		// Format date for monthly grouping, e.g., "September 2024"
+		monthYearKey := day.Format("January, 2006")
+		log.LogDate = logDate
+
+		monthlyLogs[monthYearKey] = append(monthlyLogs[monthYearKey], log)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return monthlyLogs, nil
+}
+
+func (s *SqliteStore) UpdateCalorieLog(
+	ctx context.Context,
+	profileId uuid.UUID,
+	logDate string,
+	caloriesConsumed float64,
+	caloriesBurnt float64,
+) error {
+	qStr := `
+		UPDATE user_calorie_logs
+		SET
+			calories_consumed = user_calorie_logs.calories_consumed + ?3,
+			calories_burnt = user_calorie_logs.calories_burnt + ?4
+		WHERE p_id = ?1 AND log_date = ?2
+	`
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		qStr,
+		profileId,
+		logDate,
+		caloriesConsumed,
+		caloriesBurnt,
+	); err != nil {
+		return sqliteError(err)
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) CheckLogStatusByIdAndDate(ctx context.Context, profileId uuid.UUID, logDate string) (*string, error) {
+	var logStatus string
+
+	qStr := `
+		SELECT log_status
+		FROM user_calorie_logs
+		WHERE p_id = ?1 AND log_date = ?2
+	`
+
+	if err := s.db.QueryRowContext(ctx, qStr, profileId, logDate).Scan(&logStatus); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &logStatus, nil
+}
+
+func (s *SqliteStore) DeleteCalorieLog(ctx context.Context, profileId uuid.UUID, logDate string) error {
+	qStr := `
+		DELETE FROM user_calorie_logs
+		WHERE p_id = ?1 AND log_date = ?2
+	`
+
+	if _, err := s.db.ExecContext(ctx, qStr, profileId, logDate); err != nil {
+		return sqliteError(err)
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) DeleteCaloricBalanceByLogId(ctx context.Context, logId uuid.UUID) error {
+	qStr := `
+		DELETE FROM user_caloric_balance
+		WHERE calorie_log_id = ?1
+	`
+
+	if _, err := s.db.ExecContext(ctx, qStr, logId); err != nil {
+		return sqliteError(err)
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) GetNetCaloricBalance(ctx context.Context, profileId uuid.UUID) (*float64, error) {
+	var netCaloricBalance sql.NullFloat64
+
+	qStr := `
+		SELECT SUM(user_caloric_balance.caloric_balance)
+		FROM user_calorie_logs
+		JOIN user_caloric_balance
+		ON user_calorie_logs.id = user_caloric_balance.calorie_log_id
+		WHERE user_calorie_logs.p_id = ?1
+	`
+
+	if err := s.db.QueryRowContext(ctx, qStr, profileId).Scan(&netCaloricBalance); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	if !netCaloricBalance.Valid {
+		defaultValue := 0.0
+		return &defaultValue, nil
+	}
+
+	return &netCaloricBalance.Float64, nil
+}
@@ -0,0 +1,215 @@
+package lib
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *SqliteStore) StartTOTPEnrollment(ctx context.Context, userId uuid.UUID) (string, error) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+
+	qStr := `
+		INSERT INTO user_totp (
+			id,
+			u_id,
+			secret
+		) VALUES (
+			?1,
+			?2,
+			?3
+		) ON CONFLICT (u_id) DO UPDATE
+		SET
+			secret = ?3,
+			last_used_step = 0,
+			created_at = CURRENT_TIMESTAMP
+		WHERE user_totp.enabled = 0
+	`
+
+	result, err := s.db.ExecContext(ctx, qStr, uuid.New(), userId, secret)
+	if err != nil {
+		return "", sqliteError(err)
+	}
+
+	started, err := sqliteRowUpdated(result)
+	if err != nil {
+		return "", err
+	}
+
+	if !*started {
+		return "", ErrTOTPAlreadyEnabled
+	}
+
+	return secret, nil
+}
+
+func (s *SqliteStore) VerifyTOTPEnrollment(ctx context.Context, userId uuid.UUID, code string) ([]string, error) {
+	codes, codeHashes, err := generateRecoveryCodes(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = runSqliteTx(ctx, s.db, func(tx *sql.Tx) error {
+		var secret string
+		var enabled bool
+
+		qStr := `
+			SELECT secret, enabled
+			FROM user_totp
+			WHERE u_id = ?1
+		`
+
+		if err := tx.QueryRowContext(ctx, qStr, userId).Scan(&secret, &enabled); err != nil {
+			if err == sql.ErrNoRows {
+				return ErrTOTPNotEnrolled
+			}
+
+			return err
+		}
+
+		if enabled {
+			return ErrTOTPAlreadyEnabled
+		}
+
+		step, ok := ValidateTOTPCode(secret, code, time.Now())
+		if !ok {
+			return ErrTOTPInvalidCode
+		}
+
+		qStr = `
+			UPDATE user_totp
+			SET
+				enabled = 1,
+				last_used_step = ?2,
+				enabled_at = CURRENT_TIMESTAMP
+			WHERE u_id = ?1
+		`
+
+		if _, err := tx.ExecContext(ctx, qStr, userId, step); err != nil {
+			return err
+		}
+
+		return replaceSqliteRecoveryCodes(ctx, tx, userId, codeHashes)
+	})
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return codes, nil
+}
+
+func (s *SqliteStore) IsTOTPEnabled(ctx context.Context, userId uuid.UUID) (*bool, error) {
+	var enabled bool
+
+	qStr := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM user_totp
+			WHERE u_id = ?1 AND enabled = 1
+		)`
+
+	if err := s.db.QueryRowContext(ctx, qStr, userId).Scan(&enabled); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return &enabled, nil
+}
+
+func (s *SqliteStore) VerifyTOTPCode(ctx context.Context, userId uuid.UUID, code string) (*bool, error) {
+	var secret string
+	valid := false
+
+	qStr := `
+		SELECT secret
+		FROM user_totp
+		WHERE u_id = ?1 AND enabled = 1
+	`
+
+	if err := s.db.QueryRowContext(ctx, qStr, userId).Scan(&secret); err != nil {
+		if err == sql.ErrNoRows {
+			return &valid, nil
+		}
+
+		return nil, sqliteError(err)
+	}
+
+	step, ok := ValidateTOTPCode(secret, code, time.Now())
+	if !ok {
+		return &valid, nil
+	}
+
+	qStr = `
+		UPDATE user_totp
+		SET last_used_step = ?2
+		WHERE u_id = ?1 AND last_used_step < ?2
+	`
+
+	result, err := s.db.ExecContext(ctx, qStr, userId, step)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return sqliteRowUpdated(result)
+}
+
+func (s *SqliteStore) UseRecoveryCode(ctx context.Context, userId uuid.UUID, code string) (*bool, error) {
+	qStr := `
+		UPDATE user_recovery_codes
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE u_id = ?1 AND code_hash = ?2 AND used_at IS NULL
+	`
+
+	result, err := s.db.ExecContext(ctx, qStr, userId, hashRecoveryCode(userId, code))
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+
+	return sqliteRowUpdated(result)
+}
+
+func (s *SqliteStore) DisableTOTP(ctx context.Context, userId uuid.UUID) error {
+	err := runSqliteTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE u_id = ?1`, userId); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE u_id = ?1`, userId); err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	return sqliteError(err)
+}
+
+func replaceSqliteRecoveryCodes(ctx context.Context, tx *sql.Tx, userId uuid.UUID, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE u_id = ?1`, userId); err != nil {
+		return err
+	}
+
+	qStr := `
+		INSERT INTO user_recovery_codes (
+			id,
+			u_id,
+			code_hash
+		) VALUES (
+			?1,
+			?2,
+			?3
+		)
+	`
+
+	for _, codeHash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, qStr, uuid.New(), userId, codeHash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package lib
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+func (s *SqliteStore) RecordWeighIn(ctx context.Context, profileId uuid.UUID, weightKg float64) error {
+	qStr := `
+		INSERT INTO user_weigh_ins (
+			id,
+			p_id,
+			weight_kg
+		) VALUES (
+			?1,
+			?2,
+			?3
+		) ON CONFLICT (p_id, weighed_on) DO UPDATE
+		SET weight_kg = ?3
+	`
+
+	if _, err := s.db.ExecContext(ctx, qStr, uuid.New(), profileId, weightKg); err != nil {
+		return sqliteError(err)
+	}
+
+	return nil
+}
+
+// GetWeighIns reads weighed_on as is, SQLite keeps dates as
+// "YYYY-MM-DD" text.
+func (s *SqliteStore) GetWeighIns(ctx context.Context, profileId uuid.UUID) ([]WeighIn, error) {
+	weighIns := []WeighIn{}
+
+	qStr := `
+		SELECT weighed_on, weight_kg
+		FROM user_weigh_ins
+		WHERE p_id = ?1
+		ORDER BY weighed_on
+	`
+
+	rows, err := s.db.QueryContext(ctx, qStr, profileId)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var weighIn WeighIn
+
+		if err := rows.Scan(&weighIn.WeighedOn, &weighIn.WeightKg); err != nil {
+			return nil, err
+		}
+
+		weighIns = append(weighIns, weighIn)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return weighIns, nil
+}
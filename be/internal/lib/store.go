@@ -0,0 +1,200 @@
+package lib
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Stores hide where users, their logs and balances are kept. Lookups of a
+// single row that find nothing fail with pgx.ErrNoRows in every
+// implementation, so callers handle them the same way.
+type UserStore interface {
+	DoesUserExists(ctx context.Context, username string) (*bool, error)
+	// GetUserIdByUsername returns nil when there is no such user.
+	GetUserIdByUsername(ctx context.Context, username string) (*uuid.UUID, error)
+	CreateUser(ctx context.Context, name string, username string, passwordHash string) (*uuid.UUID, error)
+	GetUsernameById(ctx context.Context, userId uuid.UUID) (*string, error)
+	GetPasswordHash(ctx context.Context, username string) (*string, error)
+	GetUserStatusById(ctx context.Context, userId uuid.UUID) (*UserStatus, error)
+	// SetUserDisabled and SetUserRole return false when there is no such user.
+	SetUserDisabled(ctx context.Context, userId uuid.UUID, disabled bool) (*bool, error)
+	SetUserRole(ctx context.Context, userId uuid.UUID, role string) (*bool, error)
+	// GetUserIdByIdentity returns nil when no user is linked to the external
+	// identity yet.
+	GetUserIdByIdentity(ctx context.Context, provider string, subject string) (*uuid.UUID, error)
+	LinkIdentity(ctx context.Context, userId uuid.UUID, provider string, subject string, email string) error
+	// CreateUserWithIdentity is CreateUser and LinkIdentity in a single
+	// transaction, so a failed link leaves no user behind.
+	CreateUserWithIdentity(
+		ctx context.Context,
+		name string,
+		username string,
+		passwordHash string,
+		provider string,
+		subject string,
+		email string,
+	) (*uuid.UUID, error)
+	UpdatePassword(ctx context.Context, userId uuid.UUID, passwordHash string) error
+	AddUserBodyDetails(ctx context.Context, profileId uuid.UUID, age int, height_cm int, weight_kg float64, gender string) error
+	SetUserGoal(ctx context.Context, profileId uuid.UUID, goal string) error
+	GetUserBmr(ctx context.Context, profileId uuid.UUID) (*float64, error)
+	GetUserWeightGoalById(ctx context.Context, profileId uuid.UUID) (*string, error)
+	DoesBodyDetailsExist(ctx context.Context, profileId uuid.UUID) (*bool, error)
+	SearchUsers(ctx context.Context, query string, limit int, offset int) ([]UserSummary, error)
+}
+
+// TwoFactorStore holds the users' TOTP enrollment and recovery codes.
+type TwoFactorStore interface {
+	StartTOTPEnrollment(ctx context.Context, userId uuid.UUID) (string, error)
+	VerifyTOTPEnrollment(ctx context.Context, userId uuid.UUID, code string) ([]string, error)
+	IsTOTPEnabled(ctx context.Context, userId uuid.UUID) (*bool, error)
+	VerifyTOTPCode(ctx context.Context, userId uuid.UUID, code string) (*bool, error)
+	UseRecoveryCode(ctx context.Context, userId uuid.UUID, code string) (*bool, error)
+	DisableTOTP(ctx context.Context, userId uuid.UUID) error
+}
+
+// RefreshTokenStore keeps the sessions, only the tokens' hashes are stored.
+type RefreshTokenStore interface {
+	IssueRefreshToken(ctx context.Context, userId uuid.UUID) (string, error)
+	RotateRefreshToken(ctx context.Context, token string) (*uuid.UUID, string, error)
+	RevokeRefreshTokenFamily(ctx context.Context, token string) error
+	RevokeUserRefreshTokens(ctx context.Context, userId uuid.UUID) error
+}
+
+type AccessTokenStore interface {
+	CreateAccessToken(ctx context.Context, userId uuid.UUID, name string, scopes []string, expiresAt *time.Time) (*uuid.UUID, string, error)
+	GetAccessTokens(ctx context.Context, userId uuid.UUID) ([]UserAccessToken, error)
+	RevokeAccessToken(ctx context.Context, userId uuid.UUID, tokenId uuid.UUID) (*bool, error)
+	AuthenticateAccessToken(ctx context.Context, token string) (*AccessTokenAuth, error)
+}
+
+type ProfileStore interface {
+	GetProfiles(ctx context.Context, userId uuid.UUID) ([]Profile, error)
+	DoesProfileBelongToUser(ctx context.Context, userId uuid.UUID, profileId uuid.UUID) (*bool, error)
+	CreateProfile(ctx context.Context, userId uuid.UUID, name string) (*uuid.UUID, error)
+	RenameProfile(ctx context.Context, userId uuid.UUID, profileId uuid.UUID, name string) (*bool, error)
+	DeleteProfile(ctx context.Context, userId uuid.UUID, profileId uuid.UUID) (*bool, error)
+}
+
+type WeighInStore interface {
+	RecordWeighIn(ctx context.Context, profileId uuid.UUID, weightKg float64) error
+	GetWeighIns(ctx context.Context, profileId uuid.UUID) ([]WeighIn, error)
+}
+
+type CoachStore interface {
+	InviteCoach(ctx context.Context, clientId uuid.UUID, profileId uuid.UUID, coachUsername string) (*uuid.UUID, error)
+	GetClientShares(ctx context.Context, clientId uuid.UUID) ([]CoachShare, error)
+	GetCoachShares(ctx context.Context, coachId uuid.UUID) ([]CoachShare, error)
+	AcceptCoachInvite(ctx context.Context, coachId uuid.UUID, shareId uuid.UUID) (*bool, error)
+	RevokeCoachShare(ctx context.Context, userId uuid.UUID, shareId uuid.UUID) (*bool, error)
+	GetActiveShare(ctx context.Context, coachId uuid.UUID, profileId uuid.UUID) (*ActiveCoachShare, error)
+	RecordCoachAccess(ctx context.Context, shareId uuid.UUID, resource string) error
+	GetCoachAccessLog(ctx context.Context, clientId uuid.UUID, limit int) ([]CoachAccess, error)
+}
+
+type LogCommentStore interface {
+	CreateLogComment(
+		ctx context.Context,
+		clientId uuid.UUID,
+		profileId uuid.UUID,
+		coachId uuid.UUID,
+		authorId uuid.UUID,
+		target LogCommentTarget,
+		body string,
+	) (*uuid.UUID, error)
+	ReplyToLogComment(ctx context.Context, profileId uuid.UUID, authorId uuid.UUID, parentId uuid.UUID, body string) (*uuid.UUID, error)
+	GetLogComments(ctx context.Context, profileId uuid.UUID, viewerId uuid.UUID, filter LogCommentFilter) ([]LogComment, error)
+	GetUnreadCommentCounts(ctx context.Context, userId uuid.UUID) ([]UnreadCommentCount, error)
+	MarkLogCommentsRead(ctx context.Context, userId uuid.UUID, commentIds []uuid.UUID) error
+}
+
+type GroupStore interface {
+	CreateGroup(ctx context.Context, ownerId uuid.UUID, profileId uuid.UUID, name string) (*uuid.UUID, error)
+	JoinGroup(ctx context.Context, profileId uuid.UUID, inviteCode string) (*uuid.UUID, error)
+	GetGroups(ctx context.Context, userId uuid.UUID, profileId uuid.UUID) ([]Group, error)
+	LeaveGroup(ctx context.Context, groupId uuid.UUID, profileId uuid.UUID) (*bool, error)
+	IsGroupMember(ctx context.Context, groupId uuid.UUID, profileId uuid.UUID) (*bool, error)
+	IsGroupOwner(ctx context.Context, groupId uuid.UUID, userId uuid.UUID) (*bool, error)
+}
+
+type ChallengeStore interface {
+	CreateChallenge(
+		ctx context.Context,
+		groupId uuid.UUID,
+		createdBy uuid.UUID,
+		name string,
+		metric string,
+		startsOn string,
+		endsOn string,
+	) (*uuid.UUID, error)
+	GetChallenges(ctx context.Context, groupId uuid.UUID) ([]Challenge, error)
+	GetChallenge(ctx context.Context, challengeId uuid.UUID) (*Challenge, error)
+	// GetChallengeScores returns the members' scores unranked, see
+	// GetLeaderboard.
+	GetChallengeScores(ctx context.Context, challenge Challenge, viewerProfileId uuid.UUID) ([]LeaderboardEntry, error)
+}
+
+type AchievementStore interface {
+	EvaluateAchievements(ctx context.Context, profileId uuid.UUID, event string) ([]Achievement, error)
+	RecomputeAchievements(ctx context.Context, profileId uuid.UUID) error
+	GetAllProfileIds(ctx context.Context) ([]uuid.UUID, error)
+	GetAchievements(ctx context.Context, profileId uuid.UUID) ([]Achievement, error)
+	GetStreaks(ctx context.Context, profileId uuid.UUID) (*Streaks, error)
+}
+
+// Store is every store at once, which PgStore and SqliteStore both are.
+type Store interface {
+	UserStore
+	TwoFactorStore
+	RefreshTokenStore
+	AccessTokenStore
+	CalorieLogStore
+	BalanceStore
+	ProfileStore
+	WeighInStore
+	CoachStore
+	LogCommentStore
+	GroupStore
+	ChallengeStore
+	AchievementStore
+}
+
+type CalorieLogStore interface {
+	DoesLogExistForTheDay(ctx context.Context, profileId uuid.UUID, logDate string) (*bool, error)
+	CreateUserLog(ctx context.Context, profileId uuid.UUID, bmr float64, logDate string) error
+	LogCaloriesConsumed(ctx context.Context, profileId uuid.UUID, caloriesConsumed float64, logDate string) error
+	LogCaloriesBurnt(ctx context.Context, profileId uuid.UUID, caloriesBurnt float64, logDate string) error
+	FetchCaloriesConsumedForTheDay(ctx context.Context, profileId uuid.UUID, logDate string) (*float64, error)
+	FetchCaloriesBurntForTheDay(ctx context.Context, profileId uuid.UUID, logDate string) (*float64, error)
+	AddCaloriesBurntInTDEE(ctx context.Context, profileId uuid.UUID, logDate string, caloriesBurnt float64) error
+	// FinalizeCalorieLog marks the day as done and saves its balance,
+	// returning it and whether the day was pending until now.
+	// ReopenCalorieLog marks it pending again. Both are atomic and can be
+	// repeated.
+	FinalizeCalorieLog(ctx context.Context, profileId uuid.UUID, logDate string) (*float64, bool, error)
+	ReopenCalorieLog(ctx context.Context, profileId uuid.UUID, logDate string) error
+	GetCalorieLogId(ctx context.Context, profileId uuid.UUID, logDate string) (*uuid.UUID, error)
+	GetCalorieLogs(ctx context.Context, profileId uuid.UUID) (map[string][]UserCalorieLogs, error)
+	UpdateCalorieLog(ctx context.Context, profileId uuid.UUID, logDate string, caloriesConsumed float64, caloriesBurnt float64) error
+	CheckLogStatusByIdAndDate(ctx context.Context, profileId uuid.UUID, logDate string) (*string, error)
+	DeleteCalorieLog(ctx context.Context, profileId uuid.UUID, logDate string) error
+}
+
+type BalanceStore interface {
+	DeleteCaloricBalanceByLogId(ctx context.Context, logId uuid.UUID) error
+	GetNetCaloricBalance(ctx context.Context, profileId uuid.UUID) (*float64, error)
+}
+
+// PgStore keeps everything in Postgres.
+type PgStore struct {
+	pool *pgxpool.Pool
+}
+
+var _ Store = (*PgStore)(nil)
+
+func NewPgStore(pool *pgxpool.Pool) *PgStore {
+	return &PgStore{pool: pool}
+}
@@ -0,0 +1,81 @@
+package lib
+
+import (
+	"calometer/internal/config"
+	"calometer/internal/db"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// testDatabaseURLEnv points the tests at a Postgres database they may
+// write to, the same one the api integration suite uses.
+const testDatabaseURLEnv = "TEST_DB_URL"
+
+// newTestSqliteStore opens a migrated SQLite database that only lives for
+// the test.
+func newTestSqliteStore(t *testing.T) *SqliteStore {
+	t.Helper()
+
+	cfg := config.DatabaseConfig{
+		Driver:         "sqlite",
+		SQLitePath:     filepath.Join(t.TempDir(), "calometer.db"),
+		MigrateOnStart: true,
+	}
+
+	conn, err := db.InitSQLite(cfg)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.CloseSQLite(conn) })
+
+	if _, err := db.CheckSchema(context.Background(), cfg, nil); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	return NewSqliteStore(conn)
+}
+
+// newTestPgStore skips the test unless a Postgres database is configured.
+// The database outlives the test, so whatever is created in it needs unique
+// names.
+func newTestPgStore(t *testing.T) *PgStore {
+	t.Helper()
+
+	url := os.Getenv(testDatabaseURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set", testDatabaseURLEnv)
+	}
+
+	cfg := config.DatabaseConfig{
+		Driver:         "postgres",
+		URL:            url,
+		MigrateOnStart: true,
+	}
+
+	pool, err := db.Init(cfg)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(pool) })
+
+	if _, err := db.CheckSchema(context.Background(), cfg, pool); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	return NewPgStore(pool)
+}
+
+func createTestUser(t *testing.T, store UserStore, username string) uuid.UUID {
+	t.Helper()
+
+	userId, err := store.CreateUser(context.Background(), username, username, "not a hash")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	return *userId
+}
@@ -0,0 +1,133 @@
+package lib
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Streaks count consecutive days marked as done. The current streak stays
+// alive until the end of the day after the last completed one.
+type Streaks struct {
+	Current         int     `json:"current"`
+	Longest         int     `json:"longest"`
+	LastCompletedOn *string `json:"last_completed_on"`
+}
+
+// ComputeStreaks works on completed days sorted oldest first.
+func ComputeStreaks(days []time.Time, today time.Time) Streaks {
+	streaks := Streaks{}
+	if len(days) == 0 {
+		return streaks
+	}
+
+	run := 0
+	for i, day := range days {
+		if i > 0 && isNextDay(days[i-1], day) {
+			run++
+		} else {
+			run = 1
+		}
+
+		if run > streaks.Longest {
+			streaks.Longest = run
+		}
+	}
+
+	last := days[len(days)-1]
+	if isStreakAlive(last, today) {
+		streaks.Current = run
+	}
+
+	lastCompletedOn := last.Format("2006-01-02")
+	streaks.LastCompletedOn = &lastCompletedOn
+
+	return streaks
+}
+
+func isNextDay(previous time.Time, day time.Time) bool {
+	return previous.AddDate(0, 0, 1).Equal(day)
+}
+
+func isStreakAlive(last time.Time, today time.Time) bool {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+
+	return !last.Before(today.AddDate(0, 0, -1))
+}
+
+func saveStreaks(ctx context.Context, tx pgx.Tx, profileId uuid.UUID, streaks Streaks) error {
+	qStr := `
+		INSERT INTO user_streaks (
+			p_id,
+			current_streak,
+			longest_streak,
+			last_completed_on
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			$4
+		) ON CONFLICT (p_id) DO UPDATE
+		SET
+			current_streak = $2,
+			longest_streak = $3,
+			last_completed_on = $4,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	if _, err := tx.Exec(
+		ctx,
+		qStr,
+		profileId,
+		streaks.Current,
+		streaks.Longest,
+		streaks.LastCompletedOn,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetStreaks reads the streaks saved by the last evaluation. A current
+// streak nobody extended since is reported as broken.
+func (s *PgStore) GetStreaks(ctx context.Context, profileId uuid.UUID) (*Streaks, error) {
+	var streaks Streaks
+	var lastCompletedOn *time.Time
+
+	qStr := `
+		SELECT current_streak, longest_streak, last_completed_on
+		FROM user_streaks
+		WHERE p_id = $1
+	`
+
+	if err := s.pool.QueryRow(ctx, qStr, profileId).Scan(
+		&streaks.Current,
+		&streaks.Longest,
+		&lastCompletedOn,
+	); err != nil {
+		if err == pgx.ErrNoRows {
+			return &Streaks{}, nil
+		}
+
+		return nil, err
+	}
+
+	return savedStreaks(streaks, lastCompletedOn), nil
+}
+
+// savedStreaks breaks the saved current streak when it ended before today.
+func savedStreaks(streaks Streaks, lastCompletedOn *time.Time) *Streaks {
+	if lastCompletedOn != nil {
+		formatted := lastCompletedOn.Format("2006-01-02")
+		streaks.LastCompletedOn = &formatted
+
+		if !isStreakAlive(*lastCompletedOn, time.Now()) {
+			streaks.Current = 0
+		}
+	}
+
+	return &streaks
+}
@@ -0,0 +1,76 @@
+package lib
+
+import (
+	"testing"
+	"time"
+)
+
+func date(t *testing.T, value string) time.Time {
+	t.Helper()
+
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		t.Fatalf("failed to parse date: %v", err)
+	}
+
+	return day
+}
+
+func TestComputeStreaks(t *testing.T) {
+	// Late in the day, only the date counts
+	today := time.Date(2024, 3, 10, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		days    []string
+		current int
+		longest int
+	}{
+		{name: "nothing completed"},
+		{name: "today", days: []string{"2024-03-10"}, current: 1, longest: 1},
+		{name: "ending yesterday", days: []string{"2024-03-08", "2024-03-09"}, current: 2, longest: 2},
+		{name: "ended two days ago", days: []string{"2024-03-07", "2024-03-08"}, longest: 2},
+		{
+			name:    "gap",
+			days:    []string{"2024-03-05", "2024-03-06", "2024-03-08", "2024-03-09", "2024-03-10"},
+			current: 3,
+			longest: 3,
+		},
+		{
+			name:    "longest before the gap",
+			days:    []string{"2024-03-01", "2024-03-02", "2024-03-03", "2024-03-04", "2024-03-09", "2024-03-10"},
+			current: 2,
+			longest: 4,
+		},
+		{
+			name:    "across months",
+			days:    []string{"2024-02-28", "2024-02-29", "2024-03-01"},
+			longest: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var days []time.Time
+			for _, day := range tt.days {
+				days = append(days, date(t, day))
+			}
+
+			streaks := ComputeStreaks(days, today)
+			if streaks.Current != tt.current || streaks.Longest != tt.longest {
+				t.Fatalf("streaks = %d current %d longest, want %d %d", streaks.Current, streaks.Longest, tt.current, tt.longest)
+			}
+
+			if len(tt.days) == 0 {
+				if streaks.LastCompletedOn != nil {
+					t.Fatalf("last completed on %s, want none", *streaks.LastCompletedOn)
+				}
+				return
+			}
+
+			if last := tt.days[len(tt.days)-1]; streaks.LastCompletedOn == nil || *streaks.LastCompletedOn != last {
+				t.Fatalf("last completed on %v, want %s", streaks.LastCompletedOn, last)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package lib
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	totpIssuer = "calometer"
+	totpDigits = 6
+	totpPeriod = 30
+
+	// Accept codes from one step before and after the current one to allow
+	// for clock drift between the server and the authenticator app.
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled    = errors.New("two-factor authentication enrollment not started")
+	ErrTOTPInvalidCode    = errors.New("invalid two-factor authentication code")
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func GenerateTOTPSecret() (string, error) {
+	bytes := make([]byte, 20)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return base32NoPadding.EncodeToString(bytes), nil
+}
+
+// TOTPProvisioningURI returns the otpauth URI authenticator apps read from
+// a QR code.
+func TOTPProvisioningURI(username string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", totpIssuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprintf("%d", totpDigits))
+	query.Set("period", fmt.Sprintf("%d", totpPeriod))
+
+	label := url.PathEscape(totpIssuer + ":" + username)
+
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+func totpCode(key []byte, step int64) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation as described in RFC 4226
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// ValidateTOTPCode checks code against the secret and returns the time step
+// it matched, so callers can refuse to accept the same step twice.
+func ValidateTOTPCode(secret string, code string, now time.Time) (int64, bool) {
+	key, err := base32NoPadding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	code = strings.TrimSpace(code)
+	currentStep := now.Unix() / totpPeriod
+
+	for skew := -totpSkew; skew <= totpSkew; skew++ {
+		step := currentStep + int64(skew)
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// StartTOTPEnrollment stores a fresh, not yet enabled secret for the user.
+// Calling it again before verification replaces the pending secret.
+func (s *PgStore) StartTOTPEnrollment(ctx context.Context, userId uuid.UUID) (string, error) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+
+	qStr := `
+		INSERT INTO user_totp (
+			u_id,
+			secret
+		) VALUES (
+			$1,
+			$2
+		) ON CONFLICT (u_id) DO UPDATE
+		SET
+			secret = $2,
+			last_used_step = 0,
+			created_at = CURRENT_TIMESTAMP
+		WHERE user_totp.enabled = FALSE
+	`
+
+	tag, err := s.pool.Exec(ctx, qStr, userId, secret)
+	if err != nil {
+		return "", err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return "", ErrTOTPAlreadyEnabled
+	}
+
+	return secret, nil
+}
+
+// VerifyTOTPEnrollment enables two-factor authentication once the user
+// proves their authenticator works, and returns a new set of recovery codes.
+func (s *PgStore) VerifyTOTPEnrollment(ctx context.Context, userId uuid.UUID, code string) ([]string, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var secret string
+	var enabled bool
+
+	qStr := `
+		SELECT secret, enabled
+		FROM user_totp
+		WHERE u_id = $1
+		FOR UPDATE
+	`
+
+	if err := tx.QueryRow(ctx, qStr, userId).Scan(&secret, &enabled); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrTOTPNotEnrolled
+		}
+
+		return nil, err
+	}
+
+	if enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	step, ok := ValidateTOTPCode(secret, code, time.Now())
+	if !ok {
+		return nil, ErrTOTPInvalidCode
+	}
+
+	codes, codeHashes, err := generateRecoveryCodes(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	qStr = `
+		UPDATE user_totp
+		SET
+			enabled = TRUE,
+			last_used_step = $2,
+			enabled_at = CURRENT_TIMESTAMP
+		WHERE u_id = $1
+	`
+
+	if _, err := tx.Exec(ctx, qStr, userId, step); err != nil {
+		return nil, err
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userId, codeHashes); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *PgStore) IsTOTPEnabled(ctx context.Context, userId uuid.UUID) (*bool, error) {
+	var enabled bool
+
+	qStr := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM user_totp
+			WHERE u_id = $1 AND enabled = TRUE
+		)
+	`
+
+	if err := s.pool.QueryRow(ctx, qStr, userId).Scan(&enabled); err != nil {
+		return nil, err
+	}
+
+	return &enabled, nil
+}
+
+// VerifyTOTPCode checks a code for a user with two-factor authentication
+// enabled. Each time step can only be used once.
+func (s *PgStore) VerifyTOTPCode(ctx context.Context, userId uuid.UUID, code string) (*bool, error) {
+	var secret string
+	valid := false
+
+	qStr := `
+		SELECT secret
+		FROM user_totp
+		WHERE u_id = $1 AND enabled = TRUE
+	`
+
+	if err := s.pool.QueryRow(ctx, qStr, userId).Scan(&secret); err != nil {
+		if err == pgx.ErrNoRows {
+			return &valid, nil
+		}
+
+		return nil, err
+	}
+
+	step, ok := ValidateTOTPCode(secret, code, time.Now())
+	if !ok {
+		return &valid, nil
+	}
+
+	qStr = `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE u_id = $1 AND last_used_step < $2
+	`
+
+	tag, err := s.pool.Exec(ctx, qStr, userId, step)
+	if err != nil {
+		return nil, err
+	}
+
+	valid = tag.RowsAffected() > 0
+
+	return &valid, nil
+}
+
+// UseRecoveryCode consumes one of the user's unused recovery codes.
+func (s *PgStore) UseRecoveryCode(ctx context.Context, userId uuid.UUID, code string) (*bool, error) {
+	qStr := `
+		UPDATE user_recovery_codes
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE u_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	tag, err := s.pool.Exec(ctx, qStr, userId, hashRecoveryCode(userId, code))
+	if err != nil {
+		return nil, err
+	}
+
+	used := tag.RowsAffected() > 0
+
+	return &used, nil
+}
+
+func (s *PgStore) DisableTOTP(ctx context.Context, userId uuid.UUID) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_totp WHERE u_id = $1`, userId); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE u_id = $1`, userId); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userId uuid.UUID, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE u_id = $1`, userId); err != nil {
+		return err
+	}
+
+	qStr := `
+		INSERT INTO user_recovery_codes (
+			u_id,
+			code_hash
+		) VALUES (
+			$1,
+			$2
+		)
+	`
+
+	for _, codeHash := range codeHashes {
+		if _, err := tx.Exec(ctx, qStr, userId, codeHash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// generateRecoveryCodes returns new recovery codes for the user along with
+// the hashes to store. It is called before any transaction starts, so the
+// transaction doesn't wait on the random source.
+func generateRecoveryCodes(userId uuid.UUID) ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	codeHashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		bytes := make([]byte, 10)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, nil, err
+		}
+
+		raw := strings.ToLower(base32NoPadding.EncodeToString(bytes))
+		code := raw[:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:]
+
+		codes = append(codes, code)
+		codeHashes = append(codeHashes, hashRecoveryCode(userId, code))
+	}
+
+	return codes, codeHashes, nil
+}
+
+// hashRecoveryCode can be as fast as HashOpaqueToken, the codes carry 80
+// random bits. Keying it with the user id keeps a code from matching any
+// other user's.
+func hashRecoveryCode(userId uuid.UUID, code string) string {
+	return HashOpaqueToken(userId.String() + ":" + normalizeRecoveryCode(code))
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
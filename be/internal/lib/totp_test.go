@@ -0,0 +1,180 @@
+package lib
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors.
+var rfc6238Secret = base32NoPadding.EncodeToString([]byte("12345678901234567890"))
+
+func TestValidateTOTPCode(t *testing.T) {
+	// RFC 6238 lists 94287082 and 07081804, the last six digits are the codes
+	tests := []struct {
+		name  string
+		now   time.Time
+		code  string
+		step  int64
+		valid bool
+	}{
+		{name: "vector at 59", now: time.Unix(59, 0), code: "287082", step: 1, valid: true},
+		{name: "vector at 1111111109", now: time.Unix(1111111109, 0), code: "081804", step: 37037036, valid: true},
+		{name: "surrounding whitespace", now: time.Unix(1111111109, 0), code: " 081804\n", step: 37037036, valid: true},
+		{name: "one step late", now: time.Unix(1111111109+totpPeriod, 0), code: "081804", step: 37037036, valid: true},
+		{name: "one step early", now: time.Unix(1111111109-totpPeriod, 0), code: "081804", step: 37037036, valid: true},
+		{name: "two steps late", now: time.Unix(1111111109+2*totpPeriod, 0), code: "081804"},
+		{name: "wrong code", now: time.Unix(1111111109, 0), code: "081805"},
+		{name: "empty code", now: time.Unix(1111111109, 0), code: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, valid := ValidateTOTPCode(rfc6238Secret, tt.code, tt.now)
+			if valid != tt.valid || step != tt.step {
+				t.Fatalf("ValidateTOTPCode() = %d, %v, want %d, %v", step, valid, tt.step, tt.valid)
+			}
+		})
+	}
+
+	if _, valid := ValidateTOTPCode("not base32!", "287082", time.Unix(59, 0)); valid {
+		t.Fatal("accepted a code for a malformed secret")
+	}
+}
+
+func testTOTPCode(t *testing.T, secret string, step int64) string {
+	t.Helper()
+
+	key, err := base32NoPadding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("failed to decode secret: %v", err)
+	}
+
+	return totpCode(key, step)
+}
+
+// twoFactorStores runs the two-factor tests against the in-memory and a
+// SQLite store.
+func twoFactorStores(t *testing.T) map[string]interface {
+	UserStore
+	TwoFactorStore
+} {
+	return map[string]interface {
+		UserStore
+		TwoFactorStore
+	}{
+		"memory": NewMemoryStore(),
+		"sqlite": newTestSqliteStore(t),
+	}
+}
+
+// enrollTestTOTP enables two-factor authentication with the code of the
+// returned step.
+func enrollTestTOTP(t *testing.T, store TwoFactorStore, userId uuid.UUID) (string, int64, []string) {
+	t.Helper()
+	ctx := context.Background()
+
+	secret, err := store.StartTOTPEnrollment(ctx, userId)
+	if err != nil {
+		t.Fatalf("failed to start enrollment: %v", err)
+	}
+
+	step := time.Now().Unix() / totpPeriod
+
+	codes, err := store.VerifyTOTPEnrollment(ctx, userId, testTOTPCode(t, secret, step))
+	if err != nil {
+		t.Fatalf("failed to verify enrollment: %v", err)
+	}
+
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("enrollment returned %d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	return secret, step, codes
+}
+
+func TestVerifyTOTPCodeRejectsReplay(t *testing.T) {
+	for name, store := range twoFactorStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			userId := createTestUser(t, store, "alice")
+			secret, step, _ := enrollTestTOTP(t, store, userId)
+
+			// Enrollment used up its step
+			valid, err := store.VerifyTOTPCode(ctx, userId, testTOTPCode(t, secret, step))
+			if err != nil {
+				t.Fatalf("failed to verify code: %v", err)
+			}
+			if *valid {
+				t.Fatal("accepted the code used for enrollment")
+			}
+
+			next := testTOTPCode(t, secret, step+1)
+			valid, err = store.VerifyTOTPCode(ctx, userId, next)
+			if err != nil {
+				t.Fatalf("failed to verify code: %v", err)
+			}
+			if !*valid {
+				t.Fatal("rejected the next step's code")
+			}
+
+			valid, err = store.VerifyTOTPCode(ctx, userId, next)
+			if err != nil {
+				t.Fatalf("failed to verify code: %v", err)
+			}
+			if *valid {
+				t.Fatal("accepted a replayed code")
+			}
+		})
+	}
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	for name, store := range twoFactorStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			userId := createTestUser(t, store, "alice")
+			otherId := createTestUser(t, store, "bob")
+			_, _, codes := enrollTestTOTP(t, store, userId)
+
+			use := func(userId uuid.UUID, code string) bool {
+				t.Helper()
+
+				used, err := store.UseRecoveryCode(ctx, userId, code)
+				if err != nil {
+					t.Fatalf("failed to use recovery code: %v", err)
+				}
+
+				return *used
+			}
+
+			if use(otherId, codes[0]) {
+				t.Fatal("another user used the recovery code")
+			}
+
+			// Codes are accepted however they were typed
+			if !use(userId, "  "+strings.ToUpper(codes[0])+" ") {
+				t.Fatal("recovery code was rejected")
+			}
+
+			if use(userId, codes[0]) {
+				t.Fatal("recovery code was used twice")
+			}
+
+			if use(userId, "aaaa-aaaa-aaaa-aaaa") {
+				t.Fatal("unknown recovery code was accepted")
+			}
+
+			if err := store.DisableTOTP(ctx, userId); err != nil {
+				t.Fatalf("failed to disable two-factor authentication: %v", err)
+			}
+
+			if use(userId, codes[1]) {
+				t.Fatal("recovery code outlived two-factor authentication")
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package lib
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const maxSerializableAttempts = 3
+
+// txBeginner is the part of a pgxpool.Pool runSerializable uses.
+type txBeginner interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// runSerializable runs fn in a serializable transaction, so concurrent
+// writers can't interleave between its statements. Transactions Postgres
+// aborts as a serialization failure are retried from the start.
+func runSerializable(ctx context.Context, pool txBeginner, fn func(ctx context.Context, tx pgx.Tx) error) error {
+	var err error
+
+	for attempt := 0; attempt < maxSerializableAttempts; attempt++ {
+		err = pgx.BeginTxFunc(ctx, pool, pgx.TxOptions{IsoLevel: pgx.Serializable}, func(tx pgx.Tx) error {
+			return fn(ctx, tx)
+		})
+
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != "40001" {
+			return err
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,172 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeTx only supports ending the transaction, which is all runSerializable
+// does with it.
+type fakeTx struct {
+	pgx.Tx
+	pool   *fakePool
+	closed bool
+}
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+	tx.closed = true
+
+	if len(tx.pool.commitErrs) > 0 {
+		err := tx.pool.commitErrs[0]
+		tx.pool.commitErrs = tx.pool.commitErrs[1:]
+		if err != nil {
+			return err
+		}
+	}
+
+	tx.pool.commits++
+	return nil
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+	tx.closed = true
+
+	tx.pool.rollbacks++
+	return nil
+}
+
+type fakePool struct {
+	// commitErrs are returned by the next commits, in order
+	commitErrs []error
+	commits    int
+	rollbacks  int
+}
+
+func (p *fakePool) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	// Like a pool that can't acquire a connection any more
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if txOptions.IsoLevel != pgx.Serializable {
+		return nil, errors.New("not a serializable transaction")
+	}
+
+	return &fakeTx{pool: p}, nil
+}
+
+var errSerialization = &pgconn.PgError{Code: "40001", Message: "could not serialize access"}
+
+func TestRunSerializable(t *testing.T) {
+	errOther := errors.New("other failure")
+
+	tests := []struct {
+		name string
+		// fnErrs are returned by fn on each attempt, nil once they run out
+		fnErrs     []error
+		commitErrs []error
+		attempts   int
+		commits    int
+		err        error
+	}{
+		{
+			name:     "first attempt",
+			attempts: 1,
+			commits:  1,
+		},
+		{
+			name:     "retried serialization failure",
+			fnErrs:   []error{errSerialization, errSerialization},
+			attempts: 3,
+			commits:  1,
+		},
+		{
+			name:       "serialization failure on commit",
+			commitErrs: []error{errSerialization},
+			attempts:   2,
+			commits:    1,
+		},
+		{
+			name:     "too many serialization failures",
+			fnErrs:   []error{errSerialization, errSerialization, errSerialization, errSerialization},
+			attempts: maxSerializableAttempts,
+			err:      errSerialization,
+		},
+		{
+			name:     "other failures aren't retried",
+			fnErrs:   []error{errOther},
+			attempts: 1,
+			err:      errOther,
+		},
+		{
+			name:     "wrapped serialization failure",
+			fnErrs:   []error{errors.Join(errors.New("failed to update log"), errSerialization)},
+			attempts: 2,
+			commits:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &fakePool{commitErrs: tt.commitErrs}
+			attempts := 0
+
+			err := runSerializable(context.Background(), pool, func(ctx context.Context, tx pgx.Tx) error {
+				attempts++
+
+				if attempts <= len(tt.fnErrs) {
+					return tt.fnErrs[attempts-1]
+				}
+
+				return nil
+			})
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+
+			if attempts != tt.attempts {
+				t.Fatalf("ran %d attempts, want %d", attempts, tt.attempts)
+			}
+
+			if pool.commits != tt.commits {
+				t.Fatalf("committed %d times, want %d", pool.commits, tt.commits)
+			}
+
+			// Every attempt that didn't commit was rolled back
+			if pool.rollbacks != tt.attempts-tt.commits-len(tt.commitErrs) {
+				t.Fatalf("rolled back %d times, want %d", pool.rollbacks, tt.attempts-tt.commits-len(tt.commitErrs))
+			}
+		})
+	}
+}
+
+func TestRunSerializableStopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	attempts := 0
+	err := runSerializable(ctx, &fakePool{}, func(ctx context.Context, tx pgx.Tx) error {
+		attempts++
+		cancel()
+
+		return errors.Join(ctx.Err(), errSerialization)
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+
+	if attempts != 1 {
+		t.Fatalf("ran %d attempts after the context was canceled, want 1", attempts)
+	}
+}
@@ -1,14 +1,13 @@
 package lib
 
 import (
-	"calometer/internal/db"
 	"context"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
-func DoesUserExists(username string) (*bool, error) {
+func (s *PgStore) DoesUserExists(ctx context.Context, username string) (*bool, error) {
 	qStr := `
 		SELECT EXISTS (
 			SELECT 1
@@ -17,14 +16,14 @@ func DoesUserExists(username string) (*bool, error) {
 		)`
 
 	var doesExist bool
-	if err := db.GetPool().QueryRow(context.Background(), qStr, username).Scan(&doesExist); err != nil {
+	if err := s.pool.QueryRow(ctx, qStr, username).Scan(&doesExist); err != nil {
 		return nil, err
 	}
 
 	return &doesExist, nil
 }
 
-func GetUserIdByUsername(username string) (*uuid.UUID, error) {
+func (s *PgStore) GetUserIdByUsername(ctx context.Context, username string) (*uuid.UUID, error) {
 	var userId *uuid.UUID
 
 	qStr := `
@@ -32,7 +31,7 @@ func GetUserIdByUsername(username string) (*uuid.UUID, error) {
 		FROM users
 		WHERE username = $1`
 
-	if err := db.GetPool().QueryRow(context.Background(), qStr, username).Scan(&userId); err != nil {
+	if err := s.pool.QueryRow(ctx, qStr, username).Scan(&userId); err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
 		}
@@ -43,12 +42,85 @@ func GetUserIdByUsername(username string) (*uuid.UUID, error) {
 	return userId, nil
 }
 
-func AddUserBodyDetails(userId uuid.UUID, age int, height_cm int, weight_kg float64, gender string) error {
+// Every user starts out with a default profile sharing their id.
+const createUserQuery = `
+		WITH new_user AS (
+			INSERT INTO users (name, username, password_hash)
+			VALUES ($1, $2, $3)
+			RETURNING id, name
+		)
+		INSERT INTO user_profiles (id, u_id, name, is_default)
+		SELECT id, id, name, TRUE
+		FROM new_user
+		RETURNING u_id`
+
+func (s *PgStore) CreateUser(ctx context.Context, name string, username string, passwordHash string) (*uuid.UUID, error) {
+	var userId uuid.UUID
+
+	if err := s.pool.QueryRow(
+		ctx,
+		createUserQuery,
+		name,
+		username,
+		passwordHash,
+	).Scan(&userId); err != nil {
+		return nil, err
+	}
+
+	return &userId, nil
+}
+
+func (s *PgStore) GetUsernameById(ctx context.Context, userId uuid.UUID) (*string, error) {
+	var username string
+
+	qStr := `
+		SELECT username
+		FROM users
+		WHERE id = $1`
+
+	if err := s.pool.QueryRow(ctx, qStr, userId).Scan(&username); err != nil {
+		return nil, err
+	}
+
+	return &username, nil
+}
+
+func (s *PgStore) GetPasswordHash(ctx context.Context, username string) (*string, error) {
+	var passwordHash string
+
+	qStr := `
+		SELECT password_hash
+		FROM users
+		WHERE username = $1`
+
+	if err := s.pool.QueryRow(ctx, qStr, username).Scan(&passwordHash); err != nil {
+		return nil, err
+	}
+
+	return &passwordHash, nil
+}
+
+func (s *PgStore) UpdatePassword(ctx context.Context, userId uuid.UUID, passwordHash string) error {
+	qStr := `
+		UPDATE users
+		SET
+			password_hash = $2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+
+	if _, err := s.pool.Exec(ctx, qStr, userId, passwordHash); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *PgStore) AddUserBodyDetails(ctx context.Context, profileId uuid.UUID, age int, height_cm int, weight_kg float64, gender string) error {
 	bmr := CalculateBMR(gender, age, weight_kg, height_cm)
 
 	qStr := `
 		INSERT INTO user_body_details (
-			u_id,
+			p_id,
 			age,
 			height_cm,
 			weight_kg,
@@ -61,7 +133,7 @@ func AddUserBodyDetails(userId uuid.UUID, age int, height_cm int, weight_kg floa
 			$4,
 			$5,
 			$6
-		) ON CONFLICT (u_id) DO UPDATE
+		) ON CONFLICT (p_id) DO UPDATE
 		SET
 			age = COALESCE(NULLIF($2, 0), user_body_details.age),
 			height_cm = COALESCE(NULLIF($3, 0), user_body_details.height_cm),
@@ -77,10 +149,10 @@ func AddUserBodyDetails(userId uuid.UUID, age int, height_cm int, weight_kg floa
 			END
 	`
 
-	if _, err := db.GetPool().Exec(
-		context.Background(),
+	if _, err := s.pool.Exec(
+		ctx,
 		qStr,
-		userId,
+		profileId,
 		age,
 		height_cm,
 		weight_kg,
@@ -93,22 +165,22 @@ func AddUserBodyDetails(userId uuid.UUID, age int, height_cm int, weight_kg floa
 	return nil
 }
 
-func SetUserGoal(userId uuid.UUID, goal string) error {
+func (s *PgStore) SetUserGoal(ctx context.Context, profileId uuid.UUID, goal string) error {
 	qStr := `
 		INSERT INTO user_weight_goal (
-			u_id,
+			p_id,
 			goal
 		) VALUES (
 			$1,
 			$2
-		) ON CONFLICT (u_id) DO UPDATE
+		) ON CONFLICT (p_id) DO UPDATE
 		SET goal = COALESCE(NULLIF($2, ''), user_weight_goal.goal)
 		`
 
-	if _, err := db.GetPool().Exec(
-		context.Background(),
+	if _, err := s.pool.Exec(
+		ctx,
 		qStr,
-		userId,
+		profileId,
 		goal,
 	); err != nil {
 		return err
@@ -117,50 +189,50 @@ func SetUserGoal(userId uuid.UUID, goal string) error {
 	return nil
 }
 
-func GetUserBmr(userId uuid.UUID) (*float64, error) {
+func (s *PgStore) GetUserBmr(ctx context.Context, profileId uuid.UUID) (*float64, error) {
 	var bmr float64
 
 	qStr := `
 		SELECT bmr
 		FROM user_body_details
-		WHERE u_id = $1
+		WHERE p_id = $1
 	`
 
-	if err := db.GetPool().QueryRow(context.Background(), qStr, userId).Scan(&bmr); err != nil {
+	if err := s.pool.QueryRow(ctx, qStr, profileId).Scan(&bmr); err != nil {
 		return nil, err
 	}
 
 	return &bmr, nil
 }
 
-func GetUserWeightGoalById(userId uuid.UUID) (*string, error) {
+func (s *PgStore) GetUserWeightGoalById(ctx context.Context, profileId uuid.UUID) (*string, error) {
 	var goal string
 
 	qStr := `
 		SELECT goal
 		FROM user_weight_goal
-		WHERE u_id = $1
+		WHERE p_id = $1
 	`
 
-	if err := db.GetPool().QueryRow(context.Background(), qStr, userId).Scan(&goal); err != nil {
+	if err := s.pool.QueryRow(ctx, qStr, profileId).Scan(&goal); err != nil {
 		return nil, err
 	}
 
 	return &goal, nil
 }
 
-func DoesBodyDetailsExist(userId uuid.UUID) (*bool, error) {
+func (s *PgStore) DoesBodyDetailsExist(ctx context.Context, profileId uuid.UUID) (*bool, error) {
 	var exists bool
 
 	qStr := `
 		SELECT EXISTS (
 			SELECT 1
 			FROM user_body_details
-			WHERE u_id = $1
+			WHERE p_id = $1
 		) AS exists
 	`
 
-	if err := db.GetPool().QueryRow(context.Background(), qStr, userId).Scan(&exists); err != nil {
+	if err := s.pool.QueryRow(ctx, qStr, profileId).Scan(&exists); err != nil {
 		return nil, err
 	}
 
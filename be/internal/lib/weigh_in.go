@@ -0,0 +1,68 @@
+package lib
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WeighIn struct {
+	WeighedOn string  `json:"weighed_on"`
+	WeightKg  float64 `json:"weight_kg"`
+}
+
+// RecordWeighIn keeps one weigh-in per day, the latest one wins.
+func (s *PgStore) RecordWeighIn(ctx context.Context, profileId uuid.UUID, weightKg float64) error {
+	qStr := `
+		INSERT INTO user_weigh_ins (
+			p_id,
+			weight_kg
+		) VALUES (
+			$1,
+			$2
+		) ON CONFLICT (p_id, weighed_on) DO UPDATE
+		SET weight_kg = $2
+	`
+
+	if _, err := s.pool.Exec(ctx, qStr, profileId, weightKg); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *PgStore) GetWeighIns(ctx context.Context, profileId uuid.UUID) ([]WeighIn, error) {
+	weighIns := []WeighIn{}
+
+	qStr := `
+		SELECT weighed_on, weight_kg
+		FROM user_weigh_ins
+		WHERE p_id = $1
+		ORDER BY weighed_on
+	`
+
+	rows, err := s.pool.Query(ctx, qStr, profileId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var weighIn WeighIn
+		var weighedOn time.Time
+
+		if err := rows.Scan(&weighedOn, &weighIn.WeightKg); err != nil {
+			return nil, err
+		}
+
+		weighIn.WeighedOn = weighedOn.Format("2006-01-02")
+		weighIns = append(weighIns, weighIn)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return weighIns, nil
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// registry holds only calometer's metrics plus the Go runtime and process
+// ones, not whatever libraries add to the global default registry
+var registry = prometheus.NewRegistry()
+
+var (
+	// Requests are labeled by route template, so /api/coach/clients/{profile_id}/log/get
+	// is one series no matter how many clients there are
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "calometer",
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "calometer",
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+
+	LogsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "calometer",
+		Name:      "calorie_logs_created_total",
+		Help:      "Calorie log entries created.",
+	})
+
+	DaysFinalized = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "calometer",
+		Name:      "days_finalized_total",
+		Help:      "Days marked as completely logged.",
+	})
+
+	Signups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "calometer",
+		Name:      "signups_total",
+		Help:      "Accounts created, by how the user signed up.",
+	}, []string{"method"})
+
+	FailedLogins = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "calometer",
+		Name:      "failed_logins_total",
+		Help:      "Login attempts rejected for a wrong password or second factor.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		LogsCreated,
+		DaysFinalized,
+		Signups,
+		FailedLogins,
+	)
+}
+
+// Register adds collectors that only exist once the server is set up, like
+// the database pool's.
+func Register(collector prometheus.Collector) error {
+	return registry.Register(collector)
+}
+
+// Handler serves every metric in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,227 @@
+// Package migratecmd runs the migration commands, which both the migrate and
+// the server binaries offer.
+package migratecmd
+
+import (
+	"bufio"
+	"calometer/internal/config"
+	"calometer/internal/db"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// Usage lists the commands, the binary name still has to be filled in.
+const Usage = `Usage: %s <command> [args...]
+
+Commands:
+  create <name>   add an up and down migration to internal/db/migrations
+  up              apply every pending migration
+  down <steps>    roll back the given number of migrations
+  goto <version>  migrate up or down to version
+  status          show the applied and latest version
+  force <version> mark version as applied without running anything
+`
+
+// Run loads the config from the environment and runs command, it exits the
+// process when anything fails. name is how the binary was invoked and is
+// used in the usage messages.
+func Run(name string, command string, args []string) {
+	cfg, err := config.Load(name, nil)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Creating migrations only touches the source tree
+	if command == "create" {
+		if len(args) < 1 {
+			log.Fatalf("Usage: %s create <migration_name>\n", name)
+		}
+
+		createMigration(cfg.Database.Driver, args[0])
+		return
+	}
+
+	if err := cfg.Database.Validate(); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+
+	// DB_DRIVER=sqlite migrates the self-hosted SQLite file instead
+	driver := cfg.Database.Driver
+	m, err := db.NewMigrate(driver, db.GetMigrationUrl(cfg.Database))
+	if err != nil {
+		log.Fatalf("Failed to create migration instance: %v", err)
+	}
+	defer m.Close()
+
+	switch command {
+	case "up":
+		migrateUp(m, driver)
+	case "down":
+		if len(args) < 1 {
+			log.Fatalf("Usage: %s down <number_of_steps>\n", name)
+		}
+
+		steps, err := strconv.Atoi(args[0])
+		if err != nil || steps < 1 {
+			log.Fatalf("Invalid number of steps: %s\n", args[0])
+		}
+
+		// Rollback by the specified number of steps
+		if err := m.Steps(-1 * steps); err != nil {
+			log.Fatalf("Failed to rollback migrations: %v", err)
+		}
+
+		log.Println("Rollback action executed successfully!")
+	case "goto":
+		if len(args) < 1 {
+			log.Fatalf("Usage: %s goto <version>\n", name)
+		}
+
+		version, err := strconv.ParseUint(args[0], 10, 0)
+		if err != nil {
+			log.Fatalf("Invalid version: %s\n", args[0])
+		}
+
+		if err := m.Migrate(uint(version)); err != nil {
+			if errors.Is(err, migrate.ErrNoChange) {
+				log.Printf("Already at version %d.\n", version)
+				return
+			}
+
+			log.Fatalf("Failed to migrate to version %d: %v", version, err)
+		}
+
+		log.Printf("Migrated to version %d.\n", version)
+	case "status":
+		printStatus(m, driver)
+	case "force":
+		if len(args) < 1 {
+			log.Fatalf("Usage: %s force <version>\n", name)
+		}
+
+		version, err := strconv.Atoi(args[0])
+		if err != nil || version < -1 {
+			log.Fatalf("Invalid version: %s\n", args[0])
+		}
+
+		printStatus(m, driver)
+		if !confirm(fmt.Sprintf("Mark version %d as applied without running any migration?", version)) {
+			log.Fatalln("Aborted.")
+		}
+
+		if err := m.Force(version); err != nil {
+			log.Fatalf("Failed to force migration state: %v", err)
+		}
+
+		log.Printf("Forced version %d.\n", version)
+	default:
+		log.Fatalf("Unknown command: %s\n", command)
+	}
+}
+
+func createMigration(driver string, name string) {
+	dir := "internal/db/migrations"
+	if driver == "sqlite" {
+		dir = "internal/db/sqlite_migrations"
+	}
+
+	cmd := exec.Command("migrate", "create", "-ext", "sql", "-dir", dir, "-seq", "-digits", "1", name)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		log.Fatalf("Failed to execute command: %v\n", err)
+	}
+
+	fmt.Println("Command executed successfully.")
+}
+
+// migrateUp applies pending migrations. A migration that failed part way
+// leaves the database dirty, every migration runs in a transaction so it
+// can be marked as not applied and retried once the operator confirms.
+func migrateUp(m *migrate.Migrate, driver string) {
+	err := m.Up()
+	if err == nil {
+		log.Println("Migrations applied successfully.")
+		return
+	}
+	if errors.Is(err, migrate.ErrNoChange) {
+		log.Println("No new migrations to apply.")
+		return
+	}
+
+	log.Printf("Migration error: %v\n", err)
+
+	version, dirty, versionErr := m.Version()
+	if versionErr != nil || !dirty {
+		os.Exit(1)
+	}
+
+	previous, err := db.PreviousMigrationVersion(driver, version)
+	if err != nil {
+		log.Fatalf("Failed to find the migration before %d: %v", version, err)
+	}
+
+	prompt := fmt.Sprintf(
+		"Migration %d failed and the database is dirty. Check that none of it was applied, then force version %d and retry?",
+		version,
+		previous,
+	)
+	if !confirm(prompt) {
+		log.Fatalf("Left the database dirty at version %d, fix it and run force.", version)
+	}
+
+	if err := m.Force(previous); err != nil {
+		log.Fatalf("Failed to force migration state: %v", err)
+	}
+
+	log.Println("Dirty state fixed. Retrying migration.")
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		log.Fatalf("Failed to reapply migrations: %v", err)
+	}
+
+	log.Println("Migrations applied successfully after fixing dirty state.")
+}
+
+func printStatus(m *migrate.Migrate, driver string) {
+	status, err := db.GetMigrationStatus(m, driver)
+	if err != nil {
+		log.Fatalf("Failed to read migration status: %v", err)
+	}
+
+	fmt.Printf("Driver:  %s\n", driver)
+	fmt.Printf("Version: %d\n", status.Version)
+	fmt.Printf("Latest:  %d\n", status.Latest)
+	fmt.Printf("Dirty:   %t\n", status.Dirty)
+
+	switch {
+	case status.Dirty:
+		fmt.Printf("Migration %d failed part way, fix it and run force.\n", status.Version)
+	case status.Version < status.Latest:
+		fmt.Println("Migrations are pending, run up.")
+	case status.Version > status.Latest:
+		fmt.Println("The database is ahead of this binary.")
+	default:
+		fmt.Println("Up to date.")
+	}
+}
+
+// confirm asks a yes or no question on the terminal, anything but yes is no.
+func confirm(prompt string) bool {
+	fmt.Printf("%s [y/N] ", prompt)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,129 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// jwksServer serves Ed25519 keys under the given kids and counts fetches.
+type jwksServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	kids    []string
+	fetches int
+}
+
+func newJWKSServer(t *testing.T, kids ...string) *jwksServer {
+	t.Helper()
+
+	s := &jwksServer{kids: kids}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.fetches++
+
+		keys := []jsonWebKey{}
+		for _, kid := range s.kids {
+			public, _, err := ed25519.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Errorf("failed to generate key: %v", err)
+			}
+
+			keys = append(keys, jsonWebKey{
+				Kty: "OKP",
+				Kid: kid,
+				Use: "sig",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *jwksServer) rotate(kids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.kids = kids
+}
+
+func (s *jwksServer) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.fetches
+}
+
+func TestKeySetKey(t *testing.T) {
+	ctx := context.Background()
+	server := newJWKSServer(t, "first")
+	keys := newKeySet(server.Client(), server.URL)
+
+	if _, err := keys.key(ctx, "first"); err != nil {
+		t.Fatalf("failed to get key: %v", err)
+	}
+
+	// Providers with a single key may leave out the kid
+	if _, err := keys.key(ctx, ""); err != nil {
+		t.Fatalf("failed to get key without kid: %v", err)
+	}
+
+	if fetches := server.fetchCount(); fetches != 1 {
+		t.Fatalf("fetched the JWKS %d times, want 1", fetches)
+	}
+}
+
+func TestKeySetRefetchIsRateLimited(t *testing.T) {
+	ctx := context.Background()
+	server := newJWKSServer(t, "first")
+	keys := newKeySet(server.Client(), server.URL)
+
+	if _, err := keys.key(ctx, "first"); err != nil {
+		t.Fatalf("failed to get key: %v", err)
+	}
+
+	server.rotate("first", "second")
+
+	// Made up kids right after a fetch don't reach the provider
+	if _, err := keys.key(ctx, "second"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("key() = %v, want %v", err, ErrUnknownKey)
+	}
+	if fetches := server.fetchCount(); fetches != 1 {
+		t.Fatalf("fetched the JWKS %d times, want 1", fetches)
+	}
+
+	keys.mu.Lock()
+	keys.fetchedAt = time.Now().Add(-jwksMinRefreshInterval)
+	keys.mu.Unlock()
+
+	// Once the interval is over an unknown kid refetches the rotated keys
+	if _, err := keys.key(ctx, "second"); err != nil {
+		t.Fatalf("failed to get rotated key: %v", err)
+	}
+	if fetches := server.fetchCount(); fetches != 2 {
+		t.Fatalf("fetched the JWKS %d times, want 2", fetches)
+	}
+
+	if _, err := keys.key(ctx, "third"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("key() = %v, want %v", err, ErrUnknownKey)
+	}
+	if fetches := server.fetchCount(); fetches != 2 {
+		t.Fatalf("fetched the JWKS %d times, want 2", fetches)
+	}
+}
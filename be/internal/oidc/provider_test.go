@@ -0,0 +1,133 @@
+package oidc
+
+import (
+	"calometer/internal/oidc/oidctest"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) *oidctest.Server {
+	t.Helper()
+
+	server, err := oidctest.NewServer("client", "secret")
+	if err != nil {
+		t.Fatalf("failed to start provider: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	server := newTestServer(t)
+	provider := NewProvider(Config{
+		IssuerURL:   server.URL,
+		ClientID:    "client",
+		RedirectURL: "https://app.example.com/callback",
+	})
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	if err != nil {
+		t.Fatalf("failed to build authorization url: %v", err)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("failed to parse authorization url: %v", err)
+	}
+
+	if got := parsed.Scheme + "://" + parsed.Host + parsed.Path; got != server.URL+"/authorize" {
+		t.Fatalf("authorization endpoint = %s, want the discovered one", got)
+	}
+
+	query := parsed.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             "client",
+		"redirect_uri":          "https://app.example.com/callback",
+		"state":                 "state",
+		"nonce":                 "nonce",
+		"code_challenge":        CodeChallengeS256("verifier"),
+		"code_challenge_method": "S256",
+	}
+	for key, value := range want {
+		if query.Get(key) != value {
+			t.Fatalf("%s = %q, want %q", key, query.Get(key), value)
+		}
+	}
+}
+
+// newDiscoveryServer answers discovery with issuer, or with a 503 while down
+// is set.
+func newDiscoveryServer(t *testing.T, issuer string, down *atomic.Bool) *httptest.Server {
+	t.Helper()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		discovered := issuer
+		if discovered == "" {
+			discovered = server.URL
+		}
+
+		json.NewEncoder(w).Encode(Discovery{
+			Issuer:                discovered,
+			AuthorizationEndpoint: server.URL + "/authorize",
+			TokenEndpoint:         server.URL + "/token",
+			JWKSURI:               server.URL + "/jwks",
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestDiscoveryIsRetriedAfterFailure(t *testing.T) {
+	ctx := context.Background()
+
+	var down atomic.Bool
+	down.Store(true)
+	issuer := newDiscoveryServer(t, "", &down)
+
+	// Creating the provider doesn't need the issuer to be up
+	provider := NewProvider(Config{IssuerURL: issuer.URL, ClientID: "client"})
+
+	if _, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier"); err == nil {
+		t.Fatal("built an authorization url without discovery")
+	}
+
+	// The failure is remembered for a while instead of asking again
+	down.Store(false)
+	if _, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier"); err == nil {
+		t.Fatal("discovery was retried right after failing")
+	}
+
+	provider.mu.Lock()
+	provider.discoveredAt = time.Now().Add(-discoveryRetryInterval)
+	provider.mu.Unlock()
+
+	if _, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier"); err != nil {
+		t.Fatalf("failed to build authorization url after the issuer came back: %v", err)
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	var down atomic.Bool
+	issuer := newDiscoveryServer(t, "https://elsewhere.example.com", &down)
+	provider := NewProvider(Config{IssuerURL: issuer.URL, ClientID: "client"})
+
+	if _, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier"); !errors.Is(err, ErrIssuerMismatch) {
+		t.Fatalf("AuthCodeURL() = %v, want %v", err, ErrIssuerMismatch)
+	}
+}
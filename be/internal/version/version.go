@@ -0,0 +1,40 @@
+package version
+
+import "runtime/debug"
+
+// Set at build time with
+//
+//	go build -ldflags "-X calometer/internal/version.GitSHA=$(git rev-parse HEAD) -X calometer/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Builds without them fall back to the VCS details the go command stamps
+// into binaries built inside a checkout, where the time is the commit's.
+var (
+	GitSHA    string
+	BuildTime string
+)
+
+// Get returns the commit and build time of the running binary, "unknown"
+// when neither source has them.
+func Get() (gitSHA string, buildTime string) {
+	gitSHA, buildTime = GitSHA, BuildTime
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && gitSHA == "":
+				gitSHA = setting.Value
+			case setting.Key == "vcs.time" && buildTime == "":
+				buildTime = setting.Value
+			}
+		}
+	}
+
+	if gitSHA == "" {
+		gitSHA = "unknown"
+	}
+	if buildTime == "" {
+		buildTime = "unknown"
+	}
+
+	return gitSHA, buildTime
+}
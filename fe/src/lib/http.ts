@@ -11,10 +11,20 @@ type HttpData<T = any> = {
 
 type HttpResponseWithData = HttpResponse & HttpData
 
+const refreshUrl = `${process.env.REACT_APP_API_URL}/api/users/token/refresh`;
+
+// Access tokens are short-lived, so an expired session is refreshed once
+// and the original request is retried before giving up.
+const refreshSession = async (): Promise<boolean> => {
+  const resp = await http_common('POST', refreshUrl, undefined, false);
+  return resp.code[200] !== undefined;
+};
+
 export const http_common = async (
   method: 'GET' | 'POST' | 'PUT' | 'DELETE',
   url: string,
-  body?: Record<string, any>
+  body?: Record<string, any>,
+  retryOnExpiry: boolean = true
 ): Promise<HttpResponseWithData> => {
   const opts: RequestInit = {
       method,
@@ -38,6 +48,16 @@ export const http_common = async (
       };
       const respData: HttpData = data
 
+      if (
+        retryOnExpiry &&
+        respData.code?.[401] !== undefined &&
+        url !== refreshUrl &&
+        !url.endsWith('/api/users/login') &&
+        (await refreshSession())
+      ) {
+        return http_common(method, url, body, false);
+      }
+
       const httpResponseWithData: HttpResponseWithData = {
         ...retResp,
         ...respData,